@@ -0,0 +1,287 @@
+package cite
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Cite is where a card is from
+type Cite struct {
+	Author         string // Full name, like Jane Smith, or several joined with and
+	Qualifications string // Why the author is worth listening to
+	Date           string // When it was published, in any format that has the year in it
+	Title          string
+	Publication    string
+	URL            string
+	Accessed       string // When the source was read
+}
+
+// Custom is the style name used for a template written by the team
+const Custom = "custom"
+
+// Default is the style used until the team picks one
+const Default = "debate"
+
+// Styles are the built in cite templates
+// Every style starts with the short cite in bold, since that is what cards are found by
+var Styles = map[string]string{
+	"debate":  `<b>{{.Short}}</b> [{{join ", " .Author .Qualifications (quote .Title) .Publication .Date .URL (prefix "accessed " .Accessed)}}]`,
+	"mla":     `<b>{{.Short}}</b> {{join ". " .Author .Qualifications (quote .Title) .Publication (join ", " .Date .URL)}}.{{with .Accessed}} Accessed {{.}}.{{end}}`,
+	"chicago": `<b>{{.Short}}</b> {{join ". " .Author (quote .Title) .Publication .Date (prefix "Accessed " .Accessed) .URL}}.{{with .Qualifications}} ({{.}}){{end}}`,
+}
+
+// StyleNames returns the names of the built in styles, sorted
+func StyleNames() []string {
+	names := []string{}
+	for name := range Styles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+var funcs = template.FuncMap{
+	"join":   join,
+	"quote":  quote,
+	"prefix": prefix,
+}
+
+// join joins the parts that aren't empty with the separator
+func join(sep string, parts ...string) string {
+	kept := []string{}
+	for _, part := range parts {
+		if part = strings.TrimSpace(part); part != "" {
+			kept = append(kept, part)
+		}
+	}
+	return strings.Join(kept, sep)
+}
+
+// quote puts quotes around text that isn't empty
+func quote(text string) string {
+	if strings.TrimSpace(text) == "" {
+		return ""
+	}
+	return fmt.Sprintf("\"%s\"", strings.TrimSpace(text))
+}
+
+// prefix puts before in front of text that isn't empty
+func prefix(before string, text string) string {
+	if strings.TrimSpace(text) == "" {
+		return ""
+	}
+	return before + strings.TrimSpace(text)
+}
+
+// checkShort stands in for the short cite when checking templates, so it can be found in what they output
+const checkShort = "Shortcite 19"
+
+// Compile checks that a template can be used to format cites
+func Compile(tmpl string) (*template.Template, error) {
+	parsed, err := template.New("cite").Funcs(funcs).Parse(tmpl)
+	if err != nil {
+		return nil, fmt.Errorf("the cite template is broken: %s", err.Error())
+	}
+	// Fields that don't exist only show up when the template runs
+	out := &bytes.Buffer{}
+	err = parsed.Execute(out, view{Cite: Cite{Author: "Jane Smith", Date: "2019"}, Short: checkShort})
+	if err != nil {
+		return nil, fmt.Errorf("the cite template is broken: %s", err.Error())
+	}
+	if !strings.Contains(out.String(), checkShort) {
+		return nil, fmt.Errorf("the cite template needs {{.Short}}, since cards are found by their short cite")
+	}
+	return parsed, nil
+}
+
+// view is what templates can use, which is the cite and its short form
+type view struct {
+	Cite
+	Short string
+}
+
+// Format renders the cite as HTML with the template
+func Format(ct Cite, tmpl string) (string, error) {
+	parsed, err := Compile(tmpl)
+	if err != nil {
+		return "", err
+	}
+	short, err := ct.Short()
+	if err != nil {
+		return "", err
+	}
+	out := &bytes.Buffer{}
+	err = parsed.Execute(out, view{Cite: ct, Short: short})
+	if err != nil {
+		return "", fmt.Errorf("failed to format the cite: %s", err.Error())
+	}
+	return out.String(), nil
+}
+
+// Template returns the template of a style, using custom when the style is Custom
+func Template(style string, custom string) string {
+	if style == Custom && strings.TrimSpace(custom) != "" {
+		return custom
+	}
+	if tmpl, ok := Styles[style]; ok {
+		return tmpl
+	}
+	return Styles[Default]
+}
+
+var yearRp = regexp.MustCompile(`\b(1[89]|20)(\d{2})\b`)
+
+// Short returns the short cite, like Smith 19
+func (ct Cite) Short() (string, error) {
+	last := LastName(ct.Author)
+	if last == "" {
+		return "", fmt.Errorf("the cite needs an author")
+	}
+	year := yearRp.FindStringSubmatch(ct.Date)
+	if year == nil {
+		return "", fmt.Errorf("the date needs a year in it")
+	}
+	return fmt.Sprintf("%s %s", last, year[2]), nil
+}
+
+// LastName returns the last name of the first author, like Smith for "Jane Smith and Bob Jones"
+func LastName(author string) string {
+	author = strings.TrimSpace(author)
+	for _, sep := range []string{" and ", " & ", ",", " et al"} {
+		if i := strings.Index(author, sep); i != -1 {
+			author = author[:i]
+		}
+	}
+	words := strings.Fields(author)
+	if len(words) == 0 {
+		return ""
+	}
+	return words[len(words)-1]
+}
+
+var (
+	shortRp    = regexp.MustCompile(`^\s*([A-Z][\w'’-]+(?:\s+(?:et al\.?|and [A-Z][\w'’-]+|& [A-Z][\w'’-]+))?),?\s+'?(\d{2}|\d{4})\b`)
+	urlRp      = regexp.MustCompile(`^(https?://|www\.)\S+$`)
+	accessedRp = regexp.MustCompile(`(?i)^(date\s+)?accessed:?\s+`)
+	sentenceRp = regexp.MustCompile(`([^.\s]{3,})\.\s+(\S)`)
+	quotedRp   = regexp.MustCompile(`^["“](.*?)[,.]?["”]$`)
+	dateRp     = regexp.MustCompile(`(?i)^(\d{1,2}[-/.]\d{1,2}[-/.]\d{2,4}|\d{4}([-/.]\d{1,2}([-/.]\d{1,2})?)?|(jan|feb|mar|apr|may|jun|jul|aug|sep|oct|nov|dec)\w*\.?\s+\d{1,2},?\s+\d{4}|\d{1,2}\s+(jan|feb|mar|apr|may|jun|jul|aug|sep|oct|nov|dec)\w*\.?\s+\d{4}|(jan|feb|mar|apr|may|jun|jul|aug|sep|oct|nov|dec)\w*\.?\s+\d{4})$`)
+)
+
+// Parse reads a cite typed by hand, like "Smith 19 [Jane Smith, Professor at Yale, "Title," Publication, 3-4-2019, URL]"
+// It returns false if the text doesn't start with a short cite, since then it probably isn't a cite
+func Parse(text string) (Cite, bool) {
+	text = strings.Join(strings.Fields(text), " ")
+	short := shortRp.FindStringSubmatch(text)
+	if short == nil {
+		return Cite{}, false
+	}
+	ct := Cite{}
+	rest := strings.TrimSpace(text[len(short[0]):])
+	if strings.HasPrefix(rest, "[") || strings.HasPrefix(rest, "(") {
+		rest = strings.TrimSuffix(strings.TrimSuffix(rest[1:], "]"), ")")
+	} else {
+		// Cites without brackets, like the MLA and Chicago styles, separate their parts with periods
+		rest = sentenceRp.ReplaceAllString(strings.TrimSuffix(rest, "."), "$1, $2")
+	}
+
+	afterTitle := false
+	extra := []string{}
+	for _, part := range joinDates(splitCite(rest)) {
+		switch {
+		case accessedRp.MatchString(part):
+			ct.Accessed = accessedRp.ReplaceAllString(part, "")
+		case urlRp.MatchString(part):
+			ct.URL = part
+		case quotedRp.MatchString(part):
+			ct.Title = quotedRp.FindStringSubmatch(part)[1]
+			afterTitle = true
+		case dateRp.MatchString(part) && ct.Date == "":
+			ct.Date = part
+		case ct.Author == "" && len(extra) == 0 && !afterTitle:
+			ct.Author = part
+		case afterTitle && ct.Publication == "":
+			ct.Publication = part
+		default:
+			extra = append(extra, part)
+		}
+	}
+	ct.Qualifications = strings.Trim(strings.Join(extra, ", "), "()")
+	if ct.Author == "" {
+		ct.Author = short[1]
+	}
+	if ct.Date == "" {
+		ct.Date = short[2]
+		if len(ct.Date) == 2 {
+			ct.Date = fullYear(ct.Date)
+		}
+	}
+	return ct, true
+}
+
+// splitCite splits a cite on commas that aren't inside quotes, and after quotes
+func splitCite(text string) []string {
+	parts := []string{}
+	inQuote := false
+	start := 0
+	runes := []rune(text)
+	for i, r := range runes {
+		switch {
+		case r == '“' || (r == '"' && !inQuote):
+			inQuote = true
+		case r == '”' || r == '"':
+			// Titles usually end with the comma inside the quotes, so the quote ends the part
+			inQuote = false
+			parts = appendPart(parts, string(runes[start:i+1]))
+			start = i + 1
+		case r == ',' && !inQuote:
+			parts = appendPart(parts, string(runes[start:i]))
+			start = i + 1
+		}
+	}
+	return appendPart(parts, string(runes[start:]))
+}
+
+var (
+	monthDayRp = regexp.MustCompile(`(?i)^(jan|feb|mar|apr|may|jun|jul|aug|sep|oct|nov|dec)\w*\.?\s+\d{1,2}$`)
+	fullYearRp = regexp.MustCompile(`^\d{4}$`)
+)
+
+// joinDates puts dates like "March 4, 2019" that were split on their comma back together
+func joinDates(parts []string) []string {
+	joined := []string{}
+	for i := 0; i < len(parts); i++ {
+		if i+1 < len(parts) && monthDayRp.MatchString(parts[i]) && fullYearRp.MatchString(parts[i+1]) {
+			joined = append(joined, parts[i]+", "+parts[i+1])
+			i++
+			continue
+		}
+		joined = append(joined, parts[i])
+	}
+	return joined
+}
+
+func appendPart(parts []string, part string) []string {
+	if part = strings.TrimSpace(part); part != "" {
+		return append(parts, part)
+	}
+	return parts
+}
+
+// fullYear guesses the century of a two digit year, assuming cites aren't from the future
+func fullYear(short string) string {
+	year, err := strconv.Atoi(short)
+	if err != nil {
+		return short
+	}
+	if 2000+year > time.Now().Year() {
+		return strconv.Itoa(1900 + year)
+	}
+	return strconv.Itoa(2000 + year)
+}
@@ -0,0 +1,278 @@
+package collab
+
+import (
+	"encoding/json"
+	"syscall/js"
+	"time"
+
+	"github.com/google/uuid"
+
+	"gitlab.com/256/DebateFrame/client/collab/shared"
+	"gitlab.com/256/DebateFrame/client/history"
+	"gitlab.com/256/DebateFrame/client/log"
+)
+
+// reconnectDelay is how long to wait before connecting to the relay again after losing the connection
+const reconnectDelay = time.Second * 5
+
+// message matches relay.Message on the server
+type message struct {
+	Type     string
+	Room     string
+	User     string
+	Name     string
+	ID       string
+	Base     int
+	Seq      int
+	Op       json.RawMessage `json:",omitempty"`
+	Presence json.RawMessage `json:",omitempty"`
+	Ops      []message       `json:",omitempty"`
+	Reset    bool            `json:",omitempty"`
+	Error    string          `json:",omitempty"`
+}
+
+// presence is where a user's cursor is
+type presence struct {
+	Block int // Index of the block the cursor is in
+}
+
+// Session keeps one case in sync with everyone else editing it through a relay
+// Changes made while disconnected are kept and sent once the relay is reachable again
+type Session struct {
+	Room string
+	Name string
+
+	// OnRemote is called with the new blocks when a teammate changes the case
+	OnRemote func(blocks []string)
+	// OnReset is called when the relay lost the case and it was replaced by the relay's copy, with what was replaced
+	OnReset func(previous []string)
+	// OnConflict is called when a teammate changed the same blocks as a change not sent yet, and both versions were kept
+	OnConflict func()
+	// OnPresence is called when a teammate moves their cursor, with a block of -1 when they leave
+	OnPresence func(user string, name string, block int)
+	// OnDenied is called when the relay won't let this user into the room or change the case, after which the session is closed
+	OnDenied func(reason string)
+	// OnStatus is called when the connection to the relay opens or closes
+	OnStatus func(connected bool)
+	// Current returns what the editor shows now, so typing that wasn't passed to Local yet is kept when a change arrives
+	Current func() []string
+
+	url    string
+	user   string
+	ws     js.Value
+	open   bool
+	closed bool
+	doc    *shared.Doc
+}
+
+// Host starts a session for a case that isn't on the relay yet, sending it the current blocks
+func Host(url string, room string, name string, blocks []string) *Session {
+	sess := newSession(url, room, name, blocks)
+	sess.connect()
+	return sess
+}
+
+// Join starts a session for a case that a teammate is hosting, starting from an empty document
+func Join(url string, room string, name string) *Session {
+	sess := newSession(url, room, name, []string{})
+	sess.connect()
+	return sess
+}
+
+func newSession(url string, room string, name string, blocks []string) *Session {
+	return &Session{
+		Room: room,
+		Name: name,
+		url:  url,
+		user: uuid.New().String(),
+		doc:  shared.NewDoc(blocks),
+	}
+}
+
+// Local records a change made in the editor, which now shows the given blocks
+func (sess *Session) Local(blocks []string) {
+	sess.doc.Record(blocks)
+	sess.sendNext()
+}
+
+// Presence tells teammates which block the cursor is in
+func (sess *Session) Presence(block int) {
+	pres, err := json.Marshal(presence{Block: block})
+	if err != nil {
+		return
+	}
+	sess.send(message{Type: "presence", Presence: pres})
+}
+
+// Close disconnects from the relay for good
+func (sess *Session) Close() {
+	sess.closed = true
+	if sess.open {
+		sess.ws.Call("close")
+	}
+}
+
+// connect opens a connection to the relay and joins the room once it is open
+func (sess *Session) connect() {
+	sess.ws = js.Global().Get("WebSocket").New(sess.url)
+	sess.ws.Set("onopen", js.NewCallback(func(args []js.Value) {
+		sess.open = true
+		sess.doc.Sent = false
+		sess.send(message{Type: "join", Room: sess.Room, User: sess.user, Name: sess.Name, Base: sess.doc.Rev})
+		sess.status(true)
+	}))
+	sess.ws.Set("onmessage", js.NewCallback(func(args []js.Value) {
+		msg := message{}
+		err := json.Unmarshal([]byte(args[0].Get("data").String()), &msg)
+		if err != nil {
+			log.WarnMessage("Ignoring bad message from the relay: %s", err.Error())
+			return
+		}
+		sess.receive(msg)
+	}))
+	sess.ws.Set("onclose", js.NewCallback(func(args []js.Value) {
+		wasOpen := sess.open
+		sess.open = false
+		if wasOpen {
+			sess.status(false)
+		}
+		if !sess.closed {
+			go func() {
+				time.Sleep(reconnectDelay)
+				sess.connect()
+			}()
+		}
+	}))
+}
+
+func (sess *Session) receive(msg message) {
+	switch msg.Type {
+	case "sync":
+		if msg.Reset {
+			sess.reset(msg.Ops)
+		} else {
+			for _, op := range msg.Ops {
+				sess.receiveOp(op)
+			}
+		}
+		sess.sendNext()
+	case "op":
+		sess.receiveOp(msg)
+		sess.sendNext()
+	case "reject":
+		// Ops the step didn't know about are on their way, and the step will be rebased over them before it is sent again
+		sess.doc.Sent = false
+		sess.sendNext()
+	case "denied":
+		log.WarnMessage("The relay denied access to room %s: %s", sess.Room, msg.Error)
+		sess.Close()
+		if sess.OnDenied != nil {
+			sess.OnDenied(msg.Error)
+		}
+	case "presence":
+		pres := presence{}
+		if json.Unmarshal(msg.Presence, &pres) == nil && sess.OnPresence != nil {
+			sess.OnPresence(msg.User, msg.Name, pres.Block)
+		}
+	case "leave":
+		if sess.OnPresence != nil {
+			sess.OnPresence(msg.User, msg.Name, -1)
+		}
+	}
+}
+
+// receiveOp applies an op accepted by the relay, which is either one of our steps or a teammate's change
+func (sess *Session) receiveOp(msg message) {
+	if msg.Seq != sess.doc.Rev {
+		return
+	}
+	splice := history.Splice{}
+	err := json.Unmarshal(msg.Op, &splice)
+	if err != nil {
+		log.WarnMessage("Ignoring bad op from the relay: %s", err.Error())
+		return
+	}
+	remote := !sess.doc.Waiting(msg.ID)
+	if remote && sess.Current != nil {
+		sess.doc.Record(sess.Current())
+	}
+	conflict, err := sess.doc.Accept(msg.ID, splice)
+	if err != nil {
+		log.WarnMessage("Op from the relay doesn't match the case, starting over: %s", err.Error())
+		sess.doc.Rev = -1
+		sess.ws.Call("close")
+		return
+	}
+	if !remote {
+		return
+	}
+	if sess.OnRemote != nil {
+		sess.OnRemote(sess.doc.Local)
+	}
+	if conflict && sess.OnConflict != nil {
+		sess.OnConflict()
+	}
+}
+
+// reset handles the relay not having the ops this session already applied, usually because it restarted
+func (sess *Session) reset(ops []message) {
+	previous := sess.doc.Local
+	if sess.Current != nil {
+		previous = sess.Current()
+	}
+	if len(ops) == 0 {
+		// Nobody else is here yet, so this copy becomes the relay's copy
+		sess.doc = shared.NewDoc(previous)
+		return
+	}
+	sess.doc = shared.NewDoc([]string{})
+	// What the editor shows is being thrown away, so it must not be picked up as typing
+	current := sess.Current
+	sess.Current = nil
+	for _, op := range ops {
+		sess.receiveOp(op)
+	}
+	sess.Current = current
+	if sess.OnReset != nil {
+		sess.OnReset(previous)
+	}
+	if sess.OnRemote != nil {
+		sess.OnRemote(sess.doc.Local)
+	}
+}
+
+// sendNext sends the oldest pending step if nothing is waiting for an answer
+func (sess *Session) sendNext() {
+	if !sess.open {
+		return
+	}
+	st, ok := sess.doc.Next()
+	if !ok {
+		return
+	}
+	op, err := json.Marshal(st.Splice)
+	if err != nil {
+		sess.doc.Sent = false
+		log.WarnMessage("Failed to convert a change to json: %s", err.Error())
+		return
+	}
+	sess.send(message{Type: "op", ID: st.ID, Base: sess.doc.Rev, Op: op})
+}
+
+func (sess *Session) send(msg message) {
+	if !sess.open {
+		return
+	}
+	str, err := json.Marshal(msg)
+	if err != nil {
+		log.WarnMessage("Failed to convert a message to json: %s", err.Error())
+		return
+	}
+	sess.ws.Call("send", string(str))
+}
+
+func (sess *Session) status(connected bool) {
+	if sess.OnStatus != nil {
+		sess.OnStatus(connected)
+	}
+}
@@ -0,0 +1,34 @@
+package shared
+
+import (
+	"gitlab.com/256/DebateFrame/client/history"
+)
+
+// rebase moves the pending steps so they apply after a remote splice, which turned oldServer into newServer
+// It returns the new local blocks, the rebased steps, and whether a step changed the same blocks as the remote splice
+// Steps that can no longer be applied are dropped
+func rebase(oldServer []string, newServer []string, remote history.Splice, pending []Step) ([]string, []Step, bool) {
+	before := oldServer // The document each old step applied to
+	after := newServer  // The document each rebased step applies to
+	over := remote      // The remote change expressed against before
+	rebased := []Step{}
+	conflict := false
+	for _, st := range pending {
+		nextBefore, err := st.Splice.Apply(before)
+		if err != nil {
+			// The step never applied cleanly, so nothing after it can be trusted either
+			break
+		}
+		moved, overlapped := history.Transform(st.Splice, over)
+		nextAfter, err := moved.Apply(after)
+		if err != nil {
+			nextAfter = after
+		} else {
+			rebased = append(rebased, Step{ID: st.ID, Splice: moved})
+			conflict = conflict || overlapped
+		}
+		over, _ = history.Diff(nextBefore, nextAfter)
+		before, after = nextBefore, nextAfter
+	}
+	return after, rebased, conflict
+}
@@ -0,0 +1,73 @@
+package shared
+
+import (
+	"github.com/google/uuid"
+
+	"gitlab.com/256/DebateFrame/client/history"
+)
+
+// Step is a local change that the relay hasn't accepted yet
+type Step struct {
+	ID     string
+	Splice history.Splice
+}
+
+// Doc is one copy of a document shared through a relay, which puts every change in a single order
+// Local changes wait as steps until the relay accepts them, and are moved over the changes it accepted first
+type Doc struct {
+	Rev     int      // How many ops from the relay have been applied
+	Server  []string // The blocks after Rev ops
+	Local   []string // The blocks after the pending steps too, which is what the editor shows
+	Pending []Step
+	Sent    bool // True if Pending[0] was sent and no answer came back yet
+}
+
+// NewDoc returns a copy that hasn't seen any ops from the relay, with the blocks as its first step
+func NewDoc(blocks []string) *Doc {
+	doc := &Doc{Server: []string{}, Local: []string{}}
+	doc.Record(blocks)
+	return doc
+}
+
+// Record adds the change from the last known local blocks as a pending step without sending it
+func (doc *Doc) Record(blocks []string) {
+	splice, changed := history.Diff(doc.Local, blocks)
+	if !changed {
+		return
+	}
+	doc.Pending = append(doc.Pending, Step{ID: uuid.New().String(), Splice: splice})
+	doc.Local = blocks
+}
+
+// Waiting returns whether the op with the id is the oldest pending step, which is the one the relay answers next
+func (doc *Doc) Waiting(id string) bool {
+	return len(doc.Pending) > 0 && doc.Pending[0].ID == id
+}
+
+// Next returns the oldest pending step to send, unless there are none or one was sent and not answered yet
+func (doc *Doc) Next() (Step, bool) {
+	if doc.Sent || len(doc.Pending) == 0 {
+		return Step{}, false
+	}
+	doc.Sent = true
+	return doc.Pending[0], true
+}
+
+// Accept applies the next op the relay accepted, which is either the oldest pending step or a teammate's change
+// A teammate's change moves the pending steps over it, and it returns true if one of them changed the same blocks
+func (doc *Doc) Accept(id string, splice history.Splice) (bool, error) {
+	server, err := splice.Apply(doc.Server)
+	if err != nil {
+		return false, err
+	}
+	doc.Rev++
+	if doc.Waiting(id) {
+		doc.Server = server
+		doc.Pending = doc.Pending[1:]
+		doc.Sent = false
+		return false, nil
+	}
+	local, pending, conflict := rebase(doc.Server, server, splice, doc.Pending)
+	doc.Local, doc.Pending, doc.Server = local, pending, server
+	return conflict, nil
+}
@@ -0,0 +1,226 @@
+package shared
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+	"testing"
+
+	"gitlab.com/256/DebateFrame/client/history"
+)
+
+// testMessage is what the relay sends a client: an op it accepted, or a rejection of the client's op
+type testMessage struct {
+	reject bool
+	id     string
+	splice history.Splice
+}
+
+// testRelay puts ops in one order like relay.Hub does, accepting an op only if it was made after every op before it
+type testRelay struct {
+	t       *testing.T
+	blocks  []string
+	ops     int
+	clients []*testClient
+}
+
+type testClient struct {
+	name  string
+	doc   *Doc
+	inbox []testMessage // Sent by the relay and not received yet, in the order they were sent
+}
+
+func newTestRelay(t *testing.T, names ...string) *testRelay {
+	relay := &testRelay{t: t, blocks: []string{}}
+	for _, name := range names {
+		relay.clients = append(relay.clients, &testClient{name: name, doc: NewDoc([]string{})})
+	}
+	return relay
+}
+
+// send sends the client's next step to the relay, if it has one to send
+func (relay *testRelay) send(cl *testClient) {
+	st, ok := cl.doc.Next()
+	if !ok {
+		return
+	}
+	if cl.doc.Rev != relay.ops {
+		cl.inbox = append(cl.inbox, testMessage{reject: true})
+		return
+	}
+	blocks, err := st.Splice.Apply(relay.blocks)
+	if err != nil {
+		relay.t.Fatalf("%s sent an op that doesn't apply to the relay's copy: %s", cl.name, err)
+	}
+	relay.blocks = blocks
+	relay.ops++
+	for _, other := range relay.clients {
+		other.inbox = append(other.inbox, testMessage{id: st.ID, splice: st.Splice})
+	}
+}
+
+// receive has the client handle the oldest message the relay sent it, like Session.receive does
+// It returns whether the message was a teammate's op that changed the same blocks as a pending step
+func (relay *testRelay) receive(cl *testClient) bool {
+	if len(cl.inbox) == 0 {
+		return false
+	}
+	msg := cl.inbox[0]
+	cl.inbox = cl.inbox[1:]
+	if msg.reject {
+		cl.doc.Sent = false
+		return false
+	}
+	conflict, err := cl.doc.Accept(msg.id, msg.splice)
+	if err != nil {
+		relay.t.Fatalf("%s couldn't apply op %v from the relay: %s", cl.name, cl.doc.Rev, err)
+	}
+	return conflict
+}
+
+// settle sends and receives until nothing is left waiting anywhere
+func (relay *testRelay) settle() {
+	for busy := true; busy; {
+		busy = false
+		for _, cl := range relay.clients {
+			relay.send(cl)
+			if len(cl.inbox) > 0 {
+				relay.receive(cl)
+				busy = true
+			}
+		}
+	}
+}
+
+// checkConverged checks every client ended up with the relay's copy and nothing left to send
+func (relay *testRelay) checkConverged(t *testing.T, name string) {
+	for _, cl := range relay.clients {
+		if len(cl.doc.Pending) != 0 {
+			t.Errorf("%s: %s still has %v steps to send", name, cl.name, len(cl.doc.Pending))
+		}
+		if strings.Join(cl.doc.Local, "") != strings.Join(relay.blocks, "") {
+			t.Errorf("%s: %s shows %q, the relay has %q", name, cl.name, cl.doc.Local, relay.blocks)
+		}
+		if strings.Join(cl.doc.Server, "") != strings.Join(relay.blocks, "") {
+			t.Errorf("%s: %s thinks the relay has %q, it has %q", name, cl.name, cl.doc.Server, relay.blocks)
+		}
+	}
+}
+
+// edit returns the blocks with the splice applied, failing the test if it doesn't apply
+func edit(t *testing.T, blocks []string, splice history.Splice) []string {
+	result, err := splice.Apply(blocks)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return result
+}
+
+func TestConcurrentEdits(t *testing.T) {
+	start := []string{"<p>A</p>", "<p>B</p>", "<p>C</p>"}
+	tests := []struct {
+		name     string
+		one      history.Splice
+		two      history.Splice
+		want     []string
+		conflict bool
+	}{
+		{"different blocks",
+			history.Splice{Index: 0, Removed: []string{"<p>A</p>"}, Inserted: []string{"<p>A1</p>"}},
+			history.Splice{Index: 2, Removed: []string{"<p>C</p>"}, Inserted: []string{"<p>C2</p>"}},
+			[]string{"<p>A1</p>", "<p>B</p>", "<p>C2</p>"}, false},
+		{"inserts in the same place",
+			history.Splice{Index: 1, Inserted: []string{"<p>X1</p>"}},
+			history.Splice{Index: 1, Inserted: []string{"<p>X2</p>"}},
+			[]string{"<p>A</p>", "<p>X2</p>", "<p>X1</p>", "<p>B</p>", "<p>C</p>"}, false},
+		{"deleting a block the other changed",
+			history.Splice{Index: 1, Removed: []string{"<p>B</p>"}},
+			history.Splice{Index: 1, Removed: []string{"<p>B</p>"}, Inserted: []string{"<p>B2</p>"}},
+			[]string{"<p>A</p>", "<p>B2</p>", "<p>C</p>"}, true},
+		{"both changing a block",
+			history.Splice{Index: 1, Removed: []string{"<p>B</p>"}, Inserted: []string{"<p>B1</p>"}},
+			history.Splice{Index: 1, Removed: []string{"<p>B</p>"}, Inserted: []string{"<p>B2</p>"}},
+			[]string{"<p>A</p>", "<p>B1</p>", "<p>B2</p>", "<p>C</p>"}, true},
+		{"both making the same change",
+			history.Splice{Index: 1, Removed: []string{"<p>B</p>"}, Inserted: []string{"<p>B1</p>"}},
+			history.Splice{Index: 1, Removed: []string{"<p>B</p>"}, Inserted: []string{"<p>B1</p>"}},
+			[]string{"<p>A</p>", "<p>B1</p>", "<p>C</p>"}, false},
+	}
+	for _, test := range tests {
+		relay := newTestRelay(t, "one", "two")
+		one, two := relay.clients[0], relay.clients[1]
+		one.doc.Record(start)
+		relay.settle()
+
+		// Both edit before seeing the other's edit, and one's reaches the relay first
+		one.doc.Record(edit(t, one.doc.Local, test.one))
+		two.doc.Record(edit(t, two.doc.Local, test.two))
+		relay.send(one)
+		// two hasn't seen one's edit, so the relay rejects two's
+		relay.send(two)
+		conflict := relay.receive(two)
+		if conflict != test.conflict {
+			t.Errorf("%s: got a conflict %v, want %v", test.name, conflict, test.conflict)
+		}
+		relay.settle()
+		relay.checkConverged(t, test.name)
+		if strings.Join(relay.blocks, "") != strings.Join(test.want, "") {
+			t.Errorf("%s: ended with %q, want %q", test.name, relay.blocks, test.want)
+		}
+	}
+}
+
+// randomEdit inserts, deletes or replaces a random run of blocks
+func randomEdit(rng *rand.Rand, blocks []string, name string, n int) []string {
+	index := rng.Intn(len(blocks) + 1)
+	removed := 0
+	if index < len(blocks) {
+		removed = rng.Intn(len(blocks)-index+1) % 3
+	}
+	inserted := []string{}
+	for i := rng.Intn(3); i > 0; i-- {
+		inserted = append(inserted, fmt.Sprintf("<p>%s %v.%v</p>", name, n, i))
+	}
+	result := append([]string{}, blocks[:index]...)
+	result = append(result, inserted...)
+	return append(result, blocks[index+removed:]...)
+}
+
+// TestRandomEdits has clients edit, send and receive in random orders, and checks they all end up with the same copy
+func TestRandomEdits(t *testing.T) {
+	for seed := int64(0); seed < 200; seed++ {
+		rng := rand.New(rand.NewSource(seed))
+		relay := newTestRelay(t, "one", "two", "three")
+		relay.clients[0].doc.Record([]string{"<p>A</p>", "<p>B</p>", "<p>C</p>", "<p>D</p>"})
+		relay.settle()
+		for n := 0; n < 60; n++ {
+			cl := relay.clients[rng.Intn(len(relay.clients))]
+			switch rng.Intn(3) {
+			case 0:
+				cl.doc.Record(randomEdit(rng, cl.doc.Local, cl.name, n))
+			case 1:
+				relay.send(cl)
+			case 2:
+				relay.receive(cl)
+			}
+		}
+		relay.settle()
+		relay.checkConverged(t, fmt.Sprintf("seed %v", seed))
+	}
+}
+
+func TestNewDoc(t *testing.T) {
+	empty := NewDoc([]string{})
+	if len(empty.Pending) != 0 {
+		t.Errorf("an empty copy has %v steps to send", len(empty.Pending))
+	}
+	blocks := []string{"<p>A</p>", "<p>B</p>"}
+	hosted := NewDoc(blocks)
+	st, ok := hosted.Next()
+	if !ok || len(hosted.Pending) != 1 || strings.Join(st.Splice.Inserted, "") != strings.Join(blocks, "") {
+		t.Errorf("a hosted copy sends %+v, want its blocks", st)
+	}
+	if _, ok := hosted.Next(); ok {
+		t.Error("Next sent another step before the first was answered")
+	}
+}
@@ -2,9 +2,8 @@ package config
 
 import (
 	"encoding/json"
-	"strings"
-
 	"fmt"
+	"time"
 
 	"github.com/dennwc/dom"
 	"github.com/dennwc/dom/storage"
@@ -14,15 +13,48 @@ import (
 )
 
 // CurrentConfig is the current Configuration of DebateFrame
-var CurrentConfig Configuration
+var CurrentConfig = Default()
 
 // LocalStorage is the JS equivalent of LocalStorage
 var LocalStorage storage.Storage
 
+const (
+	configKey = "config"        // The LocalStorage key the Configuration is saved under
+	backupKey = "config-backup" // The LocalStorage key prefix that unreadable Configurations are moved to
+
+	defaultSnapshotRetention = 20
+	defaultRelayURL          = "ws://localhost:8080/relay"
+	defaultServerURL         = "http://localhost:8080"
+	defaultCiteStyle         = "debate"
+)
+
 // Configuration holds everything needed to replicate a DebateFrame instance
 // All fields that should be saved must start with an uppercase letter to be saved by json
 type Configuration struct {
-	FinishedWizard bool
+	Version           int // The schema version the Configuration was saved with
+	FinishedWizard    bool
+	SnapshotRetention int    // How many autosave snapshots are kept for each case
+	AuthorName        string // The name saved with each revision of a case
+	RelayURL          string // The collaboration relay last used, like ws://192.168.1.5:8080/relay
+	ServerURL         string // The server holding the team's cases, like http://192.168.1.5:8080
+	ServerUser        string // The account logged in to the server, or empty if not logged in
+	ServerToken       string // The login token for ServerUser
+	CiteStyle         string // The style cites are written in, like debate or mla, or custom to use CiteTemplate
+	CiteTemplate      string // The team's own cite template, used when CiteStyle is custom
+
+	// unknown holds fields that this version of DebateFrame doesn't know about so that they are not lost when saving
+	unknown map[string]interface{}
+}
+
+// Default returns the Configuration used when nothing has been saved yet
+func Default() Configuration {
+	return Configuration{
+		Version:           CurrentVersion,
+		SnapshotRetention: defaultSnapshotRetention,
+		RelayURL:          defaultRelayURL,
+		ServerURL:         defaultServerURL,
+		CiteStyle:         defaultCiteStyle,
+	}
 }
 
 func init() {
@@ -43,14 +75,31 @@ func (config *Configuration) saveState() error {
 		return errors.Wrap(err, "failed to get json string of Configuration")
 	}
 
-	LocalStorage.SetItem("config", str)
+	LocalStorage.SetItem(configKey, str)
 
 	return nil
 }
 
 // jsonString converts the Configuration to a string that can be saved in a location and later loaded
 func jsonString(config Configuration) (string, error) {
-	cfgString, err := json.Marshal(config)
+	known, err := json.Marshal(config)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to convert object to json")
+	}
+	if len(config.unknown) == 0 {
+		return string(known), nil
+	}
+
+	// Put the unknown fields back so a newer version of DebateFrame still finds them
+	raw := make(map[string]interface{})
+	for key, value := range config.unknown {
+		raw[key] = value
+	}
+	err = json.Unmarshal(known, &raw)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to merge unknown fields into the Configuration")
+	}
+	cfgString, err := json.Marshal(raw)
 	if err != nil {
 		return "", errors.Wrap(err, "failed to convert object to json")
 	}
@@ -59,29 +108,45 @@ func jsonString(config Configuration) (string, error) {
 }
 
 // RestoreState gets the last Configuration used, and makes the website use it
+// If the saved Configuration can't be read, it is backed up in LocalStorage and the default Configuration is used instead
 func RestoreState() error {
-	var err error
-	CurrentConfig, err = restoreFromStorage()
+	jsonString, ok := LocalStorage.GetItem(configKey)
+	if !ok {
+		log.DebugMessage("No saved Configuration found, using the default one")
+		CurrentConfig = Default()
+		return nil
+	}
+
+	conf, err := decode(jsonString)
 	if err != nil {
-		return errors.Wrap(err, "failed to restore Configuration from local storage")
+		key := fmt.Sprintf("%s-%v", backupKey, time.Now().Unix())
+		LocalStorage.SetItem(key, jsonString)
+		CurrentConfig = Default()
+		return errors.Wrapf(err, "failed to restore Configuration from local storage, the old one was kept under %s", key)
 	}
-	log.WarnMessage("RestoreState doesn't work as intended")
+	CurrentConfig = conf
 	return nil
 }
 
-// restoreFromStorage gets the current Configuration from storage and sets the Configuration to it
-func restoreFromStorage() (Configuration, error) {
-	jsonString, ok := LocalStorage.GetItem("config")
-	if !ok {
-		return Configuration{}, fmt.Errorf("failed to load json from local storage")
+// decode reads a Configuration saved by any version of DebateFrame, migrating it to the current version
+func decode(jsonString string) (Configuration, error) {
+	raw := make(map[string]interface{})
+	err := json.Unmarshal([]byte(jsonString), &raw)
+	if err != nil {
+		return Configuration{}, errors.Wrap(err, "failed to decode Configuration from json")
+	}
+
+	err = migrate(raw)
+	if err != nil {
+		return Configuration{}, errors.Wrap(err, "failed to migrate Configuration")
 	}
 
-	var conf Configuration
-	dec := json.NewDecoder(strings.NewReader(jsonString))
-	err := dec.Decode(&conf)
+	conf := Default()
+	err = remarshal(raw, &conf)
 	if err != nil {
-		log.PanicMessage("failed to decode Configuration from json. panicking because given config may be in a bad state now", err)
+		return Configuration{}, errors.Wrap(err, "failed to decode migrated Configuration")
 	}
 
+	conf.unknown = unknownFields(raw)
 	return conf, nil
 }
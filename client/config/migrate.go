@@ -0,0 +1,118 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+
+	"github.com/pkg/errors"
+
+	"gitlab.com/256/DebateFrame/client/log"
+)
+
+// migration upgrades a raw Configuration by one version, changing it in place
+type migration func(raw map[string]interface{}) error
+
+// migrations holds every migration in order, so migrations[i] upgrades a Configuration from version i to version i+1
+// To change the Configuration schema, append a migration here. Never edit or remove old ones!
+var migrations = []migration{
+	// 0 -> 1: Configurations before versioning only had FinishedWizard, which is unchanged
+	func(raw map[string]interface{}) error {
+		return nil
+	},
+	// 1 -> 2: Added SnapshotRetention
+	func(raw map[string]interface{}) error {
+		raw["SnapshotRetention"] = defaultSnapshotRetention
+		return nil
+	},
+	// 2 -> 3: Added AuthorName
+	func(raw map[string]interface{}) error {
+		raw["AuthorName"] = ""
+		return nil
+	},
+	// 3 -> 4: Added RelayURL
+	func(raw map[string]interface{}) error {
+		raw["RelayURL"] = defaultRelayURL
+		return nil
+	},
+	// 4 -> 5: Added ServerURL
+	func(raw map[string]interface{}) error {
+		raw["ServerURL"] = defaultServerURL
+		return nil
+	},
+	// 5 -> 6: Added ServerUser and ServerToken
+	func(raw map[string]interface{}) error {
+		raw["ServerUser"] = ""
+		raw["ServerToken"] = ""
+		return nil
+	},
+	// 6 -> 7: Added CiteStyle and CiteTemplate
+	func(raw map[string]interface{}) error {
+		raw["CiteStyle"] = defaultCiteStyle
+		raw["CiteTemplate"] = ""
+		return nil
+	},
+}
+
+// CurrentVersion is the schema version of Configuration used by this version of DebateFrame
+var CurrentVersion = len(migrations)
+
+// migrate runs every migration needed to bring the raw Configuration up to CurrentVersion
+func migrate(raw map[string]interface{}) error {
+	version, err := rawVersion(raw)
+	if err != nil {
+		return err
+	}
+	if version > CurrentVersion {
+		log.WarnMessage("Configuration was saved by a newer version of DebateFrame (version %v, expected %v)", version, CurrentVersion)
+		return nil
+	}
+	for ; version < CurrentVersion; version++ {
+		log.DebugMessage("Migrating Configuration from version %v to %v", version, version+1)
+		err := migrations[version](raw)
+		if err != nil {
+			return errors.Wrapf(err, "failed to migrate from version %v to %v", version, version+1)
+		}
+		raw["Version"] = version + 1
+	}
+	return nil
+}
+
+// rawVersion gets the schema version of a raw Configuration, treating a missing version as 0
+func rawVersion(raw map[string]interface{}) (int, error) {
+	value, ok := raw["Version"]
+	if !ok {
+		return 0, nil
+	}
+	num, ok := value.(float64)
+	if !ok || num < 0 {
+		return 0, fmt.Errorf("bad Configuration version: %v", value)
+	}
+	return int(num), nil
+}
+
+// unknownFields returns the fields of the raw Configuration that don't exist in Configuration
+func unknownFields(raw map[string]interface{}) map[string]interface{} {
+	known := make(map[string]bool)
+	confType := reflect.TypeOf(Configuration{})
+	for i := 0; i < confType.NumField(); i++ {
+		known[confType.Field(i).Name] = true
+	}
+
+	unknown := make(map[string]interface{})
+	for key, value := range raw {
+		if !known[key] {
+			unknown[key] = value
+		}
+	}
+	return unknown
+}
+
+// remarshal converts between two json representations of the same data, such as a raw map and a struct
+func remarshal(from interface{}, to interface{}) error {
+	bytes, err := json.Marshal(from)
+	if err != nil {
+		return errors.Wrap(err, "failed to convert to json")
+	}
+	return json.Unmarshal(bytes, to)
+}
@@ -0,0 +1,92 @@
+package cutter
+
+import (
+	"errors"
+	"fmt"
+	"html"
+	"regexp"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+
+	"gitlab.com/256/DebateFrame/client/cite"
+)
+
+// Source is everything known about a piece of evidence before it becomes a card
+type Source struct {
+	Tag      string    // The claim the card makes
+	Cite     cite.Cite // Where the card is from
+	Template string    // The cite template to use, or empty for the default style
+	Body     string    // The article as pasted, which can be HTML or plain text
+	Level    uint8     // The heading level to use for the tag
+}
+
+// Cut turns the source into the HTML of a card: a tag heading, a cite line and the body
+// Every cite style starts with the author's last name and the last two digits of the year, which is what card parsing looks for
+func Cut(src Source) (string, error) {
+	tag := strings.TrimSpace(src.Tag)
+	if tag == "" {
+		return "", errors.New("the card needs a tag")
+	}
+	tmpl := src.Template
+	if strings.TrimSpace(tmpl) == "" {
+		tmpl = cite.Styles[cite.Default]
+	}
+	citeHTML, err := cite.Format(src.Cite, tmpl)
+	if err != nil {
+		return "", err
+	}
+	paragraphs, err := Paragraphs(src.Body)
+	if err != nil {
+		return "", err
+	}
+	if len(paragraphs) == 0 {
+		return "", errors.New("the card needs some text")
+	}
+	level := src.Level
+	if level < 1 || level > 6 {
+		level = 4
+	}
+
+	out := &strings.Builder{}
+	fmt.Fprintf(out, "<h%v>%s</h%v>", level, html.EscapeString(tag), level)
+	fmt.Fprintf(out, "<p>%s</p>", citeHTML)
+	for _, para := range paragraphs {
+		fmt.Fprintf(out, "<p>%s</p>", html.EscapeString(para))
+	}
+	return out.String(), nil
+}
+
+// blockTags are the elements that start a new paragraph when reading pasted HTML
+const blockTags = "p, div, li, blockquote, h1, h2, h3, h4, h5, h6, br"
+
+// Paragraphs splits pasted text or HTML into paragraphs of plain text
+func Paragraphs(body string) ([]string, error) {
+	if !strings.Contains(body, "<") {
+		return textParagraphs(body), nil
+	}
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the pasted HTML: %s", err.Error())
+	}
+	doc.Find("script, style, noscript").Remove()
+	// Mark where blocks end so the text can be split on them
+	doc.Find(blockTags).Each(func(_ int, sel *goquery.Selection) {
+		sel.AppendHtml("\n\n")
+	})
+	return textParagraphs(doc.Find("body").Text()), nil
+}
+
+var blankLineRp = regexp.MustCompile(`\n\s*\n`)
+
+// textParagraphs splits plain text on blank lines, joining the lines of each paragraph
+func textParagraphs(text string) []string {
+	paragraphs := []string{}
+	for _, para := range blankLineRp.Split(strings.Replace(text, "\r\n", "\n", -1), -1) {
+		para = strings.Join(strings.Fields(para), " ")
+		if para != "" {
+			paragraphs = append(paragraphs, para)
+		}
+	}
+	return paragraphs
+}
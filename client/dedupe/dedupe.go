@@ -0,0 +1,201 @@
+package dedupe
+
+import (
+	"hash/fnv"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Card is a card of evidence and where it is
+type Card struct {
+	Doc        string // The ID of the case the card is in
+	DocName    string
+	Level      uint8 // The heading level of the card's tag
+	Heading    int   // Which heading of that level holds the tag, counting from 0
+	Tag        string
+	Cite       string
+	Body       string
+	Highlights []string // The text of each highlighted part of the card
+}
+
+// Highlighted returns how many letters of the card are highlighted
+func (card Card) Highlighted() int {
+	count := 0
+	for _, highlight := range card.Highlights {
+		count += len(strings.TrimSpace(highlight))
+	}
+	return count
+}
+
+// Group is a set of cards that are the same card cut more than once
+type Group struct {
+	Cards []Card // The best version is first
+}
+
+const (
+	shingleSize = 5  // Words per shingle
+	hashes      = 64 // Values in a MinHash signature
+	bands       = 16 // Signatures are split into this many bands, and cards sharing any band are compared
+	rows        = hashes / bands
+	minWords    = 20  // Cards with fewer words are too short to tell apart from quotes of each other
+	threshold   = 0.6 // How much of their shingles two cards must share to be duplicates
+)
+
+// seeds make the hash functions of the signature different from each other
+var seeds = func() []uint64 {
+	seeds := make([]uint64, hashes)
+	state := uint64(0x9e3779b97f4a7c15)
+	for i := range seeds {
+		state = mix(state + uint64(i))
+		seeds[i] = state
+	}
+	return seeds
+}()
+
+// mix is the finalizer of splitmix64, which spreads the bits of a number
+func mix(x uint64) uint64 {
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+	return x
+}
+
+// Words splits text into lowercase words without punctuation, so formatting and retyped quotes don't matter
+func Words(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+}
+
+// shingles returns the hashes of every run of shingleSize words in the text
+func shingles(text string) map[uint64]bool {
+	words := Words(text)
+	set := make(map[uint64]bool)
+	for i := 0; i+shingleSize <= len(words); i++ {
+		hash := fnv.New64a()
+		hash.Write([]byte(strings.Join(words[i:i+shingleSize], " ")))
+		set[hash.Sum64()] = true
+	}
+	return set
+}
+
+// signature returns the MinHash signature of a set of shingles
+func signature(set map[uint64]bool) []uint64 {
+	sig := make([]uint64, hashes)
+	for i := range sig {
+		sig[i] = ^uint64(0)
+	}
+	for shingle := range set {
+		for i, seed := range seeds {
+			if value := mix(shingle ^ seed); value < sig[i] {
+				sig[i] = value
+			}
+		}
+	}
+	return sig
+}
+
+// jaccard returns how much of their shingles two sets share
+func jaccard(one map[uint64]bool, two map[uint64]bool) float64 {
+	shared := 0
+	for shingle := range one {
+		if two[shingle] {
+			shared++
+		}
+	}
+	all := len(one) + len(two) - shared
+	if all == 0 {
+		return 0
+	}
+	return float64(shared) / float64(all)
+}
+
+// Find groups cards whose bodies are nearly the same, ignoring tags since the same card is often tagged differently
+// Cards are only compared when their MinHash signatures share a band, so large libraries don't compare every pair
+func Find(cards []Card) []Group {
+	sets := make([]map[uint64]bool, len(cards))
+	buckets := make(map[uint64][]int)
+	for i, card := range cards {
+		if len(Words(card.Body)) < minWords {
+			continue
+		}
+		sets[i] = shingles(card.Body)
+		sig := signature(sets[i])
+		for band := 0; band < bands; band++ {
+			hash := fnv.New64a()
+			for _, value := range sig[band*rows : (band+1)*rows] {
+				for shift := uint(0); shift < 64; shift += 8 {
+					hash.Write([]byte{byte(value >> shift)})
+				}
+			}
+			// The band number is part of the key so different bands never share a bucket
+			key := hash.Sum64() ^ uint64(band)
+			buckets[key] = append(buckets[key], i)
+		}
+	}
+
+	parent := make([]int, len(cards))
+	for i := range parent {
+		parent[i] = i
+	}
+	var root func(i int) int
+	root = func(i int) int {
+		for parent[i] != i {
+			parent[i] = parent[parent[i]]
+			i = parent[i]
+		}
+		return i
+	}
+	compared := make(map[[2]int]bool)
+	for _, bucket := range buckets {
+		for a := 0; a < len(bucket); a++ {
+			for b := a + 1; b < len(bucket); b++ {
+				pair := [2]int{bucket[a], bucket[b]}
+				if compared[pair] || root(pair[0]) == root(pair[1]) {
+					continue
+				}
+				compared[pair] = true
+				if jaccard(sets[pair[0]], sets[pair[1]]) >= threshold {
+					parent[root(pair[0])] = root(pair[1])
+				}
+			}
+		}
+	}
+
+	byRoot := make(map[int][]Card)
+	order := []int{}
+	for i, card := range cards {
+		if sets[i] == nil {
+			continue
+		}
+		r := root(i)
+		if _, ok := byRoot[r]; !ok {
+			order = append(order, r)
+		}
+		byRoot[r] = append(byRoot[r], card)
+	}
+	groups := []Group{}
+	for _, r := range order {
+		if len(byRoot[r]) < 2 {
+			continue
+		}
+		group := Group{Cards: byRoot[r]}
+		sort.SliceStable(group.Cards, func(a, b int) bool {
+			return better(group.Cards[a], group.Cards[b])
+		})
+		groups = append(groups, group)
+	}
+	return groups
+}
+
+// better returns whether one version of a card should be kept over another
+// The version with the most highlighting wins, since highlighting is the work that goes into a card, then the longest one
+func better(one Card, two Card) bool {
+	if one.Highlighted() != two.Highlighted() {
+		return one.Highlighted() > two.Highlighted()
+	}
+	return len(one.Body) > len(two.Body)
+}
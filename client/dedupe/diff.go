@@ -0,0 +1,63 @@
+package dedupe
+
+// Change is a run of words that only one version of a card has
+type Change struct {
+	Text  string
+	Added bool // The other version has the words and the best one doesn't, otherwise only the best one has them
+}
+
+// maxDiffWords is the longest card that is compared word by word, since the comparison takes length squared memory
+const maxDiffWords = 3000
+
+// Differences returns the words that differ between the best version of a card and another version
+// Words are compared as Words returns them, so differences in formatting and punctuation don't show up
+func Differences(best string, other string) []Change {
+	one, two := Words(best), Words(other)
+	if len(one) > maxDiffWords || len(two) > maxDiffWords {
+		return nil
+	}
+	// common[i][j] is the length of the longest common subsequence of one[i:] and two[j:]
+	common := make([][]int, len(one)+1)
+	for i := range common {
+		common[i] = make([]int, len(two)+1)
+	}
+	for i := len(one) - 1; i >= 0; i-- {
+		for j := len(two) - 1; j >= 0; j-- {
+			if one[i] == two[j] {
+				common[i][j] = common[i+1][j+1] + 1
+			} else if common[i+1][j] >= common[i][j+1] {
+				common[i][j] = common[i+1][j]
+			} else {
+				common[i][j] = common[i][j+1]
+			}
+		}
+	}
+
+	changes := []Change{}
+	// inRun is whether the last change is still going, since a run ends where the versions agree again
+	inRun := false
+	add := func(word string, added bool) {
+		if n := len(changes); inRun && changes[n-1].Added == added {
+			changes[n-1].Text += " " + word
+			return
+		}
+		changes = append(changes, Change{Text: word, Added: added})
+		inRun = true
+	}
+	i, j := 0, 0
+	for i < len(one) || j < len(two) {
+		switch {
+		case i < len(one) && j < len(two) && one[i] == two[j]:
+			inRun = false
+			i++
+			j++
+		case j < len(two) && (i == len(one) || common[i][j+1] >= common[i+1][j]):
+			add(two[j], true)
+			j++
+		default:
+			add(one[i], false)
+			i++
+		}
+	}
+	return changes
+}
@@ -0,0 +1,74 @@
+package disclosure
+
+import (
+	"fmt"
+	"html"
+	"strings"
+
+	"gitlab.com/256/DebateFrame/client/document/card"
+)
+
+// excerptWords is how many words from each end of a card are shown in cites mode
+const excerptWords = 3
+
+// Mode is which disclosure format to produce
+type Mode int
+
+const (
+	// Cites lists the tag, cite and first and last words of each card, which is what most wikis ask for
+	Cites Mode = iota
+	// FullText is the whole document, for open sourcing it
+	FullText
+)
+
+// Doc is one document to disclose, like a case or one of the speech docs from a round
+type Doc struct {
+	Name  string
+	Cards []*card.Card
+	HTML  string // The body of the document, which is only needed for FullText
+}
+
+// Export produces the disclosure of the docs as an HTML document
+func Export(docs []Doc, mode Mode) string {
+	out := &strings.Builder{}
+	out.WriteString("<!DOCTYPE html>\n<html>\n<head>\n<meta charset=\"UTF-8\">\n<title>Disclosure</title>\n</head>\n<body>\n")
+	for _, doc := range docs {
+		fmt.Fprintf(out, "<h1>%s</h1>\n", html.EscapeString(doc.Name))
+		if mode == FullText {
+			out.WriteString(doc.HTML)
+			out.WriteString("\n")
+			continue
+		}
+		for _, cd := range doc.Cards {
+			fmt.Fprintf(out, "<h4>%s</h4>\n", html.EscapeString(strings.TrimSpace(cd.Title)))
+			fmt.Fprintf(out, "<p>%s</p>\n", html.EscapeString(cd.Cite))
+			fmt.Fprintf(out, "<p>%s</p>\n", html.EscapeString(Excerpt(cd.Body)))
+		}
+	}
+	out.WriteString("</body>\n</html>\n")
+	return out.String()
+}
+
+// CitesText lists the tag, cite and first and last words of each card as plain text, to paste into a wiki's cites box
+func CitesText(docs []Doc) string {
+	out := &strings.Builder{}
+	for _, doc := range docs {
+		fmt.Fprintf(out, "%s\n\n", doc.Name)
+		for _, cd := range doc.Cards {
+			fmt.Fprintf(out, "%s\n%s\n%s\n\n", strings.TrimSpace(cd.Title), cd.Cite, Excerpt(cd.Body))
+		}
+	}
+	return out.String()
+}
+
+// Excerpt shortens text to its first and last few words, like "The plan solves ... for every state"
+// Text that is already that short is returned as is
+func Excerpt(text string) string {
+	words := strings.Fields(text)
+	if len(words) <= excerptWords*2 {
+		return strings.Join(words, " ")
+	}
+	return fmt.Sprintf("%s ... %s",
+		strings.Join(words[:excerptWords], " "),
+		strings.Join(words[len(words)-excerptWords:], " "))
+}
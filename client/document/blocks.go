@@ -0,0 +1,76 @@
+package document
+
+import (
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/pkg/errors"
+
+	"gitlab.com/256/DebateFrame/client/sections"
+)
+
+// blocksOf splits a document into its blocks, the HTML of each top level node of its body
+func blocksOf(doc *goquery.Document) []string {
+	blocks := []string{}
+	doc.Find("body").Contents().Each(func(_ int, sel *goquery.Selection) {
+		html, err := goquery.OuterHtml(sel)
+		if err == nil {
+			blocks = append(blocks, html)
+		}
+	})
+	return blocks
+}
+
+// blocksOfHTML splits HTML into blocks
+func blocksOfHTML(html string) ([]string, error) {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(html))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create document from html")
+	}
+	return blocksOf(doc), nil
+}
+
+// joinBlocks turns blocks back into HTML
+func joinBlocks(blocks []string) string {
+	return strings.Join(blocks, "")
+}
+
+// findHeading returns the index of the block holding the nth heading of the level, or -1 if there isn't one,
+// and whether the heading is the block itself rather than nested in it
+// Headings nested in other elements count like they do for GetCards, so n is the Heading of a card
+func findHeading(blocks []string, level uint8, n int) (int, bool) {
+	for i, block := range blocks {
+		for j, found := range sections.Headings(block) {
+			if found != level {
+				continue
+			}
+			if n == 0 {
+				return i, j == 0 && sections.Level(block) == level
+			}
+			n--
+		}
+	}
+	return -1, false
+}
+
+// headingBlock returns the index of the block that is the nth heading of the level, or -1 if there isn't one
+// A heading nested in another element doesn't start a section of its own, so that is -1 too
+func headingBlock(blocks []string, level uint8, n int) int {
+	index, top := findHeading(blocks, level, n)
+	if !top {
+		return -1
+	}
+	return index
+}
+
+// sectionEnd returns the index after the last block belonging to the heading at start
+// A section ends at the next heading of the same or a bigger level
+func sectionEnd(blocks []string, start int) int {
+	level := sections.Level(blocks[start])
+	for i := start + 1; i < len(blocks); i++ {
+		if next := sections.Level(blocks[i]); next != 0 && (level == 0 || next <= level) {
+			return i
+		}
+	}
+	return len(blocks)
+}
@@ -3,6 +3,7 @@ package card
 import (
 	"fmt"
 
+	"gitlab.com/256/DebateFrame/client/kind"
 	"gitlab.com/256/WebFrame/dyndom"
 )
 
@@ -11,12 +12,21 @@ const strictCards = true
 
 // Card represents a card of evidence
 type Card struct {
-	Title    string
-	Contents string
-	URL      string
-	Year     uint8
-	Author   string
-	Element  *dyndom.Element // A reference to the actual element on the page
+	Title      string
+	Contents   string
+	URL        string
+	Year       uint8
+	Author     string
+	Cite       string          // The line after the tag that says where the card is from
+	Body       string          // The text of the card after the cite, with a line for each block
+	Highlights []string        // The text of each highlighted part of the card, in order
+	Level      uint8           // The heading level of the card's tag
+	Heading    int             // Which heading of that level holds the tag, counting from 0 in document order
+	ID         string          // The id of the tag, which stays the same as the document is edited
+	Labels     []string        // The labels of the tag and the headings it is under
+	Block      string          // The text of the closest bigger heading the card is under
+	Kind       kind.Kind       // What the card does in an argument, guessed from its tag
+	Element    *dyndom.Element // A reference to the actual element on the page
 }
 
 // GenerateElement generates an element card from a debate card
@@ -30,6 +40,16 @@ func (card *Card) GenerateElement() {
 	author := dyndom.CreateElement("h4")
 	author.SetTextContent(cleanString(fmt.Sprintf("%s %v", card.Author, card.Year)))
 	cardDiv.AppendChild(author)
+	if card.Kind != "" && card.Kind != kind.Other {
+		cardKind := dyndom.CreateElement("div", "uk-text-meta")
+		cardKind.SetTextContent(string(card.Kind))
+		cardDiv.AppendChild(cardKind)
+	}
+	for _, label := range card.Labels {
+		span := dyndom.CreateElement("span", "uk-label", "cardLabel")
+		span.SetTextContent(label)
+		cardDiv.AppendChild(span)
+	}
 	// The contents need to be in a "read more" thing
 	/*
 		content := dom.NewElement("p")
@@ -0,0 +1,99 @@
+package card
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ChangeKind says how a card changed between two versions of a document
+type ChangeKind int
+
+const (
+	// Added cards only exist in the newer version
+	Added ChangeKind = iota
+	// Removed cards only exist in the older version
+	Removed
+	// Modified cards exist in both versions with different text or highlighting
+	Modified
+)
+
+func (kind ChangeKind) String() string {
+	switch kind {
+	case Added:
+		return "Added"
+	case Removed:
+		return "Removed"
+	case Modified:
+		return "Modified"
+	}
+	return "Unknown"
+}
+
+// Change is a difference in a single card between two versions of a document
+type Change struct {
+	Kind             ChangeKind
+	Old              *Card // nil if the card was added
+	New              *Card // nil if the card was removed
+	TextChanged      bool
+	HighlightChanged bool
+}
+
+// Key identifies a card across versions of a document, so a card with a retyped body is still the same card
+func (card *Card) Key() string {
+	return fmt.Sprintf("%s|%s|%v", normalize(card.Title), normalize(card.Author), card.Year)
+}
+
+// Diff compares the cards of two versions of a document, returning changes in the order of the newer version
+// with removed cards at the end
+func Diff(old []*Card, new []*Card) []Change {
+	// Several cards can share a key, so they are matched up in order
+	oldByKey := make(map[string][]*Card)
+	for _, card := range old {
+		oldByKey[card.Key()] = append(oldByKey[card.Key()], card)
+	}
+
+	changes := []Change{}
+	for _, card := range new {
+		key := card.Key()
+		matches := oldByKey[key]
+		if len(matches) == 0 {
+			changes = append(changes, Change{Kind: Added, New: card})
+			continue
+		}
+		oldCard := matches[0]
+		oldByKey[key] = matches[1:]
+
+		change := Change{Kind: Modified, Old: oldCard, New: card}
+		change.TextChanged = normalize(oldCard.Contents) != normalize(card.Contents)
+		change.HighlightChanged = !sameStrings(oldCard.Highlights, card.Highlights)
+		if change.TextChanged || change.HighlightChanged {
+			changes = append(changes, change)
+		}
+	}
+
+	for _, card := range old {
+		matches := oldByKey[card.Key()]
+		if len(matches) > 0 && matches[0] == card {
+			changes = append(changes, Change{Kind: Removed, Old: card})
+			oldByKey[card.Key()] = matches[1:]
+		}
+	}
+	return changes
+}
+
+// normalize lowercases the string and collapses whitespace so formatting changes don't count as edits
+func normalize(str string) string {
+	return strings.Join(strings.Fields(strings.ToLower(str)), " ")
+}
+
+func sameStrings(one []string, two []string) bool {
+	if len(one) != len(two) {
+		return false
+	}
+	for i := range one {
+		if normalize(one[i]) != normalize(two[i]) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,81 @@
+package card
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// LabelsAttr is the attribute of a heading that holds its labels, so they are saved with the document
+const LabelsAttr = "data-labels"
+
+// ParseLabels splits a comma separated list of labels, dropping empty and repeated ones
+func ParseLabels(str string) []string {
+	labels := []string{}
+	seen := make(map[string]bool)
+	for _, label := range strings.Split(str, ",") {
+		label = strings.Join(strings.Fields(label), " ")
+		if label == "" || seen[strings.ToLower(label)] {
+			continue
+		}
+		seen[strings.ToLower(label)] = true
+		labels = append(labels, label)
+	}
+	return labels
+}
+
+// FormatLabels joins labels into the list ParseLabels reads
+func FormatLabels(labels []string) string {
+	return strings.Join(labels, ", ")
+}
+
+// HasLabel returns whether the card has the label, ignoring case
+func (card *Card) HasLabel(label string) bool {
+	for _, has := range card.Labels {
+		if strings.EqualFold(has, label) {
+			return true
+		}
+	}
+	return false
+}
+
+// HeadingLabels returns the labels of every heading of the level, in document order
+// A heading has its own labels and the labels of the headings it is under, so labelling a block labels its cards
+func HeadingLabels(doc *goquery.Document, level uint8) [][]string {
+	all := [][]string{}
+	// open holds the labels of the last heading of each level that hasn't been closed by a bigger heading
+	open := make([][]string, 7)
+	doc.Find("h1, h2, h3, h4, h5, h6").Each(func(_ int, sel *goquery.Selection) {
+		hlev := getHeaderLevel(goquery.NodeName(sel))
+		for i := hlev; i < uint8(len(open)); i++ {
+			open[i] = nil
+		}
+		attr, _ := sel.Attr(LabelsAttr)
+		open[hlev] = ParseLabels(attr)
+		if hlev != level {
+			return
+		}
+		labels := []string{}
+		for _, some := range open[1 : hlev+1] {
+			labels = append(labels, some...)
+		}
+		all = append(all, ParseLabels(FormatLabels(labels)))
+	})
+	return all
+}
+
+var labelQueryRp = regexp.MustCompile(`(?i)\blabel:(?:"([^"]*)"|(\S+))`)
+
+// parseQuery splits the label: filters out of a search, like label:econ or label:"econ da"
+func parseQuery(query string) ([]string, string) {
+	labels := []string{}
+	for _, match := range labelQueryRp.FindAllStringSubmatch(query, -1) {
+		label := match[1] + match[2]
+		if label = strings.TrimSpace(label); label != "" {
+			labels = append(labels, label)
+		}
+	}
+	rest := strings.Join(strings.Fields(labelQueryRp.ReplaceAllString(query, " ")), " ")
+	return labels, rest
+}
@@ -8,35 +8,96 @@ import (
 	"fmt"
 
 	"github.com/PuerkitoBio/goquery"
-	"github.com/montanaflynn/stats"
+	"golang.org/x/net/html"
 
+	"gitlab.com/256/DebateFrame/client/kind"
 	"gitlab.com/256/DebateFrame/client/log"
+	"gitlab.com/256/DebateFrame/client/sections"
 )
 
 // GetCards gets the cards from a document
 func GetCards(doc *goquery.Document) []*Card {
+	return getCards(doc, strictCards)
+}
+
+// GetEveryCard gets a card from every tag of a document, including the tags without an author and year that GetCards leaves out
+func GetEveryCard(doc *goquery.Document) []*Card {
+	return getCards(doc, false)
+}
+
+func getCards(doc *goquery.Document, strict bool) []*Card {
 	// Get most frequent header
-	headerTags := []float64{}
+	headerTags := []uint8{}
 	var header = regexp.MustCompile(`H\d`)
 	doc.Find("*").Each(func(_ int, sel *goquery.Selection) {
 		tag := strings.ToUpper(goquery.NodeName(sel))
 		if header.MatchString(tag) {
-			headerTags = append(headerTags, float64(getHeaderLevel(tag)))
+			headerTags = append(headerTags, getHeaderLevel(tag))
 		}
 	})
-	if len(headerTags) == 0 {
+	mostFreq, ok := sections.TagLevel(headerTags)
+	if !ok {
 		return []*Card{}
 	}
-	mostFreqList, err := stats.Mode(headerTags)
-	if err != nil {
-		log.PanicMessage("could not calculate mode of H levels", err)
-	}
-	mostFreq := uint8(mostFreqList[0])
 	log.DebugMessage(fmt.Sprintf("Most frequent H tag: %v", mostFreq))
 
-	sections := getCardSections(mostFreq, doc.Find("*"))
+	return getCardsAt(doc, mostFreq, strict)
+}
+
+// GetCardsAt gets the cards from a document whose tags are headings of the level
+func GetCardsAt(doc *goquery.Document, level uint8) []*Card {
+	return getCardsAt(doc, level, strictCards)
+}
+
+func getCardsAt(doc *goquery.Document, level uint8, strict bool) []*Card {
+	sections := getCardSections(level, doc.Find("*"))
+
+	cards := getCardsFromSections(level, sections, strict)
+	labels := HeadingLabels(doc, level)
+	blocks := HeadingBlocks(doc, level)
+	ids := HeadingIDs(doc, level)
+	for _, card := range cards {
+		if card.Heading < len(labels) {
+			card.Labels = labels[card.Heading]
+			card.Block = blocks[card.Heading]
+			card.ID = ids[card.Heading]
+		}
+	}
+	return cards
+}
+
+// HeadingBlocks returns the text of the closest bigger heading above every heading of the level, in document order
+// Headings that aren't under a bigger heading get an empty string
+func HeadingBlocks(doc *goquery.Document, level uint8) []string {
+	all := []string{}
+	open := make([]string, 7)
+	doc.Find("h1, h2, h3, h4, h5, h6").Each(func(_ int, sel *goquery.Selection) {
+		hlev := getHeaderLevel(goquery.NodeName(sel))
+		for i := hlev; i < uint8(len(open)); i++ {
+			open[i] = ""
+		}
+		open[hlev] = strings.Join(strings.Fields(sel.Text()), " ")
+		if hlev != level {
+			return
+		}
+		block := ""
+		for i := hlev - 1; i >= 1 && block == ""; i-- {
+			block = open[i]
+		}
+		all = append(all, block)
+	})
+	return all
+}
 
-	return getCardsFromSections(sections)
+// HeadingIDs returns the id of every heading of the level, in document order
+// Headings without one get an empty string
+func HeadingIDs(doc *goquery.Document, level uint8) []string {
+	all := []string{}
+	doc.Find(fmt.Sprintf("h%v", level)).Each(func(_ int, sel *goquery.Selection) {
+		id, _ := sel.Attr("id")
+		all = append(all, id)
+	})
+	return all
 }
 
 func getHeaderLevel(tag string) uint8 {
@@ -67,13 +128,18 @@ func getCardSections(hlev uint8, children *goquery.Selection) [][]*goquery.Selec
 	return sections
 }
 
-func getCardsFromSections(sections [][]*goquery.Selection) (cards []*Card) {
-	for _, section := range sections {
+func getCardsFromSections(hlev uint8, sections [][]*goquery.Selection, strict bool) (cards []*Card) {
+	for i, section := range sections {
 		card := Card{}
 		card.Title = section[0].Text()
+		card.Level = hlev
+		card.Heading = i
 		var text string
 		for _, snippit := range section[1:] {
 			text = text + "\n" + snippit.Text()
+			if strings.ToLower(goquery.NodeName(snippit)) == "mark" {
+				card.Highlights = append(card.Highlights, snippit.Text())
+			}
 		}
 		if len(section) >= 2 {
 			startLine := section[1].Text()
@@ -81,8 +147,10 @@ func getCardsFromSections(sections [][]*goquery.Selection) (cards []*Card) {
 			card.Author = author
 			card.Year = year
 		}
+		card.Cite, card.Body = citeAndBody(section)
+		card.Kind = kind.Classify(card.Title)
 		card.Contents = text
-		if strictCards && (len(card.Contents) == 0 || len(card.Author) == 0 || card.Year == 0 || len(card.Title) == 0) {
+		if strict && (len(card.Contents) == 0 || len(card.Author) == 0 || card.Year == 0 || len(card.Title) == 0) {
 			log.DebugMessage(fmt.Sprintf("Card with title \"%v\" was blocked by strictCards setting", card.Title))
 		} else {
 			cards = append(cards, &card)
@@ -92,6 +160,28 @@ func getCardsFromSections(sections [][]*goquery.Selection) (cards []*Card) {
 	return
 }
 
+// citeAndBody returns the text of the first block after the tag, and the text of the blocks after that
+// Sections hold nested elements too, so only elements that aren't inside another element of the section count as blocks
+func citeAndBody(section []*goquery.Selection) (string, string) {
+	inSection := make(map[*html.Node]bool)
+	for _, sel := range section {
+		inSection[sel.Nodes[0]] = true
+	}
+	blocks := []string{}
+	for _, sel := range section[1:] {
+		if inSection[sel.Nodes[0].Parent] {
+			continue
+		}
+		if text := strings.TrimSpace(sel.Text()); text != "" {
+			blocks = append(blocks, text)
+		}
+	}
+	if len(blocks) == 0 {
+		return "", ""
+	}
+	return blocks[0], strings.Join(blocks[1:], "\n")
+}
+
 var rp = regexp.MustCompile(`([A-Z]+\w+|[A-Z]+\w+\s*&\s*[A-Z]+\w+|[A-Z]+\w+\s+et\s+al|[A-Z]+\w+\s+and\s+[A-Z]+\w+),?\s+‘?'?(\d{4}|\d{1,2}|\d{1,2}[-,/]\d{1,2}[-,/]\d{1,4})[\s,\,]*`)
 
 // Returns the lastname of the author, and the last two digits of the year
@@ -44,8 +44,16 @@ const showThreshold = 30
 const minCards = 4
 
 // search returns a slice with the correct order of cards that should be shown
-func search(cards []*Card, str string) []*Card {
-	log.DebugMessage("Searching cards with query %s", str)
+// Cards without every label asked for with label: are left out
+func search(cards []*Card, query string) []*Card {
+	log.DebugMessage("Searching cards with query %s", query)
+	labels, str := parseQuery(query)
+	if len(labels) > 0 {
+		cards = withLabels(cards, labels)
+		if str == "" {
+			return cards
+		}
+	}
 	pointLevels := []int{}
 	pointsCards := make(map[int][]*Card)
 	for _, card := range cards {
@@ -77,6 +85,21 @@ func search(cards []*Card, str string) []*Card {
 	return matchedCards
 }
 
+// withLabels returns the cards that have all of the labels
+func withLabels(cards []*Card, labels []string) []*Card {
+	matched := []*Card{}
+	for _, card := range cards {
+		all := true
+		for _, label := range labels {
+			all = all && card.HasLabel(label)
+		}
+		if all {
+			matched = append(matched, card)
+		}
+	}
+	return matched
+}
+
 func getValue(query string, card *Card) int {
 	var points int
 	if hasSharedWord(card.Author, query) {
@@ -86,6 +109,7 @@ func getValue(query string, card *Card) int {
 		points += 4
 	}
 	points += sharedWordCount(query, card.Title) * 3
+	points += sharedWordCount(query, strings.Join(card.Labels, " ")) * 3
 	points += sharedWordCount(query, card.Contents)
 	return points
 }
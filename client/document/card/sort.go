@@ -0,0 +1,52 @@
+package card
+
+import (
+	"sort"
+	"strings"
+)
+
+// The orders cards can be sorted in
+const (
+	ByPosition = ""
+	ByAuthor   = "author"
+	ByDate     = "date"
+	ByTag      = "tag"
+	ByWords    = "words"
+)
+
+// Sort orders the cards in place, keeping document order between cards that are the same
+// Dates and word counts go newest and longest first, since those are the cards people look for
+func Sort(cards []*Card, by string) {
+	less := func(a *Card, b *Card) bool { return false }
+	switch by {
+	case ByAuthor:
+		less = func(a *Card, b *Card) bool { return strings.ToLower(a.Author) < strings.ToLower(b.Author) }
+	case ByDate:
+		less = func(a *Card, b *Card) bool { return fullYear(a.Year) > fullYear(b.Year) }
+	case ByTag:
+		less = func(a *Card, b *Card) bool {
+			return strings.ToLower(strings.TrimSpace(a.Title)) < strings.ToLower(strings.TrimSpace(b.Title))
+		}
+	case ByWords:
+		less = func(a *Card, b *Card) bool { return a.Words() > b.Words() }
+	}
+	sort.SliceStable(cards, func(i, j int) bool {
+		return less(cards[i], cards[j])
+	})
+}
+
+// Words returns how many words the card has after its cite
+func (card *Card) Words() int {
+	if card.Body != "" {
+		return len(strings.Fields(card.Body))
+	}
+	return len(strings.Fields(card.Contents))
+}
+
+// fullYear turns the two digit year of a card into a year that sorts properly, guessing that years past 50 are last century
+func fullYear(year uint8) int {
+	if year > 50 {
+		return 1900 + int(year)
+	}
+	return 2000 + int(year)
+}
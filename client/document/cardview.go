@@ -2,28 +2,48 @@ package document
 
 import (
 	"fmt"
+	"html"
+	"sort"
+	"strings"
+
+	"github.com/dennwc/dom"
 
 	"gitlab.com/256/DebateFrame/client/document/card"
+	"gitlab.com/256/DebateFrame/client/kind"
 	"gitlab.com/256/DebateFrame/client/log"
 	"gitlab.com/256/DebateFrame/client/waiter"
 	"gitlab.com/256/WebFrame/dyndom"
 )
 
-func cardView(cards []*card.Card) *dyndom.Element {
+// CardViewOptions are how the card view of a case shows its cards, which are saved with the case
+// The zero value shows the cards in document order, ungrouped, as a grid
+// It is part of case files, so its fields can't change without a new caseVersion
+type CardViewOptions struct {
+	Sort    string // One of the card.By orders
+	Group   string // One of the GroupBy groupings
+	List    bool   // Show the cards as rows instead of a grid
+	Compact bool   // Show the cards smaller so more fit on screen
+}
+
+// The ways the card view can group cards
+const (
+	GroupByNone  = ""
+	GroupByBlock = "block"
+	GroupByLabel = "label"
+	GroupByKind  = "kind"
+)
+
+func cardView(cs *Case) *dyndom.Element {
 	cView := cardViewElem()
-	fmt.Println(cView.Children("div"))
-	parent := cView.Children("div")[1]
 	search := cView.Child("div")
-	for _, card := range cards {
-		if card.Element == nil {
-			card.GenerateElement()
-		}
-		parent.AppendChild(card.Element)
-	}
+	cs.CardView = cView
+	bindCardOptions(cs)
+	renderCards(cs)
 	lastQuery := ""
 
 	waiter.EventWaiter(&search.NodeBase, "input", 300, func() {
 		query := search.JSValue().Get("value").String()
+		cards := cs.viewCards
 		if query != "" && lastQuery != query {
 			go inputEvent(cards, query)
 			lastQuery = query
@@ -42,6 +62,185 @@ func cardView(cards []*card.Card) *dyndom.Element {
 	return cView
 }
 
+// bindCardOptions shows the card view options of the case and saves them with it when they are changed
+func bindCardOptions(cs *Case) {
+	selects := cs.CardView.Children("div")[1].Children("select")
+	sortSelect, groupSelect, layoutSelect, densitySelect := selects[0], selects[1], selects[2], selects[3]
+	layout, density := "grid", "normal"
+	if cs.View.List {
+		layout = "list"
+	}
+	if cs.View.Compact {
+		density = "compact"
+	}
+	sortSelect.JSValue().Set("value", cs.View.Sort)
+	groupSelect.JSValue().Set("value", cs.View.Group)
+	layoutSelect.JSValue().Set("value", layout)
+	densitySelect.JSValue().Set("value", density)
+	applyCardLayout(cs)
+
+	changed := func(e dom.Event) {
+		cs.View.Sort = sortSelect.JSValue().Get("value").String()
+		cs.View.Group = groupSelect.JSValue().Get("value").String()
+		cs.View.List = layoutSelect.JSValue().Get("value").String() == "list"
+		cs.View.Compact = densitySelect.JSValue().Get("value").String() == "compact"
+		applyCardLayout(cs)
+		renderCards(cs)
+		go persistCase(cs)
+	}
+	for _, sel := range selects {
+		sel.AddEventListener("change", changed)
+	}
+}
+
+// applyCardLayout sets the layout and density classes of the card view
+func applyCardLayout(cs *Case) {
+	classes := cs.CardView.ClassList()
+	classes.Remove("cardList")
+	classes.Remove("cardCompact")
+	if cs.View.List {
+		classes.Add("cardList")
+	}
+	if cs.View.Compact {
+		classes.Add("cardCompact")
+	}
+}
+
+// renderCards replaces the cards shown in the card view of the case with its current cards
+func renderCards(cs *Case) {
+	parent := cs.CardView.Children("div")[2]
+	parent.SetInnerHTML("")
+	cs.viewCards = toCards(cs.Cards)
+	placeCards(cs.Document, cs.viewCards)
+	for i, card := range cs.viewCards {
+		if card.Element == nil {
+			card.GenerateElement()
+		}
+		card.Element.AppendChild(cardActions(cs, cs.Cards[i]))
+		linkCard(cs, card, cs.Cards[i])
+		draggableCard(cs, card)
+	}
+	// The actions are attached first, since they pair the cards with their info cards by position
+	card.Sort(cs.viewCards, cs.View.Sort)
+	if cs.View.Group == GroupByNone {
+		for _, c := range cs.viewCards {
+			parent.AppendChild(c.Element)
+		}
+		return
+	}
+	titles, groups := groupCards(cs.viewCards, cs.View.Group)
+	for _, title := range titles {
+		parent.AppendChild(cardGroupElem(title, groups[title]))
+	}
+}
+
+// groupCards splits the cards into groups, returning the titles of the groups in the order they are shown
+// Cards with several labels are grouped under their first, since a card can only be shown once
+func groupCards(cards []*card.Card, by string) ([]string, map[string][]*card.Card) {
+	titles := []string{}
+	groups := make(map[string][]*card.Card)
+	add := func(title string, c *card.Card) {
+		if _, ok := groups[title]; !ok {
+			titles = append(titles, title)
+		}
+		groups[title] = append(groups[title], c)
+	}
+	for _, c := range cards {
+		switch by {
+		case GroupByBlock:
+			if c.Block == "" {
+				add("Not in a block", c)
+			} else {
+				add(c.Block, c)
+			}
+		case GroupByLabel:
+			if len(c.Labels) == 0 {
+				add("No label", c)
+			} else {
+				add(c.Labels[0], c)
+			}
+		case GroupByKind:
+			add(string(c.Kind), c)
+		}
+	}
+	switch by {
+	case GroupByKind:
+		// Kinds go in the order of an argument rather than the order they come up
+		titles = titles[:0]
+		for _, k := range kind.Kinds {
+			if _, ok := groups[string(k)]; ok {
+				titles = append(titles, string(k))
+			}
+		}
+	case GroupByLabel:
+		sort.SliceStable(titles, func(a, b int) bool {
+			if titles[a] == "No label" || titles[b] == "No label" {
+				return titles[b] == "No label" && titles[a] != "No label"
+			}
+			return strings.ToLower(titles[a]) < strings.ToLower(titles[b])
+		})
+	}
+	return titles, groups
+}
+
+// cardGroupElem creates a titled group of cards in the card view
+func cardGroupElem(title string, cards []*card.Card) *dyndom.Element {
+	group := dyndom.CreateElement("div", "cardGroup")
+	heading := dyndom.CreateElement("h4", "uk-heading-line")
+	heading.SetInnerHTML(fmt.Sprintf("<span>%s (%v)</span>", html.EscapeString(title), len(cards)))
+	group.AppendChild(heading)
+	groupCards := dyndom.CreateElement("div", "uk-flex", "uk-flex-around", "uk-flex-wrap")
+	for _, c := range cards {
+		groupCards.AppendChild(c.Element)
+	}
+	group.AppendChild(groupCards)
+	return group
+}
+
+// cardActions creates the buttons shown on a card in the card view
+func cardActions(cs *Case, icard *InfoCard) *dyndom.Element {
+	actions := dyndom.CreateElement("div", "cardActions")
+	send := newCardButton("push", "Send to speech doc")
+	send.AddEventListener("click", func(e dom.Event) {
+		go func() {
+			err := cs.SendCard(icard, speechCase)
+			if err != nil {
+				notify(err.Error(), "warning")
+			}
+		}()
+	})
+	remove := newCardButton("trash", "Delete card")
+	remove.AddEventListener("click", func(e dom.Event) {
+		go func() {
+			err := cs.DeleteCard(icard)
+			if err != nil {
+				notify(err.Error(), "warning")
+			}
+		}()
+	})
+	labels := newCardButton("tag", "Edit labels")
+	labels.AddEventListener("click", func(e dom.Event) {
+		go func() {
+			err := cs.EditCardLabels(icard)
+			if err != nil {
+				notify(err.Error(), "warning")
+			}
+		}()
+	})
+	actions.AppendChild(send)
+	actions.AppendChild(labels)
+	actions.AppendChild(remove)
+	return actions
+}
+
+func newCardButton(iconName string, title string) *dyndom.Element {
+	button := dyndom.CreateElement("a", "uk-icon-link")
+	button.SetAttribute("href", "#")
+	button.SetAttribute("title", title)
+	button.SetAttribute("uk-icon", fmt.Sprintf("icon: %s", iconName))
+	return button
+}
+
 /*
 <div>
    <div class="uk-search uk-search-large">
@@ -57,6 +256,7 @@ func cardView(cards []*card.Card) *dyndom.Element {
 func cardViewElem() *dyndom.Element {
 	div := dyndom.CreateElement("div")
 	div.AppendChild(searchDivElem())
+	div.AppendChild(optionsDivElem())
 	div.AppendChild(cardDivElem())
 	return div
 }
@@ -69,12 +269,51 @@ func searchDivElem() *dyndom.Element {
 	input.SetId("cardSearch")
 	input.ClassList().Add("uk-search-input")
 	input.SetAttribute("type", "search")
-	input.SetAttribute("placeholder", "Search...")
+	input.SetAttribute("placeholder", "Search, or label:name to filter by label...")
 	div.AppendChild(icon)
 	div.AppendChild(input)
 	return div
 }
 
+func optionsDivElem() *dyndom.Element {
+	div := dyndom.CreateElement("div", "cardOptions")
+	div.AppendChild(optionSelectElem("Sort cards", [][2]string{
+		{card.ByPosition, "In document order"},
+		{card.ByAuthor, "By author"},
+		{card.ByDate, "Newest first"},
+		{card.ByTag, "By tag"},
+		{card.ByWords, "Longest first"},
+	}))
+	div.AppendChild(optionSelectElem("Group cards", [][2]string{
+		{GroupByNone, "Not grouped"},
+		{GroupByBlock, "Grouped by block"},
+		{GroupByLabel, "Grouped by label"},
+		{GroupByKind, "Grouped by what they do"},
+	}))
+	div.AppendChild(optionSelectElem("Layout", [][2]string{
+		{"grid", "Grid"},
+		{"list", "List"},
+	}))
+	div.AppendChild(optionSelectElem("Density", [][2]string{
+		{"normal", "Normal"},
+		{"compact", "Compact"},
+	}))
+	return div
+}
+
+// optionSelectElem creates a select with the values and names of the options
+func optionSelectElem(title string, options [][2]string) *dyndom.Element {
+	sel := dyndom.CreateElement("select", "uk-select", "uk-form-small", "uk-form-width-medium")
+	sel.SetAttribute("title", title)
+	for _, option := range options {
+		opt := dyndom.CreateElement("option")
+		opt.SetAttribute("value", option[0])
+		opt.SetTextContent(option[1])
+		sel.AppendChild(opt)
+	}
+	return sel
+}
+
 func cardDivElem() *dyndom.Element {
 	div := dyndom.CreateElement("div", "uk-flex", "uk-flex-around", "uk-flex-wrap")
 	div.SetId("cards")
@@ -10,17 +10,22 @@ import (
 	"github.com/google/uuid"
 	"github.com/pkg/errors"
 
+	"gitlab.com/256/DebateFrame/client/collab"
 	"gitlab.com/256/DebateFrame/client/document/card"
 	"gitlab.com/256/DebateFrame/client/filesaver"
+	"gitlab.com/256/DebateFrame/client/history"
+	"gitlab.com/256/DebateFrame/client/kind"
 	"gitlab.com/256/DebateFrame/client/log"
 	"gitlab.com/256/DebateFrame/client/medium"
-	"gitlab.com/256/DebateFrame/client/tocbot"
+	"gitlab.com/256/DebateFrame/client/sections"
+	"gitlab.com/256/DebateFrame/client/waiter"
 	"gitlab.com/256/WebFrame/dyndom"
 	"gitlab.com/256/WebFrame/waquery"
 )
 
 // Case is an object that holds information relating to a debate case
 type Case struct {
+	ID         string // Identifies the case in storage, and stays the same across saves and loads
 	Name       string
 	Cards      []*InfoCard
 	Document   *goquery.Document
@@ -28,6 +33,24 @@ type Case struct {
 	Editor     *medium.Editor
 	EditorElem *dyndom.Element
 	TOCElem    *dyndom.Element
+	CardView   *dyndom.Element
+	History    *history.Stack
+	Revisions  []Revision      // Every saved version of the case, oldest first
+	Collab     *collab.Session // The session keeping the case in sync with teammates, or nil if it isn't shared
+	View       CardViewOptions // How the card view shows the cards
+
+	viewCards    []*card.Card        // The cards currently shown in the card view
+	viewButton   *dyndom.Element     // Switches between the editor and the card view
+	dropSection  *js.Callback        // Moves a section that was dragged onto another, shared by everything that can be dragged
+	blocks       []string            // The blocks of the document when the history last saw it
+	parsed       sections.Cache      // The cards of each section the last time the cards were found
+	navShown     string              // The headings the navigation pane was last drawn from
+	navCollapsed map[string]bool     // The headings collapsed in the navigation pane
+	teammates    map[string]teammate // Where the cursors of teammates are, by user
+	presenceElem *dyndom.Element     // Holds the names shown next to the blocks teammates are editing
+	screen       []*dyndom.Element   // The tab, editor and navigation pane of the case, removed when it is closed
+	closed       bool                // Whether the tab was closed, so edits that were waiting aren't saved again
+	stops        []func()            // Stop listening to the events of the case, called when it is closed
 }
 
 // NewCase creates a new Case object from an HTML string
@@ -37,9 +60,12 @@ func NewCase(html string, name string) (*Case, error) {
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to create document from html")
 	}
+	stampDocument(doc, make(map[string]bool))
 	cs.Document = doc
 	cs.Cards = toInfoCards(card.GetCards(doc))
 	cs.Name = name
+	cs.ID = newUUID()
+	cs.History = &history.Stack{}
 	return &cs, nil
 }
 
@@ -52,12 +78,31 @@ func (cs *Case) Add() error {
 
 	link, uuid := newTabLink(cs.Name)
 	dom.GetDocument().GetElementById("doctab").AppendChild(&link.Element)
+	closeButton := newNavButton("close", "Close")
+	closeButton.ClassList().Add("tabClose")
+	closeButton.AddEventListener("click", func(e dom.Event) {
+		confirmed := dom.GetWindow().JSValue().Call("confirm", fmt.Sprintf("Close %s? Its copy in this browser is deleted, so download it first to keep it.", cs.Name)).Bool()
+		if confirmed {
+			go cs.Close()
+		}
+	})
+	link.AppendChild(closeButton)
 	cs.Button = dyndom.New(waquery.ToHTML(dom.GetDocument().GetElementById(fmt.Sprintf("%s-link", uuid))))
+	cs.Button.AddEventListener("click", func(e dom.Event) {
+		currentCase = cs
+		go persistSession()
+	})
 
 	// EDITOR
 
-	container := newEditorContainer(toCards(cs.Cards))
+	container := newEditorContainer()
+	cs.CardView = cardView(cs)
+	cs.CardView.ClassList().Add("simplehide")
+	container.AppendChild(cs.CardView)
+	cs.presenceElem = dyndom.CreateElement("div", "presenceLayer")
+	container.AppendChild(cs.presenceElem)
 	dom.GetDocument().GetElementById("editorSwitcher").AppendChild(&container.Element)
+	cs.screen = append(cs.screen, link, container)
 
 	editorID := fmt.Sprintf("%s_editor", dyndom.UUID())
 	container.Children("div")[1].SetId(editorID)
@@ -66,8 +111,33 @@ func (cs *Case) Add() error {
 	cs.Editor = editor
 	cs.EditorElem = container.Children("div")[1]
 	cs.Editor.SetContent(html, 0)
+	err = cs.startHistory()
+	if err != nil {
+		return errors.Wrap(err, "Failed to start the undo history")
+	}
+	cs.stops = append(cs.stops,
+		waiter.EventWaiter(&cs.EditorElem.NodeBase, "input", autosaveDelay, func() {
+			persistCase(cs)
+		}),
+		waiter.EventWaiter(&cs.EditorElem.NodeBase, "input", snapshotIdle, func() {
+			snapshotCase(cs)
+		}),
+		waiter.EventWaiter(&cs.EditorElem.NodeBase, "input", collabDelay, func() {
+			cs.sendCollab()
+		}),
+		waiter.EventWaiter(&cs.EditorElem.NodeBase, "input", reparseDelay, func() {
+			cs.reparse()
+		}),
+		waiter.EventWaiter(&dom.GetDocument().NodeBase, "selectionchange", collabDelay, func() {
+			cs.sendPresence()
+		}),
+	)
+	cs.EditorElem.AddEventListener("click", func(e dom.Event) {
+		cs.followReference(e.JSValue())
+	})
 
 	cViewButton := container.Children("div")[0].Child("a")
+	cs.viewButton = cViewButton
 	cViewButton.AddEventListener("click", func(e dom.Event) {
 		fmt.Println(cViewButton.GetAttribute("uk-icon").String())
 		if strings.Contains(cViewButton.GetAttribute("uk-icon").String(), "file-text") {
@@ -85,13 +155,18 @@ func (cs *Case) Add() error {
 
 	tocCont, uuid := newTOCCont()
 	dom.GetDocument().GetElementById("tocSwitcher").AppendChild(&tocCont.Element)
+	cs.screen = append(cs.screen, tocCont)
 
 	tocQuery := fmt.Sprintf("#%s-tocDiv", uuid)
 	cs.TOCElem = dyndom.New(waquery.ToHTML(dom.GetDocument().QuerySelector(tocQuery)))
 
-	tocbot.GenerateTOC(editorQuery, tocQuery)
+	cs.renderNav()
 
-	cardView(toCards(cs.Cards))
+	openCases = append(openCases, cs)
+	go func() {
+		persistCase(cs)
+		persistSession()
+	}()
 
 	return nil
 }
@@ -133,19 +208,22 @@ func newTabLink(text string) (*dyndom.Element, string) {
 	return item, uuid
 }
 
-func newEditorContainer(cards []*card.Card) *dyndom.Element {
+func newEditorContainer() *dyndom.Element {
 	editorCont := dyndom.CreateElement("div", "uk-card", "uk-card-body", "editorCont")
 	editorCont.AppendChild(newEditorToolbar())
 	editorCont.AppendChild(newEditorDiv())
-	cView := cardView(cards)
-	cView.ClassList().Add("simplehide")
-	editorCont.AppendChild(cView)
 	return editorCont
 }
 
 func newEditorToolbar() *dyndom.Element {
 	toolbarDiv := dyndom.CreateElement("div", "toolbar")
 	toolbarDiv.AppendChild(newCardViewButton())
+	toolbarDiv.AppendChild(newUndoButton())
+	toolbarDiv.AppendChild(newRedoButton())
+	toolbarDiv.AppendChild(newSpeechButton())
+	toolbarDiv.AppendChild(newRevisionsButton())
+	toolbarDiv.AppendChild(newReformatCitesButton())
+	toolbarDiv.AppendChild(newLabelBlockButton())
 	toolbarDiv.AppendChild(newDownloadButton())
 	return toolbarDiv
 }
@@ -163,6 +241,41 @@ func newDownloadButton() *dyndom.Element {
 	return downloadButton
 }
 
+func newUndoButton() *dyndom.Element {
+	undoButton := newToolbarButton("reply")
+	undoButton.SetAttribute("title", "Undo")
+	undoButton.AddEventListener("click", func(e dom.Event) {
+		go currentCase.Undo()
+	})
+	return undoButton
+}
+
+func newRedoButton() *dyndom.Element {
+	redoButton := newToolbarButton("forward")
+	redoButton.SetAttribute("title", "Redo")
+	redoButton.AddEventListener("click", func(e dom.Event) {
+		go currentCase.Redo()
+	})
+	return redoButton
+}
+
+func newSpeechButton() *dyndom.Element {
+	speechButton := newToolbarButton("microphone")
+	speechButton.SetAttribute("title", "Use as speech doc")
+	speechButton.AddEventListener("click", func(e dom.Event) {
+		speechCase = currentCase
+		notify(fmt.Sprintf("Cards will be sent to %s", currentCase.Name), "primary")
+	})
+	return speechButton
+}
+
+func newRevisionsButton() *dyndom.Element {
+	revisionsButton := newToolbarButton("history")
+	revisionsButton.SetAttribute("title", "Revisions")
+	revisionsButton.AddEventListener("click", OnRevisions)
+	return revisionsButton
+}
+
 func newToolbarButton(iconName string) *dyndom.Element {
 	button := dyndom.CreateElement("a", "uk-icon", "toolbarButton")
 	button.SetAttribute("href", "#")
@@ -177,11 +290,38 @@ func newEditorDiv() *dyndom.Element {
 
 // Update updates a debate case with the new HTML contents
 func (cs *Case) Update(html string) error {
-	var err error
-	cs, err = NewCase(html, cs.Name)
+	updated, err := NewCase(html, cs.Name)
+	if err != nil {
+		return errors.Wrap(err, "failed to update the debate case")
+	}
+	if cs.Editor == nil {
+		cs.Document = updated.Document
+		cs.Cards = updated.Cards
+		return nil
+	}
+	// A case on screen is changed like any other edit, so it can be undone and everything shown from it follows
+	blocks, err := blocksOfHTML(html)
 	if err != nil {
 		return errors.Wrap(err, "failed to update the debate case")
 	}
+	cs.recordEdit()
+	splice, changed := history.Diff(cs.blocks, blocks)
+	if !changed {
+		return nil
+	}
+	return cs.apply(history.Op{Label: "Update", Splices: []history.Splice{splice}})
+}
+
+// syncDocument updates the case document with what is currently in the editor
+func (cs *Case) syncDocument() error {
+	if cs.Editor == nil {
+		return nil
+	}
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(cs.Editor.GetContent(0)))
+	if err != nil {
+		return errors.Wrap(err, "failed to create document from the editor contents")
+	}
+	cs.Document = doc
 	return nil
 }
 
@@ -193,10 +333,15 @@ func (cs *Case) SetActive() error {
 }
 
 // SaveableCase is a version of Case that stores the document as HTML instead of as a goquery.Document to avoid issues with recursion limits
+// Case files save the fields by position, so new ones go at the end along with a new caseVersion
 type SaveableCase struct {
-	Name     string
-	Cards    []*InfoCard
-	Document string
+	Name      string
+	Cards     []*InfoCard
+	Document  string
+	ID        string
+	History   history.Stack
+	Revisions []Revision
+	View      CardViewOptions
 }
 
 // Saveable returns a version of the case that is saveable
@@ -209,6 +354,10 @@ func (cs *Case) Saveable() *SaveableCase {
 		log.PanicMessage("Failed to convert the goquery document to HTML", err)
 	}
 	scase.Name = cs.Name
+	scase.ID = cs.ID
+	scase.History = *cs.History
+	scase.Revisions = cs.Revisions
+	scase.View = cs.View
 	return &scase
 }
 
@@ -217,21 +366,35 @@ func (saveable *SaveableCase) Normalize() *Case {
 	var err error
 	cs := Case{}
 	cs.Name = saveable.Name
+	cs.ID = saveable.ID
+	if cs.ID == "" {
+		// Cases saved before IDs existed
+		cs.ID = newUUID()
+	}
 	cs.Cards = saveable.Cards
+	stack := saveable.History
+	cs.History = &stack
+	cs.Revisions = saveable.Revisions
+	cs.View = saveable.View
 	cs.Document, err = goquery.NewDocumentFromReader(strings.NewReader(saveable.Document))
 	if err != nil {
 		log.PanicMessage("Failed to convert the document HTML to a goquery document", err)
 	}
+	stampDocument(cs.Document, make(map[string]bool))
 	return &cs
 }
 
 // InfoCard represents a card of evidence without the attached element
+// Case files save the fields by position, so new ones go at the end along with a new caseVersion
 type InfoCard struct {
-	Title    string
-	Contents string
-	URL      string
-	Year     uint8
-	Author   string
+	Title      string
+	Contents   string
+	URL        string
+	Year       uint8
+	Author     string
+	Level      uint8
+	Heading    int
+	Highlights []string
 }
 
 // InfoCard -> Card
@@ -244,6 +407,11 @@ func (icard *InfoCard) toCard() *card.Card {
 	card.URL = icard.URL
 	card.Year = icard.Year
 	card.Author = icard.Author
+	card.Highlights = icard.Highlights
+	card.Level = icard.Level
+	card.Heading = icard.Heading
+	// The kind isn't saved with the case, since it can always be guessed again from the tag
+	card.Kind = kind.Classify(icard.Title)
 	return &card
 }
 
@@ -266,6 +434,9 @@ func toInfoCard(card *card.Card) *InfoCard {
 	icard.URL = card.URL
 	icard.Year = card.Year
 	icard.Author = card.Author
+	icard.Highlights = card.Highlights
+	icard.Level = card.Level
+	icard.Heading = card.Heading
 	return &icard
 }
 
@@ -283,8 +454,15 @@ func toInfoCards(cards []*card.Card) []*InfoCard {
 func caseSave() {
 	log.DebugMessage("Case Save initiated!")
 	log.DebugMessage("Converting to gob")
-	scase := currentCase.Saveable()
-	bytes, err := objToBytes(&scase)
+	err := currentCase.syncDocument()
+	if err != nil {
+		log.PanicMessage("Failed to read the case from the editor", err)
+	}
+	err = currentCase.addRevision()
+	if err != nil {
+		log.PanicMessage("Failed to add a revision to the case", err)
+	}
+	bytes, err := encodeCase(currentCase.Saveable())
 	if err != nil {
 		log.PanicMessage("Failed to convert the object into a Gob byte array", err)
 	}
@@ -293,12 +471,11 @@ func caseSave() {
 }
 
 func caseLoad(file *js.Value) error {
-	temp := SaveableCase{}
-	err := bytesToObj(blobToBytes(*file), &temp)
+	scase, err := decodeCase(blobToBytes(*file))
 	if err != nil {
-		fmt.Println("error encountered")
+		return errors.Wrapf(err, "Failed to read %s", file.Get("name").String())
 	}
-	cs := temp.Normalize()
+	cs := scase.Normalize()
 	err = cs.Add()
 	if err != nil {
 		return errors.Wrap(err, "Failed to add the DebateFrame case to the list of cases")
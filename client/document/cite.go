@@ -0,0 +1,105 @@
+package document
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/dennwc/dom"
+
+	"gitlab.com/256/DebateFrame/client/cite"
+	"gitlab.com/256/DebateFrame/client/config"
+	"gitlab.com/256/DebateFrame/client/history"
+	"gitlab.com/256/DebateFrame/client/log"
+	"gitlab.com/256/DebateFrame/client/sections"
+	"gitlab.com/256/WebFrame/dyndom"
+)
+
+// citeTemplate returns the cite template the team picked in the settings
+func citeTemplate() string {
+	return cite.Template(config.CurrentConfig.CiteStyle, config.CurrentConfig.CiteTemplate)
+}
+
+func newReformatCitesButton() *dyndom.Element {
+	reformatButton := newToolbarButton("quote-right")
+	reformatButton.SetAttribute("title", "Reformat cites")
+	reformatButton.AddEventListener("click", func(e dom.Event) {
+		go currentCase.ReformatCites()
+	})
+	return reformatButton
+}
+
+// ReformatCites rewrites the cite of every card in the case in the cite style from the settings
+// Cites that can't be read are left as they are
+func (cs *Case) ReformatCites() {
+	cs.recordEdit()
+	tmpl := citeTemplate()
+	level := cs.cardLevel()
+	op := history.Op{Label: "Reformat cites"}
+	skipped := 0
+	for i, block := range cs.blocks {
+		if sections.Level(block) != level {
+			continue
+		}
+		at := citeBlock(cs.blocks, i)
+		if at == -1 {
+			continue
+		}
+		ct, ok := cite.Parse(blockText(cs.blocks[at]))
+		if !ok {
+			skipped++
+			continue
+		}
+		formatted, err := cite.Format(ct, tmpl)
+		if err != nil {
+			log.DebugMessage("Failed to reformat the cite of block %v: %s", at, err.Error())
+			skipped++
+			continue
+		}
+		// The template escapes differently than blocks are written out, so the cite is turned into a block the same way before comparing
+		formattedBlocks, err := blocksOfHTML("<p>" + formatted + "</p>")
+		if err != nil || len(formattedBlocks) != 1 {
+			log.DebugMessage("The reformatted cite of block %v isn't a single block", at)
+			skipped++
+			continue
+		}
+		formatted = formattedBlocks[0]
+		if formatted == cs.blocks[at] {
+			continue
+		}
+		op.Splices = append(op.Splices, history.Splice{Index: at, Removed: []string{cs.blocks[at]}, Inserted: []string{formatted}})
+	}
+	if len(op.Splices) == 0 {
+		notify(fmt.Sprintf("No cites needed reformatting (%v couldn't be read)", skipped), "primary")
+		return
+	}
+	// Every splice replaces one block with one block, so the indexes stay right as they are applied
+	err := cs.apply(op)
+	if err != nil {
+		notify(err.Error(), "danger")
+		return
+	}
+	notify(fmt.Sprintf("Reformatted %v cites (%v couldn't be read)", len(op.Splices), skipped), "success")
+}
+
+// citeBlock returns the index of the first block after the tag that has text, or -1 if the card has no cite
+func citeBlock(blocks []string, tag int) int {
+	for i := tag + 1; i < len(blocks); i++ {
+		if sections.Level(blocks[i]) != 0 {
+			return -1
+		}
+		if blockText(blocks[i]) != "" {
+			return i
+		}
+	}
+	return -1
+}
+
+// blockText returns the text of a block without its markup
+func blockText(block string) string {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(block))
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(doc.Text())
+}
@@ -0,0 +1,307 @@
+package document
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"syscall/js"
+	"time"
+
+	"github.com/dennwc/dom"
+
+	"gitlab.com/256/DebateFrame/client/collab"
+	"gitlab.com/256/DebateFrame/client/config"
+	"gitlab.com/256/DebateFrame/client/history"
+	"gitlab.com/256/DebateFrame/client/importer"
+	"gitlab.com/256/DebateFrame/client/log"
+	"gitlab.com/256/WebFrame/dyndom"
+)
+
+// collabDelay is how many milliseconds typing has to stop for before it is sent to teammates
+const collabDelay = 300
+
+// teammate is where another person editing the case has their cursor
+type teammate struct {
+	Name  string
+	Block int
+}
+
+var collabBound = false
+
+// OnCollaborate is the event listener for when the Collaborate button is pressed
+func OnCollaborate(e dom.Event) {
+	if !collabBound {
+		dom.GetDocument().GetElementById("collabShare").AddEventListener("click", func(e dom.Event) {
+			go shareCase()
+		})
+		dom.GetDocument().GetElementById("collabJoin").AddEventListener("click", func(e dom.Event) {
+			go joinRoom()
+		})
+		dom.GetDocument().GetElementById("collabStop").AddEventListener("click", func(e dom.Event) {
+			stopCollab()
+		})
+		collabBound = true
+	}
+	setInputValue("collabRelay", config.CurrentConfig.RelayURL)
+	setInputValue("collabRoom", "")
+	status := "This case isn't shared"
+	if currentCase != nil && currentCase.Collab != nil {
+		status = fmt.Sprintf("Shared with room code %s", currentCase.Collab.Room)
+	}
+	dom.GetDocument().GetElementById("collabStatus").SetTextContent(status)
+	showModal("modal-collab")
+}
+
+// relayURL reads the relay from the collaboration form, remembering it for next time
+// The login token for the team tub is added, since the relay runs on the same server and browsers can't send it as a header
+func relayURL() (string, error) {
+	relay := strings.TrimSpace(inputValue("collabRelay"))
+	if !strings.HasPrefix(relay, "ws://") && !strings.HasPrefix(relay, "wss://") {
+		return "", fmt.Errorf("the relay address must start with ws:// or wss://")
+	}
+	config.CurrentConfig.RelayURL = relay
+	if config.CurrentConfig.ServerToken == "" {
+		return relay, nil
+	}
+	separator := "?"
+	if strings.Contains(relay, "?") {
+		separator = "&"
+	}
+	return relay + separator + "token=" + url.QueryEscape(config.CurrentConfig.ServerToken), nil
+}
+
+// shareCase puts the current case on the relay so teammates can join it with its ID
+func shareCase() {
+	if currentCase == nil {
+		notify("Open a case to share first", "warning")
+		return
+	}
+	if currentCase.Collab != nil {
+		notify(fmt.Sprintf("This case is already shared with room code %s", currentCase.Collab.Room), "primary")
+		return
+	}
+	url, err := relayURL()
+	if err != nil {
+		notify(err.Error(), "warning")
+		return
+	}
+	cs := currentCase
+	cs.recordEdit()
+	cs.startCollab(collab.Host(url, cs.ID, collabName(), cs.blocks))
+	hideModal("modal-collab")
+	notify(fmt.Sprintf("Teammates can join with the room code %s", cs.ID), "success")
+}
+
+// joinRoom opens the case a teammate shared as a new case
+func joinRoom() {
+	url, err := relayURL()
+	if err != nil {
+		notify(err.Error(), "warning")
+		return
+	}
+	room := strings.TrimSpace(inputValue("collabRoom"))
+	if room == "" {
+		notify("Enter the room code your teammate was given", "warning")
+		return
+	}
+	cs, err := NewCase("", "Shared case")
+	if err != nil {
+		log.WarnMessage("Failed to create a case for the room: %s", err.Error())
+		return
+	}
+	err = cs.Add()
+	if err != nil {
+		log.WarnMessage("Failed to add the shared case: %s", err.Error())
+		return
+	}
+	cs.SetActive()
+	cs.startCollab(collab.Join(url, room, collabName()))
+	hideModal("modal-collab")
+}
+
+// stopCollab stops sharing the current case, keeping what it has so far
+func stopCollab() {
+	cs := currentCase
+	if cs == nil || cs.Collab == nil {
+		return
+	}
+	cs.Collab.Close()
+	cs.Collab = nil
+	cs.teammates = nil
+	cs.renderPresence()
+	hideModal("modal-collab")
+	notify(fmt.Sprintf("Stopped sharing %s", cs.Name), "primary")
+}
+
+func collabName() string {
+	if config.CurrentConfig.AuthorName != "" {
+		return config.CurrentConfig.AuthorName
+	}
+	return "Teammate"
+}
+
+// startCollab connects the case to a collaboration session
+func (cs *Case) startCollab(sess *collab.Session) {
+	cs.Collab = sess
+	cs.teammates = make(map[string]teammate)
+	sess.Current = func() []string {
+		blocks, err := blocksOfHTML(cs.Editor.GetContent(0))
+		if err != nil {
+			return cs.blocks
+		}
+		return blocks
+	}
+	sess.OnRemote = cs.applyRemote
+	sess.OnReset = func(previous []string) {
+		// Keep the replaced copy as a revision so nothing typed is lost for good
+		cs.Revisions = append(cs.Revisions, Revision{
+			ID:       newUUID(),
+			Time:     time.Now().Unix(),
+			Author:   config.CurrentConfig.AuthorName,
+			Document: joinBlocks(previous),
+		})
+		notify("The relay lost this case, so it was replaced by your teammates' copy. Your copy was kept as a revision.", "warning")
+	}
+	sess.OnConflict = func() {
+		notify(fmt.Sprintf("A teammate changed the same part of %s as you, so both versions were kept. Delete the one you don't want.", cs.Name), "warning")
+	}
+	sess.OnPresence = func(user string, name string, block int) {
+		if block < 0 {
+			delete(cs.teammates, user)
+		} else {
+			cs.teammates[user] = teammate{Name: name, Block: block}
+		}
+		cs.renderPresence()
+	}
+	sess.OnDenied = func(reason string) {
+		if cs.Collab == sess {
+			cs.Collab = nil
+		}
+		cs.teammates = nil
+		cs.renderPresence()
+		notify(fmt.Sprintf("The relay stopped sharing %s: %s. Log in to the team tub with an account that can edit it.", cs.Name, reason), "danger")
+	}
+	sess.OnStatus = func(connected bool) {
+		if connected {
+			notify(fmt.Sprintf("%s is connected to the relay", cs.Name), "success")
+		} else {
+			cs.teammates = make(map[string]teammate)
+			cs.renderPresence()
+			notify(fmt.Sprintf("%s lost the relay, edits will be sent when it is back", cs.Name), "warning")
+		}
+	}
+}
+
+// sendCollab passes what was typed to the collaboration session
+func (cs *Case) sendCollab() {
+	if cs.Collab == nil {
+		return
+	}
+	cs.Collab.Local(cs.Collab.Current())
+}
+
+// sendPresence tells teammates which block the cursor is in, if it is in this case
+func (cs *Case) sendPresence() {
+	if cs.Collab == nil {
+		return
+	}
+	if block := cs.cursorBlock(); block != -1 {
+		cs.Collab.Presence(block)
+	}
+}
+
+// cursorBlock returns the index of the block the cursor is in, or -1 if it isn't in the editor
+func (cs *Case) cursorBlock() int {
+	editor := cs.EditorElem.JSValue()
+	node := js.Global().Call("getSelection").Get("anchorNode")
+	for node != js.Null() && node != js.Undefined() {
+		parent := node.Get("parentNode")
+		if parent == editor {
+			return nodeIndex(node)
+		}
+		node = parent
+	}
+	return -1
+}
+
+func nodeIndex(node js.Value) int {
+	index := 0
+	for sibling := node.Get("previousSibling"); sibling != js.Null(); sibling = sibling.Get("previousSibling") {
+		index++
+	}
+	return index
+}
+
+// applyRemote shows a change a teammate made, keeping the cursor where it is if possible
+// Anyone in the room can send anything, so what they send is sanitized like an imported file before it is shown
+func (cs *Case) applyRemote(remote []string) {
+	blocks, err := sanitizeBlocks(remote)
+	if err != nil {
+		log.WarnMessage("Ignoring a change to %s from a teammate: %s", cs.Name, err.Error())
+		return
+	}
+	cs.recordEdit()
+	editor := cs.EditorElem.JSValue()
+	splice, changed := history.Diff(cs.blocks, blocks)
+	if changed && editor.Get("childNodes").Length() == len(cs.blocks) {
+		patchNodes(editor, splice)
+	} else if changed {
+		cs.Editor.SetContent(joinBlocks(blocks), 0)
+	}
+	// The history only holds this user's changes, so it is kept and relies on Apply noticing if a teammate changed the same blocks
+	cs.blocks = blocks
+	cs.renderNav()
+	cs.renderPresence()
+	go func() {
+		persistCase(cs)
+		refreshCards(cs)
+	}()
+}
+
+// sanitizeBlocks strips everything a case can't hold from the blocks
+func sanitizeBlocks(blocks []string) ([]string, error) {
+	safe, err := importer.Sanitize(joinBlocks(blocks))
+	if err != nil {
+		return nil, err
+	}
+	return blocksOfHTML(safe)
+}
+
+// patchNodes replaces the nodes of the editor that the splice changes, leaving the rest alone
+func patchNodes(editor js.Value, splice history.Splice) {
+	nodes := editor.Get("childNodes")
+	for range splice.Removed {
+		editor.Call("removeChild", nodes.Index(splice.Index))
+	}
+	template := js.Global().Get("document").Call("createElement", "template")
+	template.Set("innerHTML", joinBlocks(splice.Inserted))
+	before := js.Null()
+	if splice.Index < nodes.Length() {
+		before = nodes.Index(splice.Index)
+	}
+	editor.Call("insertBefore", template.Get("content"), before)
+}
+
+// renderPresence shows the names of teammates next to the blocks they are editing
+func (cs *Case) renderPresence() {
+	if cs.presenceElem == nil {
+		return
+	}
+	cs.presenceElem.SetInnerHTML("")
+	names := make(map[int][]string)
+	for _, mate := range cs.teammates {
+		names[mate.Block] = append(names[mate.Block], mate.Name)
+	}
+	nodes := cs.EditorElem.JSValue().Get("childNodes")
+	for block, blockNames := range names {
+		if block >= nodes.Length() || nodes.Index(block).Get("offsetTop") == js.Undefined() {
+			continue
+		}
+		sort.Strings(blockNames)
+		marker := dyndom.CreateElement("span", "presenceMarker", "uk-label")
+		marker.SetTextContent(strings.Join(blockNames, ", "))
+		marker.SetAttribute("style", fmt.Sprintf("top: %vpx", nodes.Index(block).Get("offsetTop").Int()))
+		cs.presenceElem.AppendChild(marker)
+	}
+}
@@ -0,0 +1,138 @@
+package document
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/dennwc/dom"
+
+	"gitlab.com/256/DebateFrame/client/cite"
+	"gitlab.com/256/DebateFrame/client/config"
+	"gitlab.com/256/DebateFrame/client/cutter"
+	"gitlab.com/256/DebateFrame/client/grabber"
+	"gitlab.com/256/DebateFrame/client/history"
+	"gitlab.com/256/DebateFrame/client/sections"
+)
+
+// cutterFields are the inputs of the card cutter, which are cleared after each card
+var cutterFields = []string{"cutTag", "cutAuthor", "cutQuals", "cutDate", "cutTitle", "cutPublication", "cutURL", "cutBody"}
+
+var cutterBound = false
+
+// cutterBlock is the block the cursor was in when the cutter was opened, which is where the card goes
+var cutterBlock = -1
+
+// OnCutter is the event listener for when the Cut Card button is pressed
+func OnCutter(e dom.Event) {
+	if !cutterBound {
+		dom.GetDocument().GetElementById("cutInsert").AddEventListener("click", func(e dom.Event) {
+			go insertCut()
+		})
+		dom.GetDocument().GetElementById("cutClear").AddEventListener("click", func(e dom.Event) {
+			clearCutter()
+		})
+		cutterBound = true
+	}
+	cutterBlock = -1
+	if currentCase != nil {
+		cutterBlock = currentCase.cursorBlock()
+	}
+	showModal("modal-cutter")
+}
+
+// articleOf returns what could be found in a saved web page, or nil if the page doesn't look like an article
+// Pages saved from news sites and journals name their author or site, while documents saved as HTML don't
+func articleOf(page string) *grabber.Article {
+	art, err := grabber.Extract(page)
+	if err != nil || (len(art.Authors) == 0 && art.SiteName == "") {
+		return nil
+	}
+	return art
+}
+
+// prefillCutter opens the card cutter filled in with what was found in a saved web page
+func prefillCutter(art *grabber.Article, name string) {
+	OnCutter(nil)
+	clearCutter()
+	setInputValue("cutAuthor", strings.Join(art.Authors, " and "))
+	setInputValue("cutDate", art.Date)
+	setInputValue("cutTitle", art.Title)
+	setInputValue("cutPublication", art.SiteName)
+	setInputValue("cutURL", art.URL)
+	setInputValue("cutBody", strings.Join(art.Body, "\n\n"))
+	if len(art.Body) == 0 {
+		notify(fmt.Sprintf("Couldn't find the article text in %s, paste it in", name), "warning")
+	}
+}
+
+func clearCutter() {
+	for _, id := range cutterFields {
+		setInputValue(id, "")
+	}
+}
+
+// cutterSource reads the card cutter form
+func cutterSource() cutter.Source {
+	return cutter.Source{
+		Tag: inputValue("cutTag"),
+		Cite: cite.Cite{
+			Author:         inputValue("cutAuthor"),
+			Qualifications: inputValue("cutQuals"),
+			Date:           inputValue("cutDate"),
+			Title:          inputValue("cutTitle"),
+			Publication:    inputValue("cutPublication"),
+			URL:            inputValue("cutURL"),
+			Accessed:       time.Now().Format("1-2-2006"),
+		},
+		Template: citeTemplate(),
+		Body:     inputValue("cutBody"),
+	}
+}
+
+// insertCut builds a card from the cutter and puts it in the current case
+func insertCut() {
+	cs := currentCase
+	if cs == nil {
+		notify("Open a case to put the card in first", "warning")
+		return
+	}
+	src := cutterSource()
+	src.Level = cs.cardLevel()
+	cardHTML, err := cutter.Cut(src)
+	if err != nil {
+		notify(err.Error(), "warning")
+		return
+	}
+	blocks, err := blocksOfHTML(cardHTML)
+	if err != nil {
+		notify(err.Error(), "danger")
+		return
+	}
+	cs.recordEdit()
+	at := len(cs.blocks)
+	if cutterBlock >= 0 && cutterBlock < len(cs.blocks) {
+		at = cutterBlock + 1
+	}
+	err = cs.apply(history.Op{
+		Label:   "Cut card",
+		Splices: []history.Splice{{Index: at, Inserted: blocks}},
+	})
+	if err != nil {
+		notify(err.Error(), "danger")
+		return
+	}
+	clearCutter()
+	hideModal("modal-cutter")
+	notify("Added the card to "+cs.Name, "success")
+}
+
+// cardLevel returns the heading level the case uses for tags, so new cards are found like the rest
+// Cases without headings get h4 tags, which is what most teams use
+func (cs *Case) cardLevel() uint8 {
+	level, ok := sections.BlocksLevel(cs.blocks)
+	if !ok {
+		return 4
+	}
+	return level
+}
@@ -0,0 +1,98 @@
+package document
+
+import (
+	"strings"
+
+	"github.com/dennwc/dom"
+
+	"gitlab.com/256/DebateFrame/client/disclosure"
+	"gitlab.com/256/DebateFrame/client/document/card"
+	"gitlab.com/256/DebateFrame/client/filesaver"
+	"gitlab.com/256/DebateFrame/client/log"
+	"gitlab.com/256/WebFrame/dyndom"
+)
+
+var discloseBound = false
+
+// OnDisclose is the event listener for when the Disclose button is pressed
+func OnDisclose(e dom.Event) {
+	if !discloseBound {
+		dom.GetDocument().GetElementById("discloseExport").AddEventListener("click", func(e dom.Event) {
+			go exportDisclosure()
+		})
+		discloseBound = true
+	}
+	list := dom.GetDocument().GetElementById("discloseCases")
+	list.SetInnerHTML("")
+	for _, cs := range openCases {
+		list.AppendChild(discloseOption(cs, cs == currentCase))
+	}
+	showModal("modal-disclose")
+}
+
+func discloseOption(cs *Case, checked bool) *dyndom.Element {
+	label := dyndom.CreateElement("label")
+	box := dyndom.CreateElement("input", "uk-checkbox")
+	box.SetAttribute("type", "checkbox")
+	box.SetAttribute("value", cs.ID)
+	if checked {
+		box.SetAttribute("checked", "")
+	}
+	label.AppendChild(box)
+	name := dyndom.CreateElement("span")
+	name.SetTextContent(" " + cs.Name)
+	label.AppendChild(name)
+	item := dyndom.CreateElement("div")
+	item.AppendChild(label)
+	return item
+}
+
+// exportDisclosure downloads the disclosure of the picked cases in the picked format
+func exportDisclosure() {
+	docs := []disclosure.Doc{}
+	boxes := dom.GetDocument().QuerySelectorAll("#discloseCases input:checked")
+	for _, box := range boxes {
+		cs := openCase(box.JSValue().Get("value").String())
+		if cs == nil {
+			continue
+		}
+		doc, err := cs.disclosureDoc()
+		if err != nil {
+			log.WarnMessage("Failed to read %s for disclosure: %s", cs.Name, err.Error())
+			continue
+		}
+		docs = append(docs, doc)
+	}
+	if len(docs) == 0 {
+		notify("Pick at least one case to disclose", "warning")
+		return
+	}
+
+	switch inputValue("discloseMode") {
+	case "cites-text":
+		filesaver.Save([]byte(disclosure.CitesText(docs)), "Cites.txt", "text/plain")
+	case "full":
+		filesaver.Save([]byte(disclosure.Export(docs, disclosure.FullText)), "Disclosure.html", "text/html")
+	default:
+		filesaver.Save([]byte(disclosure.Export(docs, disclosure.Cites)), "Cites.html", "text/html")
+	}
+	hideModal("modal-disclose")
+}
+
+// disclosureDoc reads the case as it is in the editor for disclosure
+func (cs *Case) disclosureDoc() (disclosure.Doc, error) {
+	err := cs.syncDocument()
+	if err != nil {
+		return disclosure.Doc{}, err
+	}
+	body, err := cs.Document.Find("body").Html()
+	if err != nil {
+		return disclosure.Doc{}, err
+	}
+	// Every tag is disclosed, including ones whose cite is missing the author or year that the card view needs
+	return disclosure.Doc{
+		Name:  strings.TrimSpace(cs.Name),
+		Cards: card.GetEveryCard(cs.Document),
+		HTML:  body,
+	}, nil
+}
@@ -12,19 +12,21 @@ import (
 
 var currentCase *Case
 
+// openCases holds every case that has a tab, in the order of the tabs
+var openCases []*Case
+
 // Run starts the document writer code
 func Run(container *dyndom.Element) error {
-	initCase, err := NewCase("", "Untitled Document")
+	err := openStorage()
 	if err != nil {
-		return errors.Wrap(err, "Failed to create new blank case")
+		return errors.Wrap(err, "Failed to open the case storage")
 	}
-	err = initCase.Add()
+	err = restoreCases()
 	if err != nil {
-		return errors.Wrap(err, "Failed to add the case to the screen")
+		return errors.Wrap(err, "Failed to restore the previously open cases")
 	}
-	err = initCase.SetActive()
-	if err != nil {
-		return errors.Wrap(err, "Failed to set the initial case as the active case")
+	if startSnapshots() {
+		go showRecovery()
 	}
 	err = eventListeners(container)
 	if err != nil {
@@ -0,0 +1,313 @@
+package document
+
+import (
+	"fmt"
+	"html"
+	"sort"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/dennwc/dom"
+
+	"gitlab.com/256/DebateFrame/client/dedupe"
+	"gitlab.com/256/DebateFrame/client/document/card"
+	"gitlab.com/256/DebateFrame/client/history"
+	"gitlab.com/256/DebateFrame/client/log"
+	"gitlab.com/256/WebFrame/dyndom"
+)
+
+// duplicateGroups are the duplicates found by the last scan
+var duplicateGroups []dedupe.Group
+
+var duplicatesBound = false
+
+// OnDuplicates is the event listener for when the Find Duplicates button is pressed
+func OnDuplicates(e dom.Event) {
+	if !duplicatesBound {
+		dom.GetDocument().GetElementById("duplicatesMergeAll").AddEventListener("click", func(e dom.Event) {
+			go mergeDuplicates(duplicateGroups)
+		})
+		duplicatesBound = true
+	}
+	showModal("modal-duplicates")
+	go scanDuplicates()
+}
+
+// duplicateDoc is a case that is searched for duplicates, which is either open or only in the library
+type duplicateDoc struct {
+	id   string
+	name string
+	html string
+	cs   *Case // nil if the case isn't open
+}
+
+// duplicateDocs returns every open case and every file in the library, using the open version of library files that are open
+func duplicateDocs() []duplicateDoc {
+	docs := []duplicateDoc{}
+	for _, cs := range openCases {
+		err := cs.syncDocument()
+		if err != nil {
+			log.WarnMessage("Failed to read %s for duplicates: %s", cs.Name, err.Error())
+			continue
+		}
+		docHTML, err := cs.Document.Html()
+		if err != nil {
+			log.WarnMessage("Failed to read %s for duplicates: %s", cs.Name, err.Error())
+			continue
+		}
+		docs = append(docs, duplicateDoc{id: cs.ID, name: cs.Name, html: docHTML, cs: cs})
+	}
+	if lib == nil {
+		return docs
+	}
+	for _, entry := range lib.Entries() {
+		if openCase(entry.ID) != nil {
+			continue
+		}
+		docHTML, err := lib.Document(entry.ID)
+		if err != nil {
+			log.WarnMessage("Failed to read %s for duplicates: %s", entry.Name, err.Error())
+			continue
+		}
+		docs = append(docs, duplicateDoc{id: entry.ID, name: entry.Path(), html: docHTML})
+	}
+	return docs
+}
+
+// scanDuplicates finds the cards that were cut more than once and shows them
+func scanDuplicates() {
+	status := dom.GetDocument().GetElementById("duplicatesStatus")
+	report := dom.GetDocument().GetElementById("duplicatesReport")
+	mergeAll := dom.GetDocument().GetElementById("duplicatesMergeAll")
+	report.SetInnerHTML("")
+	mergeAll.ClassList().Add("simplehide")
+
+	docs := duplicateDocs()
+	cards := []dedupe.Card{}
+	for i, doc := range docs {
+		status.SetTextContent(fmt.Sprintf("Reading %s (%v of %v)", doc.name, i+1, len(docs)))
+		parsed, err := goquery.NewDocumentFromReader(strings.NewReader(doc.html))
+		if err != nil {
+			log.WarnMessage("Failed to read %s for duplicates: %s", doc.name, err.Error())
+			continue
+		}
+		for _, c := range card.GetCards(parsed) {
+			cards = append(cards, dedupe.Card{
+				Doc:        doc.id,
+				DocName:    doc.name,
+				Level:      c.Level,
+				Heading:    c.Heading,
+				Tag:        strings.TrimSpace(c.Title),
+				Cite:       c.Cite,
+				Body:       c.Body,
+				Highlights: c.Highlights,
+			})
+		}
+	}
+
+	status.SetTextContent(fmt.Sprintf("Comparing %v cards", len(cards)))
+	duplicateGroups = dedupe.Find(cards)
+	if len(duplicateGroups) == 0 {
+		status.SetTextContent(fmt.Sprintf("No duplicates in %v cards from %v files.", len(cards), len(docs)))
+		return
+	}
+	status.SetTextContent(fmt.Sprintf("%v cards were cut more than once. Merging replaces every copy with the one with the most highlighting, and removes extra copies in the same file.", len(duplicateGroups)))
+	for _, group := range duplicateGroups {
+		report.AppendChild(duplicateGroupElement(group))
+	}
+	mergeAll.ClassList().Remove("simplehide")
+}
+
+func duplicateGroupElement(group dedupe.Group) *dyndom.Element {
+	groupDiv := dyndom.CreateElement("div", "uk-card", "uk-card-default", "uk-card-small", "uk-card-body", "uk-margin-small")
+	list := dyndom.CreateElement("ul", "uk-list", "uk-list-divider")
+	best := group.Cards[0]
+	for i, dup := range group.Cards {
+		item := dyndom.CreateElement("li")
+		title := dyndom.CreateElement("div")
+		label := "Best"
+		if i > 0 {
+			label = "Copy"
+		}
+		title.SetInnerHTML(fmt.Sprintf(`<span class="uk-label">%s</span> <b>%s</b> <span class="uk-text-meta">in %s, %v letters highlighted</span>`,
+			label, html.EscapeString(dup.Tag), html.EscapeString(dup.DocName), dup.Highlighted()))
+		item.AppendChild(title)
+		if i > 0 {
+			item.AppendChild(differencesElement(best, dup))
+		}
+		list.AppendChild(item)
+	}
+	groupDiv.AppendChild(list)
+
+	merge := dyndom.CreateElement("button", "uk-button", "uk-button-small", "uk-button-primary")
+	merge.SetTextContent("Merge")
+	merge.AddEventListener("click", func(e dom.Event) {
+		go mergeDuplicates([]dedupe.Group{group})
+	})
+	groupDiv.AppendChild(merge)
+	return groupDiv
+}
+
+// differencesElement shows how a copy differs from the best version
+func differencesElement(best dedupe.Card, dup dedupe.Card) *dyndom.Element {
+	diff := dyndom.CreateElement("div", "uk-text-small")
+	changes := dedupe.Differences(best.Body, dup.Body)
+	if len(changes) == 0 {
+		diff.SetTextContent("Same text, different highlighting")
+		return diff
+	}
+	parts := []string{}
+	for _, change := range changes {
+		if change.Added {
+			parts = append(parts, "<ins>"+html.EscapeString(change.Text)+"</ins>")
+		} else {
+			parts = append(parts, "<del>"+html.EscapeString(change.Text)+"</del>")
+		}
+	}
+	diff.SetInnerHTML("Only in this copy: underlined, only in the best: struck out. " + strings.Join(parts, " … "))
+	return diff
+}
+
+// duplicateEdit replaces a copy of a card, or removes it if there is nothing to replace it with
+type duplicateEdit struct {
+	start    int
+	end      int
+	inserted []string
+}
+
+// mergeDuplicates replaces every copy in the groups with the best version of the card
+// Copies in the same file as the best version are removed, since one file doesn't need a card twice
+func mergeDuplicates(groups []dedupe.Group) {
+	docs := make(map[string]duplicateDoc)
+	for _, doc := range duplicateDocs() {
+		docs[doc.id] = doc
+	}
+	blocks := make(map[string][]string)
+	blocksOfDoc := func(id string) ([]string, bool) {
+		if found, ok := blocks[id]; ok {
+			return found, true
+		}
+		doc, ok := docs[id]
+		if !ok {
+			return nil, false
+		}
+		if doc.cs != nil {
+			doc.cs.recordEdit()
+			blocks[id] = doc.cs.blocks
+			return blocks[id], true
+		}
+		found, err := blocksOfHTML(doc.html)
+		if err != nil {
+			log.WarnMessage("Failed to split %s into blocks: %s", doc.name, err.Error())
+			return nil, false
+		}
+		blocks[id] = found
+		return found, true
+	}
+
+	edits := make(map[string][]duplicateEdit)
+	skipped := 0
+	for _, group := range groups {
+		best := group.Cards[0]
+		bestBlocks, ok := blocksOfDoc(best.Doc)
+		if !ok {
+			skipped += len(group.Cards) - 1
+			continue
+		}
+		bestStart, bestEnd, ok := duplicateSection(bestBlocks, best)
+		if !ok {
+			skipped += len(group.Cards) - 1
+			continue
+		}
+		for _, dup := range group.Cards[1:] {
+			dupBlocks, ok := blocksOfDoc(dup.Doc)
+			if !ok {
+				skipped++
+				continue
+			}
+			start, end, ok := duplicateSection(dupBlocks, dup)
+			if !ok {
+				skipped++
+				continue
+			}
+			edit := duplicateEdit{start: start, end: end}
+			if dup.Doc != best.Doc {
+				edit.inserted = append([]string{}, bestBlocks[bestStart:bestEnd]...)
+			}
+			edits[dup.Doc] = append(edits[dup.Doc], edit)
+		}
+	}
+
+	if !confirmClosedMerge(edits, docs) {
+		return
+	}
+	for id, docEdits := range edits {
+		doc := docs[id]
+		op := duplicateOp(blocks[id], docEdits)
+		if doc.cs != nil {
+			err := doc.cs.apply(op)
+			if err != nil {
+				notify(fmt.Sprintf("Couldn't merge the duplicates in %s: %s", doc.name, err.Error()), "danger")
+			}
+			continue
+		}
+		merged, err := op.Apply(blocks[id])
+		if err != nil {
+			notify(fmt.Sprintf("Couldn't merge the duplicates in %s: %s", doc.name, err.Error()), "danger")
+			continue
+		}
+		entry, ok := lib.Entry(id)
+		if !ok {
+			notify(fmt.Sprintf("Couldn't merge the duplicates in %s, since it was removed from the library", doc.name), "warning")
+			continue
+		}
+		storageError(lib.Add(entry, joinBlocks(merged)))
+	}
+	if skipped > 0 {
+		notify(fmt.Sprintf("%v copies changed since the scan and were left alone", skipped), "warning")
+	}
+	scanDuplicates()
+}
+
+// confirmClosedMerge asks before merging changes files that aren't open, since those changes can't be undone
+// It returns true if no such file is changed or the user agreed
+func confirmClosedMerge(edits map[string][]duplicateEdit, docs map[string]duplicateDoc) bool {
+	names := []string{}
+	for id := range edits {
+		if doc := docs[id]; doc.cs == nil {
+			names = append(names, doc.name)
+		}
+	}
+	if len(names) == 0 {
+		return true
+	}
+	sort.Strings(names)
+	return dom.GetWindow().JSValue().Call("confirm", fmt.Sprintf("Merging changes %v library files that aren't open, and can't be undone there: %s. Merge anyway?",
+		len(names), strings.Join(names, ", "))).Bool()
+}
+
+// duplicateSection finds the blocks of a copy, checking that its tag is still where the scan found it
+func duplicateSection(blocks []string, dup dedupe.Card) (int, int, bool) {
+	start := headingBlock(blocks, dup.Level, dup.Heading)
+	if start == -1 || strings.Join(strings.Fields(blockText(blocks[start])), " ") != strings.Join(strings.Fields(dup.Tag), " ") {
+		return 0, 0, false
+	}
+	return start, sectionEnd(blocks, start), true
+}
+
+// duplicateOp turns the edits of one document into an operation
+// Edits are applied from the end of the document back, so each one's indexes are still right when it is applied
+func duplicateOp(blocks []string, edits []duplicateEdit) history.Op {
+	sort.Slice(edits, func(a, b int) bool {
+		return edits[a].start > edits[b].start
+	})
+	op := history.Op{Label: "Merge duplicates"}
+	for _, edit := range edits {
+		op.Splices = append(op.Splices, history.Splice{
+			Index:    edit.start,
+			Removed:  append([]string{}, blocks[edit.start:edit.end]...),
+			Inserted: edit.inserted,
+		})
+	}
+	return op
+}
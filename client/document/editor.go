@@ -19,6 +19,13 @@ func newEditor(query string) *medium.Editor {
 	options.Anchor.LinkValidation = true
 	options.Paste.ForcePlainText = false
 	options.Paste.CleanPastedHTML = true
+	// Undo and redo are handled by the case history instead of the browser
+	options.KeyboardCommands.Enabled = true
+	options.KeyboardCommands.Commands = append(options.KeyboardCommands.Commands,
+		medium.Binding{Key: 'z', Meta: true},
+		medium.Binding{Key: 'z', Meta: true, Shift: true},
+		medium.Binding{Key: 'y', Meta: true},
+	)
 	editor := medium.NewEditor(query, options)
 	return editor
 }
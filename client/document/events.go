@@ -15,7 +15,6 @@ import (
 
 	"gitlab.com/256/DebateFrame/client/filesaver"
 	"gitlab.com/256/DebateFrame/client/log"
-	"gitlab.com/256/DebateFrame/client/mammoth"
 	"gitlab.com/256/DebateFrame/client/waiter"
 	"gitlab.com/256/WebFrame/waquery"
 )
@@ -25,41 +24,35 @@ func eventListeners(container *dyndom.Element) error {
 	cb := js.NewEventCallback(0, func(file js.Value) {
 		log.DebugMessage("Drop event fired")
 		fullFile := file.Get("name").String()
-		fileNoExt := strings.TrimSuffix(fullFile, filepath.Ext(fullFile))
 		fileExt := filepath.Ext(fullFile)
-		switch fileExt {
-		case ".docx":
-			mammoth.ConvertToHTML(&file, func(html string) {
-				html = postProcess(html)
-				cs, err := NewCase(html, fileNoExt)
-				if err != nil {
-					log.PanicMessage("Failed to create new case from HTML", err)
-				}
-				err = cs.Add()
-				if err != nil {
-					log.PanicMessage("Failed to add the docx document to the screen", err)
-				}
-				err = cs.SetActive()
-				if err != nil {
-					log.PanicMessage("Failed to set the docx file as the current case!", err)
-				}
-			})
+		// Files from a dropped folder have the path they had in it
+		if fullPath := file.Get("fullPath"); fullPath.Type() == js.TypeString && strings.Contains(strings.Trim(fullPath.String(), "/"), "/") {
+			queueFolderFile(file, fullPath.String())
+			return
+		}
+		switch strings.ToLower(fileExt) {
+		case ".zip":
+			go importZip(file)
 		case ".dfc":
 			log.DebugMessage("DebateFrame case detected!")
-			caseLoad(&file)
+			go func() {
+				err := caseLoad(&file)
+				if err != nil {
+					log.WarnMessage("Failed to open a case: %s", err.Error())
+					notify(err.Error(), "danger")
+				}
+			}()
+		case ".html", ".htm":
+			go dropPage(file)
+		default:
+			go importFile(file)
 		}
-
 	})
 	drop.Call("on", "addedfile", cb)
 
 	waiter.EventWaiter(&dom.GetDocument().NodeBase, "scroll", 50, func() {
-		activeLinks := dom.GetDocument().QuerySelectorAll(".is-active-link")
-		if len(activeLinks) > 0 {
-			active := activeLinks[0]
-			if !waquery.IsVisible(waquery.ToHTML(active)) {
-				topPos := active.JSValue().Get("offsetTop")
-				currentCase.TOCElem.JSValue().Set("scrollTop", topPos)
-			}
+		if currentCase != nil {
+			currentCase.highlightNav()
 		}
 	})
 
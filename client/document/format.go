@@ -0,0 +1,111 @@
+package document
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/davecgh/go-xdr/xdr"
+	"github.com/pkg/errors"
+)
+
+// caseMagic starts every case file that says which layout it uses
+// Older files start with the length of the case name, which is never this big
+const caseMagic = "DFCS"
+
+// caseVersion is the layout of SaveableCase that case files are written in
+// The layout is positional, so changing the fields of SaveableCase or anything in it needs a new version,
+// with a case for the old layout in decodeCase
+const caseVersion = 1
+
+// encodeCase converts a case to the bytes of a case file
+func encodeCase(scase *SaveableCase) ([]byte, error) {
+	version, err := xdr.Marshal(uint32(caseVersion))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to encode the format version")
+	}
+	body, err := objToBytes(scase)
+	if err != nil {
+		return nil, err
+	}
+	file := append([]byte(caseMagic), version...)
+	return append(file, body...), nil
+}
+
+// decodeCase reads a case file in any layout DebateFrame has saved
+func decodeCase(file []byte) (*SaveableCase, error) {
+	if !bytes.HasPrefix(file, []byte(caseMagic)) {
+		return decodeLegacyCase(file)
+	}
+	file = file[len(caseMagic):]
+	var version uint32
+	body, err := xdr.Unmarshal(file, &version)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read the format version")
+	}
+	switch version {
+	case caseVersion:
+		scase := SaveableCase{}
+		err = decodeLayout(body, &scase)
+		if err != nil {
+			return nil, err
+		}
+		return &scase, nil
+	default:
+		return nil, fmt.Errorf("the case was saved in format %v by a newer version of DebateFrame", version)
+	}
+}
+
+// decodeLayout decodes a case file into one layout, failing unless the layout accounts for every byte
+// so a file that isn't a case doesn't decode as one
+func decodeLayout(file []byte, layout interface{}) error {
+	rest, err := xdr.Unmarshal(file, layout)
+	if err != nil {
+		return errors.Wrap(err, "failed to decode the case")
+	}
+	if len(rest) != 0 {
+		return fmt.Errorf("failed to decode the case: %v bytes were left over", len(rest))
+	}
+	return nil
+}
+
+// decodeLegacyCase reads a case file from before case files had a version, which all have the first layout
+func decodeLegacyCase(file []byte) (*SaveableCase, error) {
+	scase, err := decodeFirstCase(file)
+	if err != nil {
+		return nil, errors.Wrap(err, "the file isn't a DebateFrame case, or is from a version of DebateFrame this one can't read")
+	}
+	return scase, nil
+}
+
+// firstCard is a card as the first case files saved it
+type firstCard struct {
+	Title    string
+	Contents string
+	URL      string
+	Year     uint8
+	Author   string
+}
+
+func (fcard *firstCard) infoCard() *InfoCard {
+	return &InfoCard{Title: fcard.Title, Contents: fcard.Contents, URL: fcard.URL, Year: fcard.Year, Author: fcard.Author}
+}
+
+// firstCase is the layout of the first case files
+type firstCase struct {
+	Name     string
+	Cards    []*firstCard
+	Document string
+}
+
+func decodeFirstCase(file []byte) (*SaveableCase, error) {
+	old := firstCase{}
+	err := decodeLayout(file, &old)
+	if err != nil {
+		return nil, err
+	}
+	scase := SaveableCase{Name: old.Name, Document: old.Document}
+	for _, fcard := range old.Cards {
+		scase.Cards = append(scase.Cards, fcard.infoCard())
+	}
+	return &scase, nil
+}
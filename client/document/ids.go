@@ -0,0 +1,174 @@
+package document
+
+import (
+	"html"
+	"regexp"
+	"strings"
+	"syscall/js"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/google/uuid"
+
+	"gitlab.com/256/DebateFrame/client/history"
+	"gitlab.com/256/DebateFrame/client/sections"
+)
+
+// newHeadingID returns an id for a heading that doesn't depend on where it is or what it says,
+// so links to it keep working however the document is edited
+func newHeadingID() string {
+	return "h" + strings.Replace(uuid.New().String(), "-", "", -1)[:12]
+}
+
+// headingSelector matches every heading, including headings nested in other elements like GetCards counts them
+const headingSelector = "h1, h2, h3, h4, h5, h6"
+
+// stampHeadings gives every heading in the editor an id if it doesn't have one, or has the same one as a heading before it
+// Splitting a heading while typing copies its id, so the first keeps it and the second gets a new one
+// The attributes are set on the nodes, which doesn't move the cursor the way setting the content would
+func stampHeadings(editor js.Value) {
+	seen := make(map[string]bool)
+	headings := editor.Call("querySelectorAll", headingSelector)
+	for i := 0; i < headings.Length(); i++ {
+		heading := headings.Index(i)
+		id := heading.Get("id").String()
+		if id == "" || seen[id] {
+			id = newHeadingID()
+			heading.Call("setAttribute", "id", id)
+		}
+		seen[id] = true
+	}
+}
+
+// stampDocument gives every heading in a document that isn't taken an id, the way stampHeadings does in the editor,
+// and returns whether any heading needed one
+// Cases are stamped when they are created or opened, so their headings can be found by id before the first edit
+func stampDocument(doc *goquery.Document, taken map[string]bool) bool {
+	changed := false
+	doc.Find(headingSelector).Each(func(_ int, heading *goquery.Selection) {
+		id, _ := heading.Attr("id")
+		if id == "" || taken[id] {
+			id = newHeadingID()
+			heading.SetAttr("id", id)
+			changed = true
+		}
+		taken[id] = true
+	})
+	return changed
+}
+
+// stampOp gives the headings an operation inserts ids, unless they already have ones that are free
+// Headings that are moved keep their ids, since the ids of removed blocks are free again
+func stampOp(blocks []string, op history.Op) history.Op {
+	taken := make(map[string]bool)
+	for _, block := range blocks {
+		for _, id := range blockIDs(block) {
+			taken[id] = true
+		}
+	}
+	for _, splice := range op.Splices {
+		for _, block := range splice.Removed {
+			for _, id := range blockIDs(block) {
+				delete(taken, id)
+			}
+		}
+	}
+	stamped := history.Op{Label: op.Label}
+	for _, splice := range op.Splices {
+		inserted := make([]string, len(splice.Inserted))
+		for i, block := range splice.Inserted {
+			inserted[i] = block
+			if len(sections.Headings(block)) == 0 {
+				continue
+			}
+			if withIDs, err := stampBlock(block, taken); err == nil {
+				inserted[i] = withIDs
+			}
+		}
+		stamped.Splices = append(stamped.Splices, history.Splice{Index: splice.Index, Removed: splice.Removed, Inserted: inserted})
+	}
+	return stamped
+}
+
+// stampBlock returns the block with an id on every heading in it, leaving it as it was if none needed one
+func stampBlock(block string, taken map[string]bool) (string, error) {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(block))
+	if err != nil {
+		return "", err
+	}
+	if !stampDocument(doc, taken) {
+		return block, nil
+	}
+	return goquery.OuterHtml(doc.Find("body").Children().First())
+}
+
+var (
+	// headingIDRp reads the id from the opening tag of a heading block
+	// Blocks are written out by goquery, which quotes every attribute and escapes > inside them, so the tag ends at the first >
+	headingIDRp = regexp.MustCompile(`(?i)^<h[1-6]\b[^>]*?\sid="([^"]*)"`)
+	// nestedIDRp reads the id from the opening tag of every heading in a block
+	nestedIDRp = regexp.MustCompile(`(?i)<h[1-6]\b[^>]*?\sid="([^"]*)"`)
+)
+
+// blockID returns the id of a heading block, or an empty string if it has none or isn't a heading
+// It is called for every block on every edit, so it reads the opening tag instead of parsing the block
+func blockID(block string) string {
+	if sections.Level(block) == 0 {
+		return ""
+	}
+	match := headingIDRp.FindStringSubmatch(block)
+	if match == nil {
+		return ""
+	}
+	return html.UnescapeString(match[1])
+}
+
+// blockIDs returns the ids of every heading in the block, including headings nested in other elements
+func blockIDs(block string) []string {
+	ids := []string{}
+	for _, match := range nestedIDRp.FindAllStringSubmatch(block, -1) {
+		ids = append(ids, html.UnescapeString(match[1]))
+	}
+	return ids
+}
+
+// idBlock returns the index of the block holding the heading with the id, or -1 if there isn't one
+func idBlock(blocks []string, id string) int {
+	for i, block := range blocks {
+		for _, found := range blockIDs(block) {
+			if found == id {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// followReference jumps to the heading an internal link points to, when it is clicked with ctrl or cmd held
+// Links can't be followed normally while they are being edited, and the ids are stable so references survive edits
+func (cs *Case) followReference(event js.Value) {
+	if !event.Get("ctrlKey").Bool() && !event.Get("metaKey").Bool() {
+		return
+	}
+	link := event.Get("target").Call("closest", "a[href^='#']")
+	if link == js.Null() {
+		return
+	}
+	id := strings.TrimPrefix(link.Call("getAttribute", "href").String(), "#")
+	go func() {
+		warnError(cs.JumpToHeading(id))
+	}()
+}
+
+// insertReference puts a link to the heading at the cursor in the editor
+func (cs *Case) insertReference(id string, text string) {
+	if cs.cursorBlock() == -1 {
+		notify("Put the cursor where the link should go first", "warning")
+		return
+	}
+	js.Global().Get("document").Call("execCommand", "insertHTML", false, referenceHTML(id, text))
+}
+
+// referenceHTML returns a link to a heading
+func referenceHTML(id string, text string) string {
+	return `<a href="#` + html.EscapeString(id) + `">` + html.EscapeString(text) + `</a>`
+}
@@ -0,0 +1,106 @@
+package document
+
+import (
+	"errors"
+	"fmt"
+	"html"
+	"path/filepath"
+	"strings"
+	"syscall/js"
+
+	"gitlab.com/256/DebateFrame/client/importer"
+	"gitlab.com/256/DebateFrame/client/mammoth"
+	"gitlab.com/256/DebateFrame/client/pdfjs"
+)
+
+// Importers that need a JavaScript library are registered here, the rest are in the importer package
+func init() {
+	importer.Register([]string{".docx"}, []string{"application/vnd.openxmlformats-officedocument.wordprocessingml.document"}, importDocx)
+	importer.Register([]string{".pdf"}, []string{"application/pdf"}, importPDF)
+}
+
+func importDocx(data []byte) (string, error) {
+	docHTML, err := mammoth.ConvertBytes(data)
+	if err != nil {
+		return "", err
+	}
+	return postProcess(docHTML), nil
+}
+
+func importPDF(data []byte) (string, error) {
+	paragraphs, err := pdfjs.Paragraphs(data)
+	if err != nil {
+		return "", err
+	}
+	if len(paragraphs) == 0 {
+		return "", errors.New("the PDF has no text in it, it may be a scan")
+	}
+	out := &strings.Builder{}
+	for _, para := range paragraphs {
+		fmt.Fprintf(out, "<p>%s</p>", html.EscapeString(para))
+	}
+	return out.String(), nil
+}
+
+// readFile reads a dropped file, so it must never be called from a callback
+func readFile(file js.Value) []byte {
+	done := make(chan []byte, 1)
+	mammoth.FileAsArrayBuffer(&file, func(buffer *js.Value) {
+		data := make([]byte, buffer.Get("byteLength").Int())
+		array := js.TypedArrayOf(data)
+		array.Call("set", js.Global().Get("Uint8Array").New(*buffer))
+		array.Release()
+		done <- data
+	})
+	return <-done
+}
+
+// importFile opens a dropped file as a new case, using the importer for its type
+func importFile(file js.Value) {
+	name := file.Get("name").String()
+	imp, ok := importer.Find(name, file.Get("type").String())
+	if !ok {
+		notify(fmt.Sprintf("DebateFrame can't open %s. It opens .dfc, %s files", name, strings.Join(importer.Extensions(), ", ")), "danger")
+		return
+	}
+	showModal("modal-loading")
+	data := readFile(file)
+	openImported(name, imp, data)
+	hideModal("modal-loading")
+}
+
+// openImported turns the contents of a file into a case and shows it
+func openImported(name string, imp importer.Importer, data []byte) {
+	caseHTML, err := imp(data)
+	if err != nil {
+		notify(fmt.Sprintf("Couldn't open %s: %s", name, err.Error()), "danger")
+		return
+	}
+	cs, err := NewCase(caseHTML, strings.TrimSuffix(name, filepath.Ext(name)))
+	if err != nil {
+		notify(fmt.Sprintf("Couldn't open %s: %s", name, err.Error()), "danger")
+		return
+	}
+	err = cs.Add()
+	if err != nil {
+		notify(fmt.Sprintf("Couldn't show %s: %s", name, err.Error()), "danger")
+		return
+	}
+	err = cs.SetActive()
+	if err != nil {
+		notify(fmt.Sprintf("Couldn't switch to %s: %s", name, err.Error()), "danger")
+	}
+}
+
+// dropPage fills the card cutter from a saved article, and opens any other HTML file as a case
+func dropPage(file js.Value) {
+	name := file.Get("name").String()
+	page := readFile(file)
+	if currentCase != nil {
+		if art := articleOf(string(page)); art != nil {
+			prefillCutter(art, name)
+			return
+		}
+	}
+	openImported(name, importer.HTML, page)
+}
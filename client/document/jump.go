@@ -0,0 +1,99 @@
+package document
+
+import (
+	"fmt"
+	"syscall/js"
+
+	"github.com/dennwc/dom"
+
+	"gitlab.com/256/DebateFrame/client/document/card"
+)
+
+// flashTime is how long the tag of a card that was jumped to is lit up, in milliseconds
+const flashTime = 1500
+
+// linkCard makes clicking a card in the card view, or pressing enter on it, jump to it in the editor
+func linkCard(cs *Case, c *card.Card, icard *InfoCard) {
+	c.Element.SetAttribute("tabindex", "0")
+	c.Element.SetAttribute("role", "button")
+	c.Element.SetAttribute("title", "Show in the document")
+	c.Element.AddEventListener("click", func(e dom.Event) {
+		// The buttons on the card do their own thing
+		if e.JSValue().Get("target").Call("closest", ".cardActions") != js.Null() {
+			return
+		}
+		go func() {
+			warnError(cs.jumpToCard(c, icard))
+		}()
+	})
+	c.Element.AddEventListener("keydown", func(e dom.Event) {
+		event := e.JSValue()
+		if event.Get("target") == c.Element.JSValue() && event.Get("key").String() == "Enter" {
+			go func() {
+				warnError(cs.jumpToCard(c, icard))
+			}()
+		}
+	})
+}
+
+// warnError shows an error from a command the user gave, if there was one
+func warnError(err error) {
+	if err != nil {
+		notify(err.Error(), "warning")
+	}
+}
+
+// JumpToCard switches to the editor, puts the cursor at the start of the card's tag and lights the tag up
+// A tag nested in another element is found by lighting up the block holding it
+func (cs *Case) JumpToCard(icard *InfoCard) error {
+	cs.recordEdit()
+	start, _ := findHeading(cs.blocks, icard.Level, icard.Heading)
+	if start == -1 {
+		return fmt.Errorf("could not find the card \"%s\" in the document", icard.Title)
+	}
+	return cs.jumpToBlock(start)
+}
+
+// jumpToCard jumps to a card in the card view by the id of its tag, so it is found even if headings were added above it since the card view was drawn
+func (cs *Case) jumpToCard(c *card.Card, icard *InfoCard) error {
+	if c.ID == "" {
+		return cs.JumpToCard(icard)
+	}
+	return cs.JumpToHeading(c.ID)
+}
+
+// JumpToHeading switches to the editor and puts the cursor at the start of the heading with the id
+func (cs *Case) JumpToHeading(id string) error {
+	cs.recordEdit()
+	index := idBlock(cs.blocks, id)
+	if index == -1 {
+		return fmt.Errorf("the heading this points to isn't in the document any more")
+	}
+	return cs.jumpToBlock(index)
+}
+
+// jumpToBlock switches to the editor, puts the cursor at the start of the block and lights it up
+func (cs *Case) jumpToBlock(index int) error {
+	nodes := cs.EditorElem.JSValue().Get("childNodes")
+	if index < 0 || index >= nodes.Length() {
+		return fmt.Errorf("there is no block %v in the document", index)
+	}
+	cs.showEditor()
+	block := nodes.Index(index)
+	cs.EditorElem.JSValue().Call("focus")
+	js.Global().Call("getSelection").Call("collapse", block, 0)
+	block.Call("scrollIntoView", map[string]interface{}{"behavior": "smooth", "block": "center"})
+	// An animation doesn't touch the markup, so the flash never ends up in the history or the saved case
+	block.Call("animate", []interface{}{
+		map[string]interface{}{"backgroundColor": "#fff59d"},
+		map[string]interface{}{"backgroundColor": "transparent"},
+	}, flashTime)
+	return nil
+}
+
+// showEditor switches the case from the card view to the editor, if the card view is showing
+func (cs *Case) showEditor() {
+	if cs.EditorElem.JSValue().Get("classList").Call("contains", "simplehide").Bool() {
+		cs.viewButton.JSValue().Call("click")
+	}
+}
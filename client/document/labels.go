@@ -0,0 +1,135 @@
+package document
+
+import (
+	"fmt"
+	"strings"
+	"syscall/js"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/dennwc/dom"
+
+	"gitlab.com/256/DebateFrame/client/document/card"
+	"gitlab.com/256/DebateFrame/client/history"
+	"gitlab.com/256/DebateFrame/client/sections"
+	"gitlab.com/256/WebFrame/dyndom"
+)
+
+func newLabelBlockButton() *dyndom.Element {
+	labelButton := newToolbarButton("tag")
+	labelButton.SetAttribute("title", "Label the block the cursor is in")
+	labelButton.AddEventListener("click", func(e dom.Event) {
+		// The cursor has to be read before the prompt takes the focus
+		block := currentCase.cursorBlock()
+		go currentCase.promptBlockLabels(block)
+	})
+	return labelButton
+}
+
+// promptBlockLabels asks for the labels of the heading at or above the block
+func (cs *Case) promptBlockLabels(block int) {
+	cs.recordEdit()
+	heading := -1
+	for i := block; i >= 0 && i < len(cs.blocks); i-- {
+		if sections.Level(cs.blocks[i]) != 0 {
+			heading = i
+			break
+		}
+	}
+	if heading == -1 {
+		notify("Put the cursor in a block under a heading to label it", "warning")
+		return
+	}
+	labels, ok := promptLabels(blockText(cs.blocks[heading]), headingLabels(cs.blocks[heading]))
+	if !ok {
+		return
+	}
+	err := cs.setHeadingLabels(heading, labels)
+	if err != nil {
+		notify(err.Error(), "danger")
+	}
+}
+
+// EditCardLabels asks for the labels of the card's tag
+func (cs *Case) EditCardLabels(icard *InfoCard) error {
+	cs.recordEdit()
+	start, _, err := cs.cardSection(icard)
+	if err != nil {
+		return err
+	}
+	labels, ok := promptLabels(icard.Title, headingLabels(cs.blocks[start]))
+	if !ok {
+		return nil
+	}
+	return cs.setHeadingLabels(start, labels)
+}
+
+// promptLabels asks for a comma separated list of labels, returning false if the prompt was cancelled
+func promptLabels(title string, labels []string) ([]string, bool) {
+	answer := dom.GetWindow().JSValue().Call("prompt",
+		fmt.Sprintf("Labels for \"%s\", separated by commas. Blocks pass their labels on to the cards under them.", strings.TrimSpace(title)),
+		card.FormatLabels(labels))
+	if answer == js.Null() {
+		return nil, false
+	}
+	return card.ParseLabels(answer.String()), true
+}
+
+// setHeadingLabels replaces the labels of the heading at the block index
+func (cs *Case) setHeadingLabels(index int, labels []string) error {
+	labelled, err := withHeadingLabels(cs.blocks[index], labels)
+	if err != nil {
+		return err
+	}
+	if labelled == cs.blocks[index] {
+		return nil
+	}
+	return cs.apply(history.Op{
+		Label:   "Label",
+		Splices: []history.Splice{{Index: index, Removed: []string{cs.blocks[index]}, Inserted: []string{labelled}}},
+	})
+}
+
+// placeCards gives the cards the labels, blocks and ids of their headings in the document
+// They are read from the document rather than kept on the info cards, so they can't get out of date with it
+func placeCards(doc *goquery.Document, cards []*card.Card) {
+	labels := make(map[uint8][][]string)
+	blocks := make(map[uint8][]string)
+	ids := make(map[uint8][]string)
+	for _, c := range cards {
+		if _, ok := labels[c.Level]; !ok {
+			labels[c.Level] = card.HeadingLabels(doc, c.Level)
+			blocks[c.Level] = card.HeadingBlocks(doc, c.Level)
+			ids[c.Level] = card.HeadingIDs(doc, c.Level)
+		}
+		if c.Heading < len(labels[c.Level]) {
+			c.Labels = labels[c.Level][c.Heading]
+			c.Block = blocks[c.Level][c.Heading]
+			c.ID = ids[c.Level][c.Heading]
+		}
+	}
+}
+
+// headingLabels returns the labels of a heading block
+func headingLabels(block string) []string {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(block))
+	if err != nil {
+		return []string{}
+	}
+	attr, _ := doc.Find("body").Children().First().Attr(card.LabelsAttr)
+	return card.ParseLabels(attr)
+}
+
+// withHeadingLabels returns the heading block with its labels replaced, dropping the attribute when there are none
+func withHeadingLabels(block string, labels []string) (string, error) {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(block))
+	if err != nil {
+		return "", err
+	}
+	heading := doc.Find("body").Children().First()
+	if len(labels) == 0 {
+		heading.RemoveAttr(card.LabelsAttr)
+	} else {
+		heading.SetAttr(card.LabelsAttr, card.FormatLabels(labels))
+	}
+	return goquery.OuterHtml(heading)
+}
@@ -0,0 +1,272 @@
+package document
+
+import (
+	"fmt"
+	"strings"
+	"syscall/js"
+	"time"
+
+	"github.com/dennwc/dom"
+
+	"gitlab.com/256/DebateFrame/client/library"
+	"gitlab.com/256/DebateFrame/client/log"
+	"gitlab.com/256/WebFrame/dyndom"
+)
+
+// lib holds the files imported from folders and zips, or is nil if it couldn't be opened
+var lib *library.Library
+
+// folderDelay is how long to wait after the last file of a dropped folder before importing them all
+const folderDelay = 500 * time.Millisecond
+
+// folderFiles collects the files of a dropped folder, since Dropzone adds them one at a time
+var folderFiles = []library.Source{}
+var folderTimer *time.Timer
+
+// queueFolderFile adds a file from a dropped folder to the batch that is imported once the folder is done
+func queueFolderFile(file js.Value, fullPath string) {
+	folderFiles = append(folderFiles, library.Source{
+		Path: strings.Trim(fullPath, "/"),
+		MIME: file.Get("type").String(),
+		Read: func() ([]byte, error) {
+			return readFile(file), nil
+		},
+	})
+	if folderTimer != nil {
+		folderTimer.Stop()
+	}
+	folderTimer = time.AfterFunc(folderDelay, func() {
+		sources := folderFiles
+		folderFiles = []library.Source{}
+		importBatch(sources)
+	})
+}
+
+// importZip imports every file in a dropped zip into the library
+func importZip(file js.Value) {
+	name := file.Get("name").String()
+	sources, err := library.Unzip(readFile(file))
+	if err != nil {
+		notify(fmt.Sprintf("Couldn't open %s: %s", name, err.Error()), "danger")
+		return
+	}
+	importBatch(sources)
+}
+
+// importBatch imports files into the library, showing the progress and every file that failed
+func importBatch(sources []library.Source) {
+	if lib == nil {
+		notify("The library couldn't be opened, so files can't be imported into it", "danger")
+		return
+	}
+	if len(sources) == 0 {
+		notify("There were no files to import", "warning")
+		return
+	}
+	status := dom.GetDocument().GetElementById("importStatus")
+	progress := dom.GetDocument().GetElementById("importProgress").JSValue()
+	errorList := dom.GetDocument().GetElementById("importErrors")
+	errorList.SetInnerHTML("")
+	showModal("modal-import")
+
+	results := lib.Import(sources, func(done int, total int, current string) {
+		progress.Set("max", total)
+		progress.Set("value", done)
+		if current != "" {
+			status.SetTextContent(fmt.Sprintf("Importing %s (%v of %v)", current, done+1, total))
+		}
+	})
+	for _, result := range results {
+		if result.Err != nil {
+			item := dyndom.CreateElement("li")
+			item.SetTextContent(fmt.Sprintf("%s: %s", result.Path, result.Err.Error()))
+			errorList.AppendChild(item)
+		}
+	}
+	summary := library.Summary(results)
+	status.SetTextContent(summary)
+	notify(summary, "success")
+	renderLibrary()
+}
+
+var libraryBound = false
+
+// OnLibrary is the event listener for when the Library button is pressed
+func OnLibrary(e dom.Event) {
+	if !libraryBound {
+		dom.GetDocument().GetElementById("librarySearch").AddEventListener("input", func(e dom.Event) {
+			renderLibrary()
+		})
+		dom.GetDocument().GetElementById("libraryGroup").AddEventListener("change", func(e dom.Event) {
+			renderLibrary()
+		})
+		libraryBound = true
+	}
+	renderLibrary()
+	showModal("modal-library")
+}
+
+// renderLibrary shows the library as a tree, grouped and filtered the way the user picked
+func renderLibrary() {
+	tree := dom.GetDocument().GetElementById("libraryTree")
+	count := dom.GetDocument().GetElementById("libraryCount")
+	tree.SetInnerHTML("")
+	if lib == nil {
+		count.SetTextContent("The library couldn't be opened.")
+		return
+	}
+	all := lib.Entries()
+	entries := library.Filter(all, inputValue("librarySearch"))
+	if len(all) == 0 {
+		count.SetTextContent("The library is empty. Drop a folder or a zip of evidence to import it.")
+		return
+	}
+	count.SetTextContent(fmt.Sprintf("%v of %v files", len(entries), len(all)))
+	root := library.Tree(entries, inputValue("libraryGroup"))
+	// Groups start open when searching so the matches can be seen
+	open := strings.TrimSpace(inputValue("librarySearch")) != ""
+	tree.AppendChild(libraryNode(root, open))
+}
+
+// libraryNode draws a group of the tree and everything under it
+func libraryNode(node *library.Node, open bool) *dyndom.Element {
+	list := dyndom.CreateElement("ul", "uk-list", "libraryList")
+	for _, child := range node.Children {
+		group := dyndom.CreateElement("details")
+		if open {
+			group.SetAttribute("open", "")
+		}
+		summary := dyndom.CreateElement("summary", "libraryGroup")
+		summary.SetTextContent(fmt.Sprintf("%s (%v)", child.Name, child.Count()))
+		group.AppendChild(summary)
+		group.AppendChild(libraryNode(child, open))
+		item := dyndom.CreateElement("li")
+		item.AppendChild(group)
+		list.AppendChild(item)
+	}
+	for _, entry := range node.Entries {
+		list.AppendChild(libraryRow(entry))
+	}
+	return list
+}
+
+func libraryRow(entry library.Entry) *dyndom.Element {
+	row := dyndom.CreateElement("li", "libraryEntry")
+	name := dyndom.CreateElement("a")
+	name.SetAttribute("href", "#")
+	name.SetAttribute("title", "Open "+entry.Path())
+	name.SetTextContent(entry.Name)
+	name.AddEventListener("click", func(e dom.Event) {
+		go openLibraryEntry(entry.ID)
+	})
+	row.AppendChild(name)
+
+	details := []string{}
+	for _, detail := range []string{entry.Side, entry.Topic} {
+		if detail != "" {
+			details = append(details, detail)
+		}
+	}
+	if len(details) > 0 {
+		meta := dyndom.CreateElement("span", "uk-text-meta")
+		meta.SetTextContent(" " + strings.Join(details, ", "))
+		row.AppendChild(meta)
+	}
+
+	edit := dyndom.CreateElement("a", "uk-icon-link", "uk-margin-small-left")
+	edit.SetAttribute("href", "#")
+	edit.SetAttribute("uk-icon", "icon: pencil; ratio: 0.8")
+	edit.SetAttribute("title", "Set the topic and side")
+	edit.AddEventListener("click", func(e dom.Event) {
+		go editLibraryEntry(entry.ID)
+	})
+	row.AppendChild(edit)
+	remove := dyndom.CreateElement("a", "uk-icon-link", "uk-margin-small-left")
+	remove.SetAttribute("href", "#")
+	remove.SetAttribute("uk-icon", "icon: trash; ratio: 0.8")
+	remove.SetAttribute("title", "Remove from the library")
+	remove.AddEventListener("click", func(e dom.Event) {
+		go removeLibraryEntry(entry.ID)
+	})
+	row.AppendChild(remove)
+	return row
+}
+
+// openLibraryEntry shows a file from the library in the editor
+// The case keeps the ID of the entry, so opening it again switches to it or reopens it with the changes made to it
+func openLibraryEntry(id string) {
+	entry, ok := lib.Entry(id)
+	if !ok {
+		return
+	}
+	hideModal("modal-library")
+	if cs := openCase(id); cs != nil {
+		cs.SetActive()
+		return
+	}
+	cs, err := loadCase(id)
+	if err != nil {
+		docHTML, err := lib.Document(id)
+		if err != nil {
+			notify(fmt.Sprintf("Couldn't read %s from the library: %s", entry.Name, err.Error()), "danger")
+			return
+		}
+		cs, err = NewCase(docHTML, entry.Name)
+		if err != nil {
+			notify(fmt.Sprintf("Couldn't open %s: %s", entry.Name, err.Error()), "danger")
+			return
+		}
+		cs.ID = entry.ID
+	}
+	err = cs.Add()
+	if err != nil {
+		notify(fmt.Sprintf("Couldn't show %s: %s", entry.Name, err.Error()), "danger")
+		return
+	}
+	err = cs.SetActive()
+	if err != nil {
+		log.WarnMessage("Failed to switch to %s: %s", entry.Name, err.Error())
+	}
+}
+
+// editLibraryEntry asks for the topic and side of a file
+func editLibraryEntry(id string) {
+	entry, ok := lib.Entry(id)
+	if !ok {
+		return
+	}
+	window := dom.GetWindow().JSValue()
+	topic := window.Call("prompt", fmt.Sprintf("What topic is %s about?", entry.Name), entry.Topic)
+	if topic == js.Null() {
+		return
+	}
+	side := window.Call("prompt", fmt.Sprintf("Which side is %s for? Write Aff, Neg, or leave it empty for both", entry.Name), entry.Side)
+	if side == js.Null() {
+		return
+	}
+	entry.Topic = strings.TrimSpace(topic.String())
+	switch strings.ToLower(strings.TrimSpace(side.String())) {
+	case "aff", "affirmative":
+		entry.Side = library.Aff
+	case "neg", "negative":
+		entry.Side = library.Neg
+	default:
+		entry.Side = ""
+	}
+	storageError(lib.Update(entry))
+	renderLibrary()
+}
+
+// removeLibraryEntry deletes a file from the library after asking, leaving it open if it is open
+func removeLibraryEntry(id string) {
+	entry, ok := lib.Entry(id)
+	if !ok {
+		return
+	}
+	confirmed := dom.GetWindow().JSValue().Call("confirm", fmt.Sprintf("Remove %s from the library?", entry.Name)).Bool()
+	if !confirmed {
+		return
+	}
+	storageError(lib.Remove(id))
+	renderLibrary()
+}
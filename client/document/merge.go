@@ -0,0 +1,251 @@
+package document
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/dennwc/dom"
+	"github.com/pkg/errors"
+
+	"gitlab.com/256/DebateFrame/client/importer"
+	"gitlab.com/256/DebateFrame/client/log"
+	"gitlab.com/256/DebateFrame/client/merge"
+	"gitlab.com/256/DebateFrame/client/sections"
+	"gitlab.com/256/WebFrame/dyndom"
+)
+
+// pendingMerge is a merge waiting for its conflicts to be resolved
+var pendingMerge struct {
+	ours   *SaveableCase
+	theirs *SaveableCase
+	parts  []merge.Part
+}
+
+var mergeBound = false
+
+// OnMerge is the event listener for when the Merge button is pressed
+func OnMerge(e dom.Event) {
+	if !mergeBound {
+		dom.GetDocument().GetElementById("mergeStart").AddEventListener("click", func(e dom.Event) {
+			go startMerge()
+		})
+		dom.GetDocument().GetElementById("mergeFinish").AddEventListener("click", func(e dom.Event) {
+			go finishMerge(conflictChoices())
+		})
+		mergeBound = true
+	}
+	setInputValue("mergeOurs", "")
+	setInputValue("mergeTheirs", "")
+	dom.GetDocument().GetElementById("mergeConflicts").ClassList().Add("simplehide")
+	dom.GetDocument().GetElementById("mergeFinish").ClassList().Add("simplehide")
+	dom.GetDocument().GetElementById("mergeStart").ClassList().Remove("simplehide")
+	showModal("modal-merge")
+}
+
+// startMerge merges the two picked files, asking the user to resolve conflicts if there are any
+func startMerge() {
+	ours, err := pickedCase("mergeOurs")
+	if err != nil {
+		notify(err.Error(), "warning")
+		return
+	}
+	theirs, err := pickedCase("mergeTheirs")
+	if err != nil {
+		notify(err.Error(), "warning")
+		return
+	}
+
+	base := ""
+	if ancestor := commonAncestor(ours, theirs); ancestor != nil {
+		base = ancestor.Document
+	} else {
+		log.WarnMessage("The two versions share no revision, so every difference is a conflict")
+	}
+	baseSections, err := sectionsOf(base)
+	if err != nil {
+		notify(err.Error(), "danger")
+		return
+	}
+	ourSections, err := sectionsOf(ours.Document)
+	if err != nil {
+		notify(err.Error(), "danger")
+		return
+	}
+	theirSections, err := sectionsOf(theirs.Document)
+	if err != nil {
+		notify(err.Error(), "danger")
+		return
+	}
+
+	pendingMerge.ours = ours
+	pendingMerge.theirs = theirs
+	pendingMerge.parts = merge.Merge(baseSections, ourSections, theirSections)
+	conflicts := merge.Conflicts(pendingMerge.parts)
+	if len(conflicts) == 0 {
+		finishMerge(nil)
+		return
+	}
+	showConflicts(conflicts)
+}
+
+// pickedCase reads the case file picked in the file input with the given id
+func pickedCase(id string) (*SaveableCase, error) {
+	files := dom.GetDocument().GetElementById(id).JSValue().Get("files")
+	if files.Length() == 0 {
+		return nil, errors.New("pick both versions of the case first")
+	}
+	file := files.Index(0)
+	scase, err := decodeCase(blobToBytes(file))
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read %s", file.Get("name").String())
+	}
+	return scase, nil
+}
+
+// commonAncestor returns the newest revision that both cases have, or nil if they share none
+func commonAncestor(ours *SaveableCase, theirs *SaveableCase) *Revision {
+	theirIDs := make(map[string]bool)
+	for _, rev := range theirs.Revisions {
+		theirIDs[rev.ID] = true
+	}
+	for i := len(ours.Revisions) - 1; i >= 0; i-- {
+		if theirIDs[ours.Revisions[i].ID] {
+			return &ours.Revisions[i]
+		}
+	}
+	return nil
+}
+
+// sectionsOf splits the HTML into sections that start at each heading
+func sectionsOf(html string) ([]merge.Section, error) {
+	blocks, err := blocksOfHTML(html)
+	if err != nil {
+		return nil, err
+	}
+	return merge.Split(blocks, headingKey), nil
+}
+
+// headingKey identifies a heading block by its level and text
+func headingKey(block string) (string, bool) {
+	level := sections.Level(block)
+	if level == 0 {
+		return "", false
+	}
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(block))
+	if err != nil {
+		return "", false
+	}
+	return fmt.Sprintf("h%v|%s", level, strings.Join(strings.Fields(strings.ToLower(doc.Text())), " ")), true
+}
+
+// showConflicts lists each conflict side by side so the user can pick which side to keep
+func showConflicts(conflicts []*merge.Conflict) {
+	dom.GetDocument().GetElementById("mergeConflictCount").SetTextContent(
+		fmt.Sprintf("%v cards were changed by both of you. Pick which version to keep for each.", len(conflicts)))
+	list := dom.GetDocument().GetElementById("mergeConflictList")
+	list.SetInnerHTML("")
+	for _, conflict := range conflicts {
+		list.AppendChild(&conflictElem(conflict).Element)
+	}
+	dom.GetDocument().GetElementById("mergeConflicts").ClassList().Remove("simplehide")
+	dom.GetDocument().GetElementById("mergeStart").ClassList().Add("simplehide")
+	dom.GetDocument().GetElementById("mergeFinish").ClassList().Remove("simplehide")
+}
+
+func conflictElem(conflict *merge.Conflict) *dyndom.Element {
+	div := dyndom.CreateElement("div", "uk-margin")
+	sides := dyndom.CreateElement("div", "uk-grid-small", "uk-child-width-1-2")
+	sides.SetAttribute("uk-grid", "")
+	sides.AppendChild(conflictSide("Yours", conflict.Ours))
+	sides.AppendChild(conflictSide("Theirs", conflict.Theirs))
+	div.AppendChild(sides)
+
+	choice := dyndom.CreateElement("select", "uk-select", "mergeChoice")
+	choice.SetAttribute("data-key", conflict.Key)
+	for _, opt := range []struct {
+		choice merge.Choice
+		text   string
+	}{{merge.KeepOurs, "Keep yours"}, {merge.KeepTheirs, "Keep theirs"}, {merge.KeepBoth, "Keep both"}} {
+		option := dyndom.CreateElement("option")
+		option.SetAttribute("value", fmt.Sprintf("%v", int(opt.choice)))
+		option.SetTextContent(opt.text)
+		choice.AppendChild(option)
+	}
+	div.AppendChild(choice)
+	return div
+}
+
+func conflictSide(title string, section *merge.Section) *dyndom.Element {
+	side := dyndom.CreateElement("div")
+	card := dyndom.CreateElement("div", "uk-card", "uk-card-default", "uk-card-small", "uk-card-body", "mergeConflict")
+	heading := dyndom.CreateElement("h4")
+	heading.SetTextContent(title)
+	card.AppendChild(heading)
+	contents := dyndom.CreateElement("div")
+	if section == nil {
+		contents.SetTextContent("Deleted")
+	} else {
+		// Case files come from other people, so they are shown the way an imported page would be
+		safe, err := importer.Sanitize(joinBlocks(section.Blocks))
+		if err != nil {
+			log.WarnMessage("Failed to read a conflicting card: %s", err.Error())
+			safe = ""
+		}
+		contents.SetInnerHTML(safe)
+	}
+	card.AppendChild(contents)
+	side.AppendChild(card)
+	return side
+}
+
+// conflictChoices reads what the user picked for each conflict
+func conflictChoices() map[string]merge.Choice {
+	choices := make(map[string]merge.Choice)
+	for _, elem := range dom.GetDocument().QuerySelectorAll(".mergeChoice") {
+		choice, _ := strconv.Atoi(elem.JSValue().Get("value").String())
+		choices[elem.GetAttribute("data-key").String()] = merge.Choice(choice)
+	}
+	return choices
+}
+
+// finishMerge opens the merged case in a new tab
+func finishMerge(choices map[string]merge.Choice) {
+	hideModal("modal-merge")
+	html, err := importer.Sanitize(joinBlocks(merge.Resolve(pendingMerge.parts, choices)))
+	if err != nil {
+		notify(fmt.Sprintf("Failed to read the merged case: %s", err.Error()), "danger")
+		return
+	}
+	cs, err := NewCase(html, fmt.Sprintf("%s (merged)", pendingMerge.ours.Name))
+	if err != nil {
+		log.PanicMessage("Failed to create the merged case", err)
+	}
+	cs.Revisions = mergeRevisions(pendingMerge.ours.Revisions, pendingMerge.theirs.Revisions)
+	err = cs.Add()
+	if err != nil {
+		log.PanicMessage("Failed to add the merged case to the screen", err)
+	}
+	err = cs.SetActive()
+	if err != nil {
+		log.PanicMessage("Failed to set the merged case as the current case", err)
+	}
+}
+
+// mergeRevisions combines the revisions of both cases so later merges can find their common ancestor
+func mergeRevisions(ours []Revision, theirs []Revision) []Revision {
+	seen := make(map[string]bool)
+	revisions := []Revision{}
+	for _, rev := range append(append([]Revision{}, ours...), theirs...) {
+		if !seen[rev.ID] {
+			seen[rev.ID] = true
+			revisions = append(revisions, rev)
+		}
+	}
+	sort.SliceStable(revisions, func(i, j int) bool {
+		return revisions[i].Time < revisions[j].Time
+	})
+	return revisions
+}
@@ -0,0 +1,361 @@
+package document
+
+import (
+	"fmt"
+	"strings"
+	"syscall/js"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/dennwc/dom"
+	"golang.org/x/net/html/atom"
+
+	"gitlab.com/256/DebateFrame/client/history"
+	"gitlab.com/256/DebateFrame/client/sections"
+	"gitlab.com/256/WebFrame/dyndom"
+)
+
+// levelNames are what debaters call each heading level
+var levelNames = map[uint8]string{1: "Pocket", 2: "Hat", 3: "Block", 4: "Tag"}
+
+// activeOffset is how far down the window, in pixels, a heading has to scroll before it counts as the one being read
+const activeOffset = 120
+
+// navNode is a heading in the navigation pane and the headings under it
+type navNode struct {
+	key      string // Finds the heading again after typing has moved it, see idKey
+	id       string
+	level    uint8
+	text     string
+	children []*navNode
+}
+
+// navTree returns the outline of the headings in the blocks
+func navTree(blocks []string) []*navNode {
+	roots := []*navNode{}
+	// open is the chain of headings the next heading could go under, biggest first
+	open := []*navNode{}
+	counts := make(map[uint8]int)
+	for _, block := range blocks {
+		level := sections.Level(block)
+		n := counts[level]
+		countHeadings(counts, block)
+		if level == 0 {
+			continue
+		}
+		id := blockID(block)
+		node := &navNode{key: idKey(id, level, n), id: id, level: level, text: blockText(block)}
+		for len(open) > 0 && open[len(open)-1].level >= level {
+			open = open[:len(open)-1]
+		}
+		if len(open) == 0 {
+			roots = append(roots, node)
+		} else {
+			parent := open[len(open)-1]
+			parent.children = append(parent.children, node)
+		}
+		open = append(open, node)
+	}
+	return roots
+}
+
+// renderNav shows the headings of the case in its navigation pane, if they changed since it was last shown
+// The blocks of the case must already be the ones shown
+func (cs *Case) renderNav() {
+	headings := []string{}
+	for _, block := range cs.blocks {
+		if sections.Level(block) != 0 {
+			headings = append(headings, block)
+		}
+	}
+	shown := strings.Join(headings, "")
+	if shown == cs.navShown && cs.TOCElem.JSValue().Get("childNodes").Length() > 0 {
+		return
+	}
+	cs.navShown = shown
+	if cs.navCollapsed == nil {
+		cs.navCollapsed = make(map[string]bool)
+	}
+	cs.TOCElem.SetInnerHTML("")
+	cs.TOCElem.AppendChild(navListElem(cs, navTree(cs.blocks)))
+	cs.highlightNav()
+}
+
+func navListElem(cs *Case, nodes []*navNode) *dyndom.Element {
+	list := dyndom.CreateElement("ul", "navTree")
+	for _, node := range nodes {
+		list.AppendChild(navNodeElem(cs, node))
+	}
+	return list
+}
+
+func navNodeElem(cs *Case, node *navNode) *dyndom.Element {
+	item := dyndom.CreateElement("li")
+	row := dyndom.CreateElement("div", "navItem", fmt.Sprintf("navLevel%v", node.level))
+	row.SetAttribute("data-key", node.key)
+	item.AppendChild(row)
+
+	var children *dyndom.Element
+	if len(node.children) > 0 {
+		children = navListElem(cs, node.children)
+		toggle := newNavButton("chevron-down", "Collapse")
+		toggle.ClassList().Add("navToggle")
+		setCollapsed := func(collapsed bool) {
+			if collapsed {
+				children.ClassList().Add("simplehide")
+				toggle.SetAttribute("uk-icon", "icon: chevron-right")
+				toggle.SetAttribute("title", "Expand")
+			} else {
+				children.ClassList().Remove("simplehide")
+				toggle.SetAttribute("uk-icon", "icon: chevron-down")
+				toggle.SetAttribute("title", "Collapse")
+			}
+		}
+		setCollapsed(cs.navCollapsed[node.key])
+		toggle.AddEventListener("click", func(e dom.Event) {
+			collapsed := !cs.navCollapsed[node.key]
+			cs.navCollapsed[node.key] = collapsed
+			setCollapsed(collapsed)
+		})
+		row.AppendChild(toggle)
+	} else {
+		row.AppendChild(dyndom.CreateElement("span", "navToggle"))
+	}
+
+	title := dyndom.CreateElement("span", "navTitle")
+	title.SetTextContent(node.text)
+	if name, ok := levelNames[node.level]; ok {
+		title.SetAttribute("title", name)
+	}
+	title.AddEventListener("click", func(e dom.Event) {
+		go func() {
+			warnError(cs.jumpToBlock(cs.sectionAt(node.key)))
+		}()
+	})
+	title.AddEventListener("dblclick", func(e dom.Event) {
+		renameNavTitle(cs, title, node)
+	})
+	row.AppendChild(title)
+	row.AppendChild(navActionsElem(cs, title, node))
+	draggableSection(cs, row, node.key, false)
+
+	if children != nil {
+		item.AppendChild(children)
+	}
+	return item
+}
+
+// navActionsElem creates the buttons that edit the outline next to a heading
+func navActionsElem(cs *Case, title *dyndom.Element, node *navNode) *dyndom.Element {
+	actions := dyndom.CreateElement("span", "navActions")
+	rename := newNavButton("pencil", "Rename")
+	rename.AddEventListener("click", func(e dom.Event) {
+		renameNavTitle(cs, title, node)
+	})
+	promote := newNavButton("arrow-left", "Promote")
+	promote.AddEventListener("click", func(e dom.Event) {
+		go func() {
+			warnError(cs.ShiftSection(cs.sectionAt(node.key), -1))
+		}()
+	})
+	demote := newNavButton("arrow-right", "Demote")
+	demote.AddEventListener("click", func(e dom.Event) {
+		go func() {
+			warnError(cs.ShiftSection(cs.sectionAt(node.key), 1))
+		}()
+	})
+	remove := newNavButton("trash", "Delete section")
+	remove.AddEventListener("click", func(e dom.Event) {
+		confirmed := dom.GetWindow().JSValue().Call("confirm", fmt.Sprintf("Delete \"%s\" and everything under it?", node.text)).Bool()
+		if confirmed {
+			go func() {
+				warnError(cs.DeleteSection(cs.sectionAt(node.key)))
+			}()
+		}
+	})
+	if node.id != "" {
+		reference := newNavButton("link", "Link to this at the cursor")
+		js.Global().Call("keepSelection", reference.JSValue())
+		reference.AddEventListener("click", func(e dom.Event) {
+			cs.insertReference(node.id, node.text)
+		})
+		actions.AppendChild(reference)
+	}
+	actions.AppendChild(rename)
+	actions.AppendChild(promote)
+	actions.AppendChild(demote)
+	actions.AppendChild(remove)
+	return actions
+}
+
+// newNavButton creates an icon button without a link, since following # would scroll the page to the top
+func newNavButton(iconName string, title string) *dyndom.Element {
+	button := dyndom.CreateElement("a", "uk-icon-link")
+	button.SetAttribute("role", "button")
+	button.SetAttribute("title", title)
+	button.SetAttribute("uk-icon", fmt.Sprintf("icon: %s", iconName))
+	return button
+}
+
+// sectionAt returns the block index of the heading with the key, catching up with any typing first
+func (cs *Case) sectionAt(key string) int {
+	cs.recordEdit()
+	return keyBlock(cs.blocks, key)
+}
+
+// renameNavTitle swaps the title of a heading for a text box, renaming the heading on enter and cancelling on escape
+func renameNavTitle(cs *Case, title *dyndom.Element, node *navNode) {
+	if title.JSValue().Get("classList").Call("contains", "simplehide").Bool() {
+		return
+	}
+	input := dyndom.CreateElement("input", "uk-input", "uk-form-small", "navRename")
+	input.SetAttribute("type", "text")
+	input.JSValue().Set("value", node.text)
+	title.ClassList().Add("simplehide")
+	title.JSValue().Get("parentNode").Call("insertBefore", input.JSValue(), title.JSValue().Get("nextSibling"))
+	input.JSValue().Call("focus")
+	input.JSValue().Call("select")
+
+	cancelled := false
+	input.AddEventListener("keydown", func(e dom.Event) {
+		switch e.JSValue().Get("key").String() {
+		case "Enter":
+			input.JSValue().Call("blur")
+		case "Escape":
+			cancelled = true
+			input.JSValue().Call("blur")
+		}
+	})
+	input.AddEventListener("blur", func(e dom.Event) {
+		text := strings.Join(strings.Fields(input.JSValue().Get("value").String()), " ")
+		input.JSValue().Call("remove")
+		title.ClassList().Remove("simplehide")
+		if cancelled || text == "" || text == node.text {
+			return
+		}
+		title.SetTextContent(text)
+		go func() {
+			warnError(cs.RenameHeading(cs.sectionAt(node.key), text))
+		}()
+	})
+}
+
+// highlightNav marks the heading being read in the navigation pane, scrolling the pane to it if it is out of sight
+func (cs *Case) highlightNav() {
+	nodes := cs.EditorElem.JSValue().Get("childNodes")
+	active := ""
+	counts := make(map[uint8]int)
+	for i := 0; i < nodes.Length(); i++ {
+		node := nodes.Index(i)
+		if node.Get("nodeType").Int() != 1 {
+			continue
+		}
+		block := node.Get("outerHTML").String()
+		level := sections.Level(block)
+		n := counts[level]
+		countHeadings(counts, block)
+		if level == 0 {
+			continue
+		}
+		if node.Call("getBoundingClientRect").Get("top").Float() > activeOffset {
+			break
+		}
+		active = idKey(node.Get("id").String(), level, n)
+	}
+	toc := cs.TOCElem.JSValue()
+	if old := toc.Call("querySelector", ".navActive"); old != js.Null() {
+		old.Get("classList").Call("remove", "navActive")
+	}
+	if active == "" {
+		return
+	}
+	item := toc.Call("querySelector", fmt.Sprintf(".navItem[data-key='%s']", active))
+	if item == js.Null() {
+		return
+	}
+	item.Get("classList").Call("add", "navActive")
+	top, height := item.Get("offsetTop").Float(), item.Get("offsetHeight").Float()
+	if top < toc.Get("scrollTop").Float() || top+height > toc.Get("scrollTop").Float()+toc.Get("clientHeight").Float() {
+		toc.Set("scrollTop", top)
+	}
+}
+
+// RenameHeading replaces the text of the heading at the block index, keeping its attributes
+func (cs *Case) RenameHeading(index int, text string) error {
+	cs.recordEdit()
+	if index < 0 || index >= len(cs.blocks) || sections.Level(cs.blocks[index]) == 0 {
+		return fmt.Errorf("there is no heading %v to rename", index)
+	}
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(cs.blocks[index]))
+	if err != nil {
+		return err
+	}
+	heading := doc.Find("body").Children().First()
+	heading.SetText(text)
+	renamed, err := goquery.OuterHtml(heading)
+	if err != nil {
+		return err
+	}
+	return cs.apply(history.Op{
+		Label:   "Rename heading",
+		Splices: []history.Splice{{Index: index, Removed: []string{cs.blocks[index]}, Inserted: []string{renamed}}},
+	})
+}
+
+// ShiftSection changes the level of the heading at the block index and every heading under it, promoting them for -1 and demoting them for 1
+func (cs *Case) ShiftSection(index int, by int) error {
+	cs.recordEdit()
+	if index < 0 || index >= len(cs.blocks) || sections.Level(cs.blocks[index]) == 0 {
+		return fmt.Errorf("there is no heading %v to move", index)
+	}
+	end := sectionEnd(cs.blocks, index)
+	shifted := []string{}
+	for _, block := range cs.blocks[index:end] {
+		level := int(sections.Level(block))
+		if level == 0 {
+			shifted = append(shifted, block)
+			continue
+		}
+		if level+by < 1 || level+by > 6 {
+			return fmt.Errorf("headings only go from 1 to 6, so this section can't move further")
+		}
+		retagged, err := retag(block, uint8(level+by))
+		if err != nil {
+			return err
+		}
+		shifted = append(shifted, retagged)
+	}
+	label := "Demote"
+	if by < 0 {
+		label = "Promote"
+	}
+	return cs.apply(history.Op{
+		Label:   label,
+		Splices: []history.Splice{{Index: index, Removed: append([]string{}, cs.blocks[index:end]...), Inserted: shifted}},
+	})
+}
+
+// DeleteSection removes the heading at the block index and everything under it
+func (cs *Case) DeleteSection(index int) error {
+	cs.recordEdit()
+	if index < 0 || index >= len(cs.blocks) {
+		return fmt.Errorf("there is no block %v to delete", index)
+	}
+	end := sectionEnd(cs.blocks, index)
+	return cs.apply(history.Op{
+		Label:   "Delete section",
+		Splices: []history.Splice{{Index: index, Removed: append([]string{}, cs.blocks[index:end]...)}},
+	})
+}
+
+// retag turns a heading block into a heading of another level
+func retag(block string, level uint8) (string, error) {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(block))
+	if err != nil {
+		return "", err
+	}
+	heading := doc.Find("body").Children().First()
+	node := heading.Nodes[0]
+	node.Data = fmt.Sprintf("h%v", level)
+	node.DataAtom = atom.Lookup([]byte(node.Data))
+	return goquery.OuterHtml(heading)
+}
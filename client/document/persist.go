@@ -0,0 +1,232 @@
+package document
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/pkg/errors"
+
+	"gitlab.com/256/DebateFrame/client/idb"
+	"gitlab.com/256/DebateFrame/client/library"
+	"gitlab.com/256/DebateFrame/client/log"
+)
+
+const (
+	dbName        = "debateframe"
+	dbVersion     = 3
+	casesStore    = "cases"   // Holds a SaveableCase as json for every open case, keyed by case ID
+	sessionStore  = "session" // Holds the sessionState under sessionKey
+	sessionKey    = "tabs"
+	autosaveDelay = 1000 // Milliseconds of no typing before a case is saved to storage
+)
+
+var db *idb.DB
+
+// sessionState is what is needed to reopen the same tabs after a reload
+type sessionState struct {
+	Cases  []string // IDs of the open cases in tab order
+	Active string   // ID of the case that was on screen
+}
+
+// openStorage opens the IndexedDB database that cases are kept in
+func openStorage() error {
+	var err error
+	db, err = idb.Open(dbName, dbVersion, casesStore, sessionStore, snapshotStore, library.EntriesStore, library.DocsStore)
+	if err != nil {
+		return errors.Wrap(err, "failed to open IndexedDB")
+	}
+	lib, err = library.Open(db)
+	if err != nil {
+		// Open cases still work without the library, so this isn't fatal
+		log.WarnMessage("Failed to open the library: %s", err.Error())
+	}
+	return nil
+}
+
+// restoreCases reopens every case that was open last time, or a blank case if there were none
+func restoreCases() error {
+	session, err := loadSession()
+	if err != nil {
+		log.WarnMessage("Failed to load the last session, starting with a blank case: %s", err.Error())
+		session = sessionState{}
+	}
+
+	var active *Case
+	for _, id := range session.Cases {
+		cs, err := loadCase(id)
+		if err != nil {
+			log.WarnMessage("Failed to restore case %s: %s", id, err.Error())
+			continue
+		}
+		err = cs.Add()
+		if err != nil {
+			return errors.Wrap(err, "failed to add a restored case to the screen")
+		}
+		if id == session.Active || active == nil {
+			active = cs
+		}
+	}
+
+	if active == nil {
+		active, err = NewCase("", "Untitled Document")
+		if err != nil {
+			return errors.Wrap(err, "failed to create new blank case")
+		}
+		err = active.Add()
+		if err != nil {
+			return errors.Wrap(err, "failed to add the case to the screen")
+		}
+	}
+	err = active.SetActive()
+	if err != nil {
+		return errors.Wrap(err, "failed to set the restored case as the active case")
+	}
+	return nil
+}
+
+func loadSession() (sessionState, error) {
+	session := sessionState{}
+	str, ok, err := db.Get(sessionStore, sessionKey)
+	if err != nil || !ok {
+		return session, err
+	}
+	err = json.Unmarshal([]byte(str), &session)
+	if err != nil {
+		return session, errors.Wrap(err, "failed to decode the session from json")
+	}
+	return session, nil
+}
+
+func loadCase(id string) (*Case, error) {
+	str, ok, err := db.Get(casesStore, id)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, fmt.Errorf("case %s is not in storage", id)
+	}
+	scase := SaveableCase{}
+	err = json.Unmarshal([]byte(str), &scase)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to decode the case from json")
+	}
+	return scase.Normalize(), nil
+}
+
+// persistCase saves the current contents of the case to storage
+func persistCase(cs *Case) {
+	if cs.closed {
+		return
+	}
+	err := cs.syncDocument()
+	if err != nil {
+		log.WarnMessage("Failed to read case %s from the editor: %s", cs.Name, err.Error())
+		return
+	}
+	str, err := json.Marshal(cs.Saveable())
+	if err != nil {
+		log.WarnMessage("Failed to convert case %s to json: %s", cs.Name, err.Error())
+		return
+	}
+	storageError(db.Put(casesStore, cs.ID, string(str)))
+}
+
+// Close removes the case's tab and its copy in storage, switching to the tab next to it
+// The last tab is replaced by a blank case, since there is always one to type in
+func (cs *Case) Close() {
+	if cs.Collab != nil {
+		cs.Collab.Close()
+		cs.Collab = nil
+	}
+	cs.closed = true
+	for _, stop := range cs.stops {
+		stop()
+	}
+	cs.stops = nil
+	if speechCase == cs {
+		speechCase = nil
+	}
+	if cs.dropSection != nil {
+		cs.dropSection.Release()
+		cs.dropSection = nil
+	}
+	index := -1
+	for i, open := range openCases {
+		if open == cs {
+			index = i
+		}
+	}
+	if index != -1 {
+		openCases = append(openCases[:index], openCases[index+1:]...)
+	}
+	for _, elem := range cs.screen {
+		elem.JSValue().Call("remove")
+	}
+	storageError(db.Delete(casesStore, cs.ID))
+	err := deleteSnapshots(cs.ID)
+	if err != nil {
+		log.WarnMessage("Failed to remove the snapshots of %s: %s", cs.Name, err.Error())
+	}
+
+	if currentCase != cs {
+		persistSession()
+		return
+	}
+	currentCase = nil
+	if len(openCases) == 0 {
+		blank, err := NewCase("", "Untitled Document")
+		if err != nil {
+			log.WarnMessage("Failed to create a blank case: %s", err.Error())
+			return
+		}
+		err = blank.Add()
+		if err != nil {
+			log.WarnMessage("Failed to add a blank case: %s", err.Error())
+			return
+		}
+	}
+	if index >= len(openCases) {
+		index = len(openCases) - 1
+	} else if index < 0 {
+		index = 0
+	}
+	err = openCases[index].SetActive()
+	if err != nil {
+		log.WarnMessage("Failed to switch to case %s: %s", openCases[index].Name, err.Error())
+	}
+	persistSession()
+}
+
+// persistSession saves which cases are open so they can be reopened after a reload
+func persistSession() {
+	session := sessionState{}
+	for _, cs := range openCases {
+		session.Cases = append(session.Cases, cs.ID)
+	}
+	if currentCase != nil {
+		session.Active = currentCase.ID
+	}
+	str, err := json.Marshal(session)
+	if err != nil {
+		log.WarnMessage("Failed to convert the session to json: %s", err.Error())
+		return
+	}
+	storageError(db.Put(sessionStore, sessionKey, string(str)))
+}
+
+// storageError tells the user about a failed write, since their work isn't being saved
+func storageError(err error) {
+	if err == nil {
+		return
+	}
+	log.WarnMessage("Failed to write to storage: %s", err.Error())
+	message := "Your work could not be saved to the browser. Download your cases to keep them safe."
+	if errors.Cause(err) == idb.ErrQuota {
+		message = "The browser is out of storage space for DebateFrame, so your work is no longer being saved."
+		usage, quota, err := idb.Estimate()
+		if err == nil {
+			message = fmt.Sprintf("%s (%.1f MB of %.1f MB used)", message, usage/1e6, quota/1e6)
+		}
+	}
+	notify(message, "danger")
+}
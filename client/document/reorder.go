@@ -0,0 +1,103 @@
+package document
+
+import (
+	"fmt"
+	"strings"
+	"syscall/js"
+
+	"gitlab.com/256/DebateFrame/client/document/card"
+	"gitlab.com/256/DebateFrame/client/sections"
+	"gitlab.com/256/WebFrame/dyndom"
+)
+
+// sectionKey identifies a heading by its level and how many headings of that level come before it,
+// which still finds it after typing moves the blocks around it
+// Headings nested in other elements are counted too, so a card's Heading is its key
+func sectionKey(level uint8, n int) string {
+	return fmt.Sprintf("%v:%v", level, n)
+}
+
+// idKey identifies a heading by its id, which also survives edits that add or remove headings before it
+// Headings only go without an id until the next edit is recorded, so sectionKey is the fallback
+func idKey(id string, level uint8, n int) string {
+	if id == "" {
+		return sectionKey(level, n)
+	}
+	return "#" + id
+}
+
+// countHeadings adds every heading in the block to the count of its level, including headings nested in other elements
+func countHeadings(counts map[uint8]int, block string) {
+	for _, level := range sections.Headings(block) {
+		counts[level]++
+	}
+}
+
+// keyBlock returns the block index of the heading with the key, or -1 if there isn't one
+func keyBlock(blocks []string, key string) int {
+	if strings.HasPrefix(key, "#") {
+		id := strings.TrimPrefix(key, "#")
+		index := idBlock(blocks, id)
+		if index == -1 || blockID(blocks[index]) != id {
+			// A heading nested in another element doesn't start a section of its own
+			return -1
+		}
+		return index
+	}
+	var level uint8
+	var n int
+	_, err := fmt.Sscanf(key, "%d:%d", &level, &n)
+	if err != nil {
+		return -1
+	}
+	return headingBlock(blocks, level, n)
+}
+
+// moveDropped moves the section at from next to the section at target, after it if after is set
+// Dropping a section onto itself or something under it does nothing
+func (cs *Case) moveDropped(from int, target int, after bool) {
+	if from < 0 || from >= len(cs.blocks) || target < 0 || target >= len(cs.blocks) {
+		return
+	}
+	to := target
+	if after {
+		to = sectionEnd(cs.blocks, target)
+	}
+	if to >= from && to <= sectionEnd(cs.blocks, from) {
+		return
+	}
+	err := cs.MoveSection(from, to)
+	if err != nil {
+		notify(err.Error(), "warning")
+	}
+}
+
+// draggableSection lets the section of the heading with the key be dragged by the element, and other sections be dropped on it
+// The dragging itself is done by sectionDrag in JS, since Go only sees the drag events after they have been handled
+func draggableSection(cs *Case, elem *dyndom.Element, key string, across bool) {
+	js.Global().Call("sectionDrag", elem.JSValue(), key, across, dropCallback(cs))
+}
+
+// dropCallback returns the callback that moves a dropped section, which every element of a case shares so redrawing doesn't leak them
+func dropCallback(cs *Case) js.Callback {
+	if cs.dropSection == nil {
+		dropSection := js.NewCallback(func(args []js.Value) {
+			from, target, after := args[0].String(), args[1].String(), args[2].Bool()
+			go func() {
+				cs.recordEdit()
+				cs.moveDropped(keyBlock(cs.blocks, from), keyBlock(cs.blocks, target), after)
+			}()
+		})
+		cs.dropSection = &dropSection
+	}
+	return *cs.dropSection
+}
+
+// draggableCard lets a card in the card view be dragged onto another to move it there in the document
+// Cards can only be dragged when they are shown in document order, since that is the order being changed
+func draggableCard(cs *Case, c *card.Card) {
+	if cs.View.Sort != card.ByPosition {
+		return
+	}
+	draggableSection(cs, c.Element, idKey(c.ID, c.Level, c.Heading), !cs.View.List)
+}
@@ -0,0 +1,58 @@
+package document
+
+import (
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+
+	"gitlab.com/256/DebateFrame/client/document/card"
+	"gitlab.com/256/DebateFrame/client/log"
+	"gitlab.com/256/DebateFrame/client/sections"
+)
+
+// reparseDelay is how many milliseconds of no typing pass before the cards are found again
+const reparseDelay = 500
+
+// parseCards returns the cards in the HTML whose tags are headings of the level
+func parseCards(html string, level uint8) []*sections.Card {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(html))
+	if err != nil {
+		log.WarnMessage("Failed to parse a card: %s", err.Error())
+		return []*sections.Card{}
+	}
+	return sectionCards(toInfoCards(card.GetCardsAt(doc, level)))
+}
+
+// sectionCards converts info cards to the cards sections compares
+func sectionCards(icards []*InfoCard) []*sections.Card {
+	cards := []*sections.Card{}
+	for _, icard := range icards {
+		cards = append(cards, (*sections.Card)(icard))
+	}
+	return cards
+}
+
+// fromSectionCards converts the cards sections found to info cards
+func fromSectionCards(cards []*sections.Card) []*InfoCard {
+	icards := []*InfoCard{}
+	for _, scard := range cards {
+		icards = append(icards, (*InfoCard)(scard))
+	}
+	return icards
+}
+
+// reparse finds the cards of the case again after typing, updating the navigation pane and card view if they changed
+func (cs *Case) reparse() {
+	cs.recordEdit()
+	cs.renderNav()
+	cards := cs.parsed.Cards(cs.blocks, parseCards)
+	if sections.Same(sectionCards(cs.Cards), cards) {
+		return
+	}
+	cs.Cards = fromSectionCards(cards)
+	err := cs.syncDocument()
+	if err != nil {
+		log.WarnMessage("Failed to read case %s from the editor: %s", cs.Name, err.Error())
+	}
+	renderCards(cs)
+}
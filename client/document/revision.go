@@ -0,0 +1,196 @@
+package document
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/dennwc/dom"
+	"github.com/pkg/errors"
+
+	"gitlab.com/256/DebateFrame/client/config"
+	"gitlab.com/256/DebateFrame/client/document/card"
+	"gitlab.com/256/WebFrame/dyndom"
+)
+
+// currentRevision is the option value used to compare against what is in the editor right now
+const currentRevision = "current"
+
+// Revision is a version of a case as it was when it was saved
+type Revision struct {
+	ID       string
+	Time     int64 // Unix seconds
+	Author   string
+	Document string
+}
+
+// addRevision keeps the current document as a revision, unless it is the same as the last one
+func (cs *Case) addRevision() error {
+	html, err := cs.Document.Html()
+	if err != nil {
+		return errors.Wrap(err, "failed to convert the case document to HTML")
+	}
+	if len(cs.Revisions) > 0 && cs.Revisions[len(cs.Revisions)-1].Document == html {
+		return nil
+	}
+	cs.Revisions = append(cs.Revisions, Revision{
+		ID:       newUUID(),
+		Time:     time.Now().Unix(),
+		Author:   config.CurrentConfig.AuthorName,
+		Document: html,
+	})
+	return nil
+}
+
+// revision returns the revision of the case with the given ID, which can also be currentRevision
+func (cs *Case) revision(id string) (*Revision, error) {
+	if id == currentRevision {
+		err := cs.syncDocument()
+		if err != nil {
+			return nil, err
+		}
+		html, err := cs.Document.Html()
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to convert the case document to HTML")
+		}
+		return &Revision{ID: currentRevision, Time: time.Now().Unix(), Author: config.CurrentConfig.AuthorName, Document: html}, nil
+	}
+	for i := range cs.Revisions {
+		if cs.Revisions[i].ID == id {
+			return &cs.Revisions[i], nil
+		}
+	}
+	return nil, fmt.Errorf("there is no revision %s", id)
+}
+
+// cards finds the cards in the revision
+func (rev *Revision) cards() ([]*card.Card, error) {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(rev.Document))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create document from revision")
+	}
+	return card.GetCards(doc), nil
+}
+
+// label describes the revision for the revision pickers
+func (rev *Revision) label() string {
+	if rev.ID == currentRevision {
+		return "Current document"
+	}
+	author := rev.Author
+	if author == "" {
+		author = "Unknown"
+	}
+	return fmt.Sprintf("%s by %s", time.Unix(rev.Time, 0).Format("Jan 2 3:04 PM"), author)
+}
+
+var revisionsBound = false
+
+// OnRevisions is the event listener for when the revisions button is pressed
+func OnRevisions(e dom.Event) {
+	if !revisionsBound {
+		dom.GetDocument().GetElementById("revisionCompare").AddEventListener("click", func(e dom.Event) {
+			go compareRevisions(currentCase, inputValue("revisionFrom"), inputValue("revisionTo"))
+		})
+		revisionsBound = true
+	}
+
+	from := dom.GetDocument().GetElementById("revisionFrom")
+	to := dom.GetDocument().GetElementById("revisionTo")
+	from.SetInnerHTML("")
+	to.SetInnerHTML("")
+	to.AppendChild(&revisionOption(&Revision{ID: currentRevision}).Element)
+	for i := len(currentCase.Revisions) - 1; i >= 0; i-- {
+		from.AppendChild(&revisionOption(&currentCase.Revisions[i]).Element)
+		to.AppendChild(&revisionOption(&currentCase.Revisions[i]).Element)
+	}
+	dom.GetDocument().GetElementById("revisionChanges").SetInnerHTML("")
+	if len(currentCase.Revisions) == 0 {
+		notify("This case has no revisions yet. One is made every time it is downloaded.", "primary")
+		return
+	}
+	showModal("modal-revisions")
+}
+
+func revisionOption(rev *Revision) *dyndom.Element {
+	option := dyndom.CreateElement("option")
+	option.SetAttribute("value", rev.ID)
+	option.SetTextContent(rev.label())
+	return option
+}
+
+// compareRevisions lists the card changes between two revisions of the case
+func compareRevisions(cs *Case, fromID string, toID string) {
+	list := dom.GetDocument().GetElementById("revisionChanges")
+	list.SetInnerHTML("")
+	changes, err := diffRevisions(cs, fromID, toID)
+	if err != nil {
+		notify(err.Error(), "danger")
+		return
+	}
+	if len(changes) == 0 {
+		item := dyndom.CreateElement("li")
+		item.SetTextContent("No cards changed")
+		list.AppendChild(&item.Element)
+		return
+	}
+	for _, change := range changes {
+		list.AppendChild(&changeItem(change).Element)
+	}
+}
+
+func diffRevisions(cs *Case, fromID string, toID string) ([]card.Change, error) {
+	from, err := cs.revision(fromID)
+	if err != nil {
+		return nil, err
+	}
+	to, err := cs.revision(toID)
+	if err != nil {
+		return nil, err
+	}
+	fromCards, err := from.cards()
+	if err != nil {
+		return nil, err
+	}
+	toCards, err := to.cards()
+	if err != nil {
+		return nil, err
+	}
+	return card.Diff(fromCards, toCards), nil
+}
+
+var changeLabels = map[card.ChangeKind]string{
+	card.Added:    "uk-label-success",
+	card.Removed:  "uk-label-danger",
+	card.Modified: "uk-label-warning",
+}
+
+func changeItem(change card.Change) *dyndom.Element {
+	item := dyndom.CreateElement("li")
+	label := dyndom.CreateElement("span", "uk-label", changeLabels[change.Kind])
+	label.SetTextContent(change.Kind.String())
+	item.AppendChild(label)
+
+	shown := change.New
+	if shown == nil {
+		shown = change.Old
+	}
+	title := dyndom.CreateElement("span", "uk-margin-small-left")
+	title.SetTextContent(fmt.Sprintf("%s (%s %v)", shown.Title, shown.Author, shown.Year))
+	item.AppendChild(title)
+
+	details := []string{}
+	if change.TextChanged {
+		details = append(details, "text changed")
+	}
+	if change.HighlightChanged {
+		details = append(details, fmt.Sprintf("highlighting changed (%v to %v highlighted parts)", len(change.Old.Highlights), len(change.New.Highlights)))
+	}
+	if len(details) > 0 {
+		detail := dyndom.CreateElement("div", "uk-text-meta")
+		detail.SetTextContent(strings.Join(details, ", "))
+		item.AppendChild(detail)
+	}
+	return item
+}
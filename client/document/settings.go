@@ -0,0 +1,60 @@
+package document
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/dennwc/dom"
+
+	"gitlab.com/256/DebateFrame/client/cite"
+	"gitlab.com/256/DebateFrame/client/config"
+)
+
+var settingsBound = false
+
+// OnSettings is the event listener for when the Settings button is pressed
+func OnSettings(e dom.Event) {
+	if !settingsBound {
+		dom.GetDocument().GetElementById("settingsSave").AddEventListener("click", saveSettings)
+		settingsBound = true
+	}
+	setInputValue("settingsSnapshots", strconv.Itoa(config.CurrentConfig.SnapshotRetention))
+	setInputValue("settingsAuthor", config.CurrentConfig.AuthorName)
+	setInputValue("settingsCiteStyle", config.CurrentConfig.CiteStyle)
+	setInputValue("settingsCiteTemplate", config.CurrentConfig.CiteTemplate)
+	showModal("modal-settings")
+}
+
+// saveSettings copies the settings form into the current Configuration
+func saveSettings(e dom.Event) {
+	retention, err := strconv.Atoi(inputValue("settingsSnapshots"))
+	if err != nil || retention < 1 {
+		notify("The number of snapshots must be a whole number above 0", "warning")
+		return
+	}
+	style := inputValue("settingsCiteStyle")
+	tmpl := strings.TrimSpace(inputValue("settingsCiteTemplate"))
+	if style == cite.Custom {
+		if tmpl == "" {
+			notify("Write a cite template to use the custom style", "warning")
+			return
+		}
+		if _, err := cite.Compile(tmpl); err != nil {
+			notify(err.Error(), "warning")
+			return
+		}
+	}
+	config.CurrentConfig.SnapshotRetention = retention
+	config.CurrentConfig.AuthorName = strings.TrimSpace(inputValue("settingsAuthor"))
+	config.CurrentConfig.CiteStyle = style
+	config.CurrentConfig.CiteTemplate = tmpl
+	hideModal("modal-settings")
+}
+
+func inputValue(id string) string {
+	return dom.GetDocument().GetElementById(id).JSValue().Get("value").String()
+}
+
+func setInputValue(id string, value string) {
+	dom.GetDocument().GetElementById(id).JSValue().Set("value", value)
+}
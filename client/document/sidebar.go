@@ -12,4 +12,28 @@ func generateSidebars() {
 	btn.OnClick(OnReadMode)
 	dom.GetDocument().GetElementById("appSideBar").AppendChild(btn)
 	btn.Element.SetId("readMode")
+	btn = newButton("Merge")
+	btn.OnClick(OnMerge)
+	dom.GetDocument().GetElementById("appSideBar").AppendChild(btn)
+	btn = newButton("Cut Card")
+	btn.OnClick(OnCutter)
+	dom.GetDocument().GetElementById("appSideBar").AppendChild(btn)
+	btn = newButton("Disclose")
+	btn.OnClick(OnDisclose)
+	dom.GetDocument().GetElementById("appSideBar").AppendChild(btn)
+	btn = newButton("Library")
+	btn.OnClick(OnLibrary)
+	dom.GetDocument().GetElementById("appSideBar").AppendChild(btn)
+	btn = newButton("Find Duplicates")
+	btn.OnClick(OnDuplicates)
+	dom.GetDocument().GetElementById("appSideBar").AppendChild(btn)
+	btn = newButton("Team Tub")
+	btn.OnClick(OnTub)
+	dom.GetDocument().GetElementById("appSideBar").AppendChild(btn)
+	btn = newButton("Collaborate")
+	btn.OnClick(OnCollaborate)
+	dom.GetDocument().GetElementById("appSideBar").AppendChild(btn)
+	btn = newButton("Settings")
+	btn.OnClick(OnSettings)
+	dom.GetDocument().GetElementById("appSideBar").AppendChild(btn)
 }
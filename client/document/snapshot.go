@@ -0,0 +1,289 @@
+package document
+
+import (
+	"crypto/sha1"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall/js"
+	"time"
+
+	"github.com/dennwc/dom"
+	"github.com/pkg/errors"
+
+	"gitlab.com/256/DebateFrame/client/config"
+	"gitlab.com/256/DebateFrame/client/log"
+	"gitlab.com/256/WebFrame/dyndom"
+)
+
+const (
+	snapshotStore    = "snapshots"      // Holds a snapshot as json under "<case ID>/<unix nanoseconds>"
+	snapshotIdle     = 5000             // Milliseconds of no typing before a snapshot is taken
+	snapshotInterval = time.Minute * 2  // How often every open case is snapshotted while typing continues
+	runningPrefix    = "running/"       // LocalStorage key prefix of the time each open tab was last seen, by tab
+	legacyRunningKey = "running"        // LocalStorage key that only existed while DebateFrame was open, before tabs had their own
+	runningBeat      = time.Second * 30 // How often a tab marks itself as still running
+	runningStale     = time.Minute * 3  // How long a tab can go unseen before it is taken to have crashed, long enough for browsers slowing background tabs
+)
+
+// snapshot is a copy of a case at a point in time, used to recover work after a crash
+type snapshot struct {
+	Time int64 // Unix nanoseconds
+	Case SaveableCase
+}
+
+// lastSnapshot holds a hash of each case's document when it was last snapshotted, so unchanged cases are skipped
+var lastSnapshot = make(map[string]string)
+
+// startSnapshots snapshots every open case periodically, and marks the tab as running so crashes can be detected
+// It returns true if a tab stopped without shutting down
+func startSnapshots() bool {
+	crashed := crashedTabs()
+	key := runningPrefix + newUUID()
+	go func() {
+		for {
+			config.LocalStorage.SetItem(key, fmt.Sprintf("%v", time.Now().Unix()))
+			time.Sleep(runningBeat)
+		}
+	}()
+	dom.GetWindow().AddEventListener("beforeunload", func(dom.Event) {
+		config.LocalStorage.RemoveItem(key)
+	})
+
+	go func() {
+		for {
+			time.Sleep(snapshotInterval)
+			for _, cs := range openCases {
+				snapshotCase(cs)
+			}
+		}
+	}()
+	return crashed
+}
+
+// crashedTabs returns whether a tab stopped without shutting down, forgetting the tabs that did
+// Every open tab marks itself as running, so another tab being open doesn't look like a crash unless it stopped being seen
+func crashedTabs() bool {
+	_, crashed := config.LocalStorage.GetItem(legacyRunningKey)
+	config.LocalStorage.RemoveItem(legacyRunningKey)
+	storage := js.Global().Get("localStorage")
+	keys := []string{}
+	for i := 0; i < storage.Get("length").Int(); i++ {
+		if key := storage.Call("key", i).String(); strings.HasPrefix(key, runningPrefix) {
+			keys = append(keys, key)
+		}
+	}
+	for _, key := range keys {
+		seen, _ := config.LocalStorage.GetItem(key)
+		unix, err := strconv.ParseInt(seen, 10, 64)
+		if err == nil && time.Since(time.Unix(unix, 0)) < runningStale {
+			continue
+		}
+		crashed = true
+		config.LocalStorage.RemoveItem(key)
+	}
+	return crashed
+}
+
+// snapshotCase stores a snapshot of the case if it changed since the last one, then removes snapshots past the retention limit
+func snapshotCase(cs *Case) {
+	if cs.closed {
+		return
+	}
+	err := cs.syncDocument()
+	if err != nil {
+		log.WarnMessage("Failed to read case %s from the editor: %s", cs.Name, err.Error())
+		return
+	}
+	scase := cs.Saveable()
+	hash := fmt.Sprintf("%x", sha1.Sum([]byte(scase.Document)))
+	if lastSnapshot[cs.ID] == hash {
+		return
+	}
+
+	now := time.Now().UnixNano()
+	str, err := json.Marshal(snapshot{Time: now, Case: *scase})
+	if err != nil {
+		log.WarnMessage("Failed to convert snapshot of %s to json: %s", cs.Name, err.Error())
+		return
+	}
+	err = db.Put(snapshotStore, snapshotKey(cs.ID, now), string(str))
+	if err != nil {
+		storageError(err)
+		return
+	}
+	lastSnapshot[cs.ID] = hash
+	log.DebugMessage("Took a snapshot of %s", cs.Name)
+
+	err = pruneSnapshots(cs.ID)
+	if err != nil {
+		log.WarnMessage("Failed to remove old snapshots of %s: %s", cs.Name, err.Error())
+	}
+}
+
+func snapshotKey(caseID string, time int64) string {
+	// Zero padded so keys sort by time
+	return fmt.Sprintf("%s/%020d", caseID, time)
+}
+
+// snapshotKeys returns the keys of every snapshot of each case, oldest first
+func snapshotKeys() (map[string][]string, error) {
+	keys, err := db.Keys(snapshotStore)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list snapshots")
+	}
+	sort.Strings(keys)
+	byCase := make(map[string][]string)
+	for _, key := range keys {
+		caseID := strings.SplitN(key, "/", 2)[0]
+		byCase[caseID] = append(byCase[caseID], key)
+	}
+	return byCase, nil
+}
+
+// pruneSnapshots deletes the oldest snapshots of the case until only the configured amount remain
+func pruneSnapshots(caseID string) error {
+	byCase, err := snapshotKeys()
+	if err != nil {
+		return err
+	}
+	keys := byCase[caseID]
+	retention := config.CurrentConfig.SnapshotRetention
+	if retention < 1 {
+		retention = 1
+	}
+	for len(keys) > retention {
+		err = db.Delete(snapshotStore, keys[0])
+		if err != nil {
+			return err
+		}
+		keys = keys[1:]
+	}
+	return nil
+}
+
+// deleteSnapshots removes every snapshot of the case, once it has been recovered or closed
+func deleteSnapshots(caseID string) error {
+	byCase, err := snapshotKeys()
+	if err != nil {
+		return err
+	}
+	for _, key := range byCase[caseID] {
+		err = db.Delete(snapshotStore, key)
+		if err != nil {
+			return err
+		}
+	}
+	delete(lastSnapshot, caseID)
+	return nil
+}
+
+func loadSnapshot(key string) (*snapshot, error) {
+	str, ok, err := db.Get(snapshotStore, key)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, fmt.Errorf("snapshot %s is not in storage", key)
+	}
+	snap := snapshot{}
+	err = json.Unmarshal([]byte(str), &snap)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to decode the snapshot from json")
+	}
+	return &snap, nil
+}
+
+// unsavedSnapshots returns the newest snapshot of every case whose saved contents don't match it
+func unsavedSnapshots() ([]*snapshot, error) {
+	byCase, err := snapshotKeys()
+	if err != nil {
+		return nil, err
+	}
+	unsaved := []*snapshot{}
+	for caseID, keys := range byCase {
+		snap, err := loadSnapshot(keys[len(keys)-1])
+		if err != nil {
+			log.WarnMessage("Skipping snapshot of %s: %s", caseID, err.Error())
+			continue
+		}
+		if cs := openCase(caseID); cs != nil {
+			html, err := cs.Document.Html()
+			if err == nil && html == snap.Case.Document {
+				continue
+			}
+		}
+		unsaved = append(unsaved, snap)
+	}
+	sort.Slice(unsaved, func(i, j int) bool {
+		return unsaved[i].Time > unsaved[j].Time
+	})
+	return unsaved, nil
+}
+
+// openCase returns the open case with the given ID, or nil if it isn't open
+func openCase(id string) *Case {
+	for _, cs := range openCases {
+		if cs.ID == id {
+			return cs
+		}
+	}
+	return nil
+}
+
+// showRecovery lists the unsaved snapshots in the recovery dialog, if there are any
+func showRecovery() {
+	snaps, err := unsavedSnapshots()
+	if err != nil {
+		log.WarnMessage("Failed to look for unsaved work: %s", err.Error())
+		return
+	}
+	if len(snaps) == 0 {
+		return
+	}
+
+	list := dom.GetDocument().GetElementById("recoveryList")
+	list.SetInnerHTML("")
+	for _, snap := range snaps {
+		list.AppendChild(&recoveryItem(snap).Element)
+	}
+	showModal("modal-recovery")
+}
+
+func recoveryItem(snap *snapshot) *dyndom.Element {
+	item := dyndom.CreateElement("li", "uk-flex", "uk-flex-between", "uk-flex-middle")
+	label := dyndom.CreateElement("span")
+	label.SetTextContent(fmt.Sprintf("%s (%s)", snap.Case.Name, time.Unix(0, snap.Time).Format("Jan 2 3:04 PM")))
+	restore := dyndom.CreateElement("button", "uk-button", "uk-button-primary", "uk-button-small")
+	restore.SetTextContent("Restore")
+	restore.AddEventListener("click", func(e dom.Event) {
+		item.Style().Set("display", "none")
+		go restoreSnapshot(snap)
+	})
+	item.AppendChild(label)
+	item.AppendChild(restore)
+	return item
+}
+
+// restoreSnapshot opens the snapshot as a new case so nothing already open is overwritten
+// The snapshots of the case it came from are deleted, since the recovered case has its own and they would be offered again after every crash
+func restoreSnapshot(snap *snapshot) {
+	recovered := snap.Case
+	recovered.ID = newUUID()
+	recovered.Name = fmt.Sprintf("%s (recovered)", snap.Case.Name)
+	cs := recovered.Normalize()
+	err := cs.Add()
+	if err != nil {
+		log.PanicMessage("Failed to add the recovered case to the screen", err)
+	}
+	err = cs.SetActive()
+	if err != nil {
+		log.PanicMessage("Failed to set the recovered case as the current case", err)
+	}
+	err = deleteSnapshots(snap.Case.ID)
+	if err != nil {
+		log.WarnMessage("Failed to remove the snapshots of %s: %s", snap.Case.Name, err.Error())
+	}
+}
@@ -0,0 +1,322 @@
+package document
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"syscall/js"
+
+	"github.com/dennwc/dom"
+	"github.com/pkg/errors"
+
+	"gitlab.com/256/DebateFrame/client/config"
+	"gitlab.com/256/DebateFrame/client/importer"
+	"gitlab.com/256/DebateFrame/client/log"
+	"gitlab.com/256/DebateFrame/client/tub"
+	"gitlab.com/256/WebFrame/dyndom"
+)
+
+var tubBound = false
+
+// OnTub is the event listener for when the Team Tub button is pressed
+func OnTub(e dom.Event) {
+	if !tubBound {
+		dom.GetDocument().GetElementById("tubRefresh").AddEventListener("click", func(e dom.Event) {
+			go listTub()
+		})
+		dom.GetDocument().GetElementById("tubUpload").AddEventListener("click", func(e dom.Event) {
+			go uploadCase()
+		})
+		dom.GetDocument().GetElementById("tubLoginButton").AddEventListener("click", func(e dom.Event) {
+			go loginTub()
+		})
+		dom.GetDocument().GetElementById("tubLogout").AddEventListener("click", func(e dom.Event) {
+			go logoutTub()
+		})
+		tubBound = true
+	}
+	setInputValue("tubServer", config.CurrentConfig.ServerURL)
+	showLogin(false)
+	showModal("modal-tub")
+	go listTub()
+}
+
+// showLogin shows the login form, or who is logged in if the form isn't needed
+func showLogin(needed bool) {
+	login := dom.GetDocument().GetElementById("tubLogin")
+	account := dom.GetDocument().GetElementById("tubAccount")
+	if needed {
+		login.ClassList().Remove("simplehide")
+		account.ClassList().Add("simplehide")
+		return
+	}
+	login.ClassList().Add("simplehide")
+	if config.CurrentConfig.ServerUser == "" {
+		account.ClassList().Add("simplehide")
+		return
+	}
+	account.ClassList().Remove("simplehide")
+	dom.GetDocument().GetElementById("tubUser").SetTextContent(config.CurrentConfig.ServerUser)
+}
+
+// loginTub logs in with the name and password in the tub dialog
+func loginTub() {
+	client, err := tubClient()
+	if err != nil {
+		notify(err.Error(), "warning")
+		return
+	}
+	name := strings.TrimSpace(inputValue("tubName"))
+	err = client.Login(name, inputValue("tubPassword"))
+	setInputValue("tubPassword", "")
+	if err != nil {
+		notify(err.Error(), "danger")
+		return
+	}
+	config.CurrentConfig.ServerUser = name
+	config.CurrentConfig.ServerToken = client.Token
+	showLogin(false)
+	listTub()
+}
+
+// logoutTub ends the session with the server
+func logoutTub() {
+	client, err := tubClient()
+	if err == nil {
+		client.Logout()
+	}
+	config.CurrentConfig.ServerUser = ""
+	config.CurrentConfig.ServerToken = ""
+	dom.GetDocument().GetElementById("tubList").SetInnerHTML("")
+	showLogin(true)
+}
+
+// tubFailed shows why a request to the tub failed, asking the user to log in if that is the reason
+func tubFailed(err error) {
+	if errors.Cause(err) == tub.ErrLogin {
+		config.CurrentConfig.ServerUser = ""
+		config.CurrentConfig.ServerToken = ""
+		showLogin(true)
+		return
+	}
+	notify(err.Error(), "danger")
+}
+
+// tubClient returns a client for the server in the tub dialog, remembering it for next time
+func tubClient() (*tub.Client, error) {
+	server := strings.TrimSpace(inputValue("tubServer"))
+	if !strings.HasPrefix(server, "http://") && !strings.HasPrefix(server, "https://") {
+		return nil, fmt.Errorf("the server address must start with http:// or https://")
+	}
+	if server != config.CurrentConfig.ServerURL {
+		// A login only works on the server it was made on
+		config.CurrentConfig.ServerUser = ""
+		config.CurrentConfig.ServerToken = ""
+	}
+	config.CurrentConfig.ServerURL = server
+	return &tub.Client{Server: server, Token: config.CurrentConfig.ServerToken}, nil
+}
+
+// listTub shows every case in the tub
+func listTub() {
+	client, err := tubClient()
+	if err != nil {
+		notify(err.Error(), "warning")
+		return
+	}
+	list := dom.GetDocument().GetElementById("tubList")
+	list.SetInnerHTML("")
+	metas, err := client.List()
+	if err != nil {
+		tubFailed(err)
+		return
+	}
+	if len(metas) == 0 {
+		empty := dyndom.CreateElement("td")
+		empty.SetAttribute("colspan", "4")
+		empty.SetTextContent("The tub is empty. Upload a case to share it with the team.")
+		row := dyndom.CreateElement("tr")
+		row.AppendChild(empty)
+		list.AppendChild(row)
+		return
+	}
+	for _, meta := range metas {
+		list.AppendChild(tubRow(client, meta))
+	}
+}
+
+func tubRow(client *tub.Client, meta tub.Meta) *dyndom.Element {
+	row := dyndom.CreateElement("tr")
+	name := dyndom.CreateElement("td")
+	name.SetTextContent(meta.Name)
+	row.AppendChild(name)
+	uploader := dyndom.CreateElement("td")
+	uploader.SetTextContent(meta.Uploader)
+	row.AppendChild(uploader)
+	updated := dyndom.CreateElement("td")
+	updated.SetTextContent(meta.Updated.Local().Format("Jan 2 15:04"))
+	row.AppendChild(updated)
+
+	actions := dyndom.CreateElement("td", "uk-text-right")
+	open := dyndom.CreateElement("a", "uk-icon-link")
+	open.SetAttribute("href", "#")
+	open.SetAttribute("uk-icon", "icon: folder")
+	open.SetAttribute("title", "Open")
+	open.AddEventListener("click", func(e dom.Event) {
+		go openTubCase(client, meta)
+	})
+	actions.AppendChild(open)
+	if meta.Role != "owner" {
+		row.AppendChild(actions)
+		return row
+	}
+	share := dyndom.CreateElement("a", "uk-icon-link")
+	share.SetAttribute("href", "#")
+	share.SetAttribute("uk-icon", "icon: users")
+	share.SetAttribute("title", "Choose who can access it")
+	share.AddEventListener("click", func(e dom.Event) {
+		go shareTubCase(client, meta)
+	})
+	actions.AppendChild(share)
+	remove := dyndom.CreateElement("a", "uk-icon-link")
+	remove.SetAttribute("href", "#")
+	remove.SetAttribute("uk-icon", "icon: trash")
+	remove.SetAttribute("title", "Delete from the tub")
+	remove.AddEventListener("click", func(e dom.Event) {
+		go deleteTubCase(client, meta)
+	})
+	actions.AppendChild(remove)
+	row.AppendChild(actions)
+	return row
+}
+
+// uploadCase saves the current case and puts it in the tub
+func uploadCase() {
+	if currentCase == nil {
+		notify("Open a case to upload first", "warning")
+		return
+	}
+	client, err := tubClient()
+	if err != nil {
+		notify(err.Error(), "warning")
+		return
+	}
+	err = currentCase.syncDocument()
+	if err != nil {
+		log.WarnMessage("Failed to read the case from the editor: %s", err.Error())
+		return
+	}
+	err = currentCase.addRevision()
+	if err != nil {
+		log.WarnMessage("Failed to add a revision to the case: %s", err.Error())
+		return
+	}
+	bytes, err := encodeCase(currentCase.Saveable())
+	if err != nil {
+		log.WarnMessage("Failed to convert the case for uploading: %s", err.Error())
+		return
+	}
+	err = client.Upload(currentCase.ID, currentCase.Name, config.CurrentConfig.AuthorName, bytes)
+	if err != nil {
+		tubFailed(err)
+		return
+	}
+	notify(fmt.Sprintf("Uploaded %s to the tub", currentCase.Name), "success")
+	listTub()
+}
+
+// openTubCase downloads a case from the tub and opens it
+// A case that is already open is opened as a copy so the two don't overwrite each other
+func openTubCase(client *tub.Client, meta tub.Meta) {
+	bytes, err := client.Download(meta.ID)
+	if err != nil {
+		tubFailed(err)
+		return
+	}
+	scase, err := decodeCase(bytes)
+	if err != nil {
+		notify(fmt.Sprintf("Failed to read %s: %s", meta.Name, err.Error()), "danger")
+		return
+	}
+	// Anyone on the team can upload a case, so it could hold scripts that would run when it's shown
+	scase.Document, err = importer.Sanitize(scase.Document)
+	if err != nil {
+		notify(fmt.Sprintf("Failed to read %s: %s", meta.Name, err.Error()), "danger")
+		return
+	}
+	if openCase(scase.ID) != nil {
+		scase.ID = newUUID()
+		scase.Name = fmt.Sprintf("%s (tub)", scase.Name)
+	}
+	cs := scase.Normalize()
+	err = cs.Add()
+	if err != nil {
+		log.WarnMessage("Failed to add the case from the tub: %s", err.Error())
+		return
+	}
+	cs.SetActive()
+	hideModal("modal-tub")
+}
+
+// deleteTubCase removes a case from the tub after asking
+func deleteTubCase(client *tub.Client, meta tub.Meta) {
+	confirmed := dom.GetWindow().JSValue().Call("confirm", fmt.Sprintf("Delete %s from the tub for everyone?", meta.Name)).Bool()
+	if !confirmed {
+		return
+	}
+	err := client.Delete(meta.ID)
+	if err != nil {
+		tubFailed(err)
+		return
+	}
+	listTub()
+}
+
+// shareTubCase asks the owner of a case who else can access it
+// The access list is written like "team:varsity=editor, user:coach=owner"
+func shareTubCase(client *tub.Client, meta tub.Meta) {
+	entries := []string{}
+	for who, role := range meta.ACL {
+		entries = append(entries, fmt.Sprintf("%s=%s", who, role))
+	}
+	sort.Strings(entries)
+	prompt := fmt.Sprintf("Who can access %s? Write entries like team:varsity=reader or user:coach=editor, separated by commas", meta.Name)
+	answer := dom.GetWindow().JSValue().Call("prompt", prompt, strings.Join(entries, ", "))
+	if answer == js.Null() {
+		return
+	}
+	acl, err := parseACL(answer.String())
+	if err != nil {
+		notify(err.Error(), "warning")
+		return
+	}
+	err = client.SetACL(meta.ID, acl)
+	if err != nil {
+		tubFailed(err)
+		return
+	}
+	notify(fmt.Sprintf("Changed who can access %s", meta.Name), "success")
+	listTub()
+}
+
+// parseACL reads an access list written like "team:varsity=editor, user:coach=owner"
+func parseACL(text string) (map[string]string, error) {
+	acl := make(map[string]string)
+	for _, entry := range strings.Split(text, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		parts := strings.SplitN(entry, "=", 2)
+		who := strings.TrimSpace(parts[0])
+		if len(parts) != 2 || (!strings.HasPrefix(who, "user:") && !strings.HasPrefix(who, "team:")) {
+			return nil, fmt.Errorf("%q should look like team:varsity=reader or user:coach=editor", entry)
+		}
+		role := strings.ToLower(strings.TrimSpace(parts[1]))
+		if role != "reader" && role != "editor" && role != "owner" {
+			return nil, fmt.Errorf("%q is not a role, use reader, editor or owner", parts[1])
+		}
+		acl[who] = role
+	}
+	return acl, nil
+}
@@ -0,0 +1,26 @@
+package document
+
+import (
+	"syscall/js"
+
+	"github.com/dennwc/dom"
+)
+
+// notify shows a UIkit notification with the given status (primary, success, warning or danger)
+func notify(message string, status string) {
+	options := make(map[string]interface{})
+	options["message"] = message
+	options["status"] = status
+	options["pos"] = "bottom-right"
+	js.Global().Get("UIkit").Call("notification", options)
+}
+
+// showModal opens the UIkit modal with the given id
+func showModal(id string) {
+	js.Global().Get("UIkit").Call("modal", dom.GetDocument().GetElementById(id).JSValue()).Call("show")
+}
+
+// hideModal closes the UIkit modal with the given id
+func hideModal(id string) {
+	js.Global().Get("UIkit").Call("modal", dom.GetDocument().GetElementById(id).JSValue()).Call("hide")
+}
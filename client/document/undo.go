@@ -0,0 +1,173 @@
+package document
+
+import (
+	"fmt"
+	"syscall/js"
+
+	"github.com/pkg/errors"
+
+	"gitlab.com/256/DebateFrame/client/history"
+	"gitlab.com/256/DebateFrame/client/log"
+	"gitlab.com/256/DebateFrame/client/waiter"
+)
+
+// historyDelay is how many milliseconds typing has to stop for before it becomes an undo step
+const historyDelay = 700
+
+// speechCase is the case that cards are sent to
+var speechCase *Case
+
+// startHistory starts recording edits in the editor so they can be undone
+func (cs *Case) startHistory() error {
+	stampHeadings(cs.EditorElem.JSValue())
+	blocks, err := blocksOfHTML(cs.Editor.GetContent(0))
+	if err != nil {
+		return err
+	}
+	cs.blocks = blocks
+	cs.stops = append(cs.stops, waiter.EventWaiter(&cs.EditorElem.NodeBase, "input", historyDelay, func() {
+		cs.recordEdit()
+	}))
+	// Stopping the browser's own undo is done by historyKeys in JS, since Go only sees the keydown after it has been handled
+	undo := js.NewCallback(func(args []js.Value) {
+		go cs.Undo()
+	})
+	redo := js.NewCallback(func(args []js.Value) {
+		go cs.Redo()
+	})
+	js.Global().Call("historyKeys", cs.EditorElem.JSValue(), undo, redo)
+	cs.stops = append(cs.stops, undo.Release, redo.Release)
+	return nil
+}
+
+// recordEdit adds whatever was typed since the last recorded edit to the history
+func (cs *Case) recordEdit() {
+	stampHeadings(cs.EditorElem.JSValue())
+	blocks, err := blocksOfHTML(cs.Editor.GetContent(0))
+	if err != nil {
+		log.WarnMessage("Failed to read case %s from the editor: %s", cs.Name, err.Error())
+		return
+	}
+	splice, changed := history.Diff(cs.blocks, blocks)
+	if !changed {
+		return
+	}
+	cs.History.Push(history.Op{Label: "Typing", Splices: []history.Splice{splice}})
+	cs.blocks = blocks
+}
+
+// Undo reverts the last change to the case
+func (cs *Case) Undo() {
+	cs.recordEdit()
+	blocks, err := cs.History.Undo(cs.blocks)
+	cs.afterHistory(blocks, err)
+}
+
+// Redo applies the last undone change to the case again
+func (cs *Case) Redo() {
+	cs.recordEdit()
+	blocks, err := cs.History.Redo(cs.blocks)
+	cs.afterHistory(blocks, err)
+}
+
+func (cs *Case) afterHistory(blocks []string, err error) {
+	if err != nil {
+		// The history no longer matches the document, so it can't be trusted
+		log.WarnMessage("Clearing the history of %s: %s", cs.Name, err.Error())
+		cs.History = &history.Stack{}
+		notify("The undo history didn't match the document and was cleared", "warning")
+		return
+	}
+	cs.setBlocks(blocks)
+}
+
+// apply runs a command on the case and records it in the history
+func (cs *Case) apply(op history.Op) error {
+	cs.recordEdit()
+	op = stampOp(cs.blocks, op)
+	blocks, err := op.Apply(cs.blocks)
+	if err != nil {
+		return errors.Wrapf(err, "failed to %s", op.Label)
+	}
+	cs.History.Push(op)
+	cs.setBlocks(blocks)
+	return nil
+}
+
+// setBlocks replaces the contents of the case, updating everything shown from it
+func (cs *Case) setBlocks(blocks []string) {
+	cs.blocks = blocks
+	cs.Editor.SetContent(joinBlocks(blocks), 0)
+	cs.renderNav()
+	cs.sendCollab()
+	persistCase(cs)
+	refreshCards(cs)
+}
+
+// refreshCards finds the cards of the case again and shows them in the card view
+// The blocks of the case must already be the ones shown
+func refreshCards(cs *Case) {
+	cs.Cards = fromSectionCards(cs.parsed.Cards(cs.blocks, parseCards))
+	renderCards(cs)
+}
+
+// cardSection returns the range of blocks that make up the card
+func (cs *Case) cardSection(icard *InfoCard) (int, int, error) {
+	start, top := findHeading(cs.blocks, icard.Level, icard.Heading)
+	if start == -1 {
+		return 0, 0, fmt.Errorf("could not find the card \"%s\" in the document", icard.Title)
+	}
+	if !top {
+		return 0, 0, fmt.Errorf("the card \"%s\" is inside another element, so it can't be changed on its own", icard.Title)
+	}
+	return start, sectionEnd(cs.blocks, start), nil
+}
+
+// DeleteCard removes the card from the case
+func (cs *Case) DeleteCard(icard *InfoCard) error {
+	cs.recordEdit()
+	start, end, err := cs.cardSection(icard)
+	if err != nil {
+		return err
+	}
+	removed := append([]string{}, cs.blocks[start:end]...)
+	return cs.apply(history.Op{
+		Label:   "Delete card",
+		Splices: []history.Splice{{Index: start, Removed: removed}},
+	})
+}
+
+// MoveSection moves the heading at the block index from, along with everything under it, to before the block index to
+func (cs *Case) MoveSection(from int, to int) error {
+	cs.recordEdit()
+	if from < 0 || from >= len(cs.blocks) {
+		return fmt.Errorf("there is no block %v to move", from)
+	}
+	op, err := history.Move(cs.blocks, from, sectionEnd(cs.blocks, from)-from, to)
+	if err != nil {
+		return err
+	}
+	op.Label = "Move block"
+	return cs.apply(op)
+}
+
+// SendCard copies the card to the end of the target case, which is recorded in the history of the target
+func (cs *Case) SendCard(icard *InfoCard, target *Case) error {
+	if target == nil {
+		return errors.New("pick a speech doc first with the microphone button")
+	}
+	if target == cs {
+		return errors.New("this case is the speech doc")
+	}
+	cs.recordEdit()
+	start, end, err := cs.cardSection(icard)
+	if err != nil {
+		return err
+	}
+	target.recordEdit()
+	sent := append([]string{}, cs.blocks[start:end]...)
+	return target.apply(history.Op{
+		Label:   "Send to speech doc",
+		Splices: []history.Splice{{Index: len(target.blocks), Inserted: sent}},
+	})
+}
@@ -0,0 +1,334 @@
+package grabber
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"regexp"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"golang.org/x/net/html"
+)
+
+// Article is what could be found out about a saved web page
+// Fields that couldn't be found are empty
+type Article struct {
+	Title    string
+	Authors  []string
+	Date     string // As the page gives it, or YYYY-MM-DD if the page gives a full timestamp
+	SiteName string
+	URL      string   // The canonical address of the page
+	Body     []string // The paragraphs of the article, without menus, ads and comments
+}
+
+// Extract reads the metadata and article text of a saved web page
+// Metadata is taken from citation_* tags first, which academic sites use, then OpenGraph, then JSON-LD, then the page itself
+func Extract(page string) (*Article, error) {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(page))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the page: %s", err.Error())
+	}
+	ld := linkedData(doc)
+	art := &Article{}
+
+	art.Title = first(
+		meta(doc, "citation_title"),
+		meta(doc, "og:title"),
+		ldString(ld, "headline"),
+		ldString(ld, "name"),
+		pageTitle(doc),
+	)
+	art.Authors = metas(doc, "citation_author")
+	if len(art.Authors) == 0 {
+		art.Authors = ldAuthors(ld)
+	}
+	if len(art.Authors) == 0 {
+		if author := first(meta(doc, "author"), meta(doc, "dc.creator"), notURL(meta(doc, "article:author")), byline(doc)); author != "" {
+			art.Authors = []string{author}
+		}
+	}
+	art.Date = cleanDate(first(
+		meta(doc, "citation_publication_date"),
+		meta(doc, "citation_date"),
+		meta(doc, "article:published_time"),
+		ldString(ld, "datePublished"),
+		meta(doc, "date"),
+		meta(doc, "dc.date"),
+		attr(doc.Find("time[datetime]").First(), "datetime"),
+	))
+	art.SiteName = first(
+		meta(doc, "og:site_name"),
+		meta(doc, "citation_journal_title"),
+		meta(doc, "citation_publisher"),
+		ldPublisher(ld),
+	)
+	art.URL = first(
+		attr(doc.Find("link[rel=canonical]").First(), "href"),
+		meta(doc, "og:url"),
+		meta(doc, "citation_public_url"),
+		ldString(ld, "url"),
+	)
+	art.Body = mainText(doc)
+	return art, nil
+}
+
+// first returns the first value that isn't empty
+func first(values ...string) string {
+	for _, value := range values {
+		if value = strings.TrimSpace(value); value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+func attr(sel *goquery.Selection, name string) string {
+	value, _ := sel.Attr(name)
+	return strings.TrimSpace(value)
+}
+
+// metas returns the content of every meta tag with the name or property, ignoring case
+func metas(doc *goquery.Document, name string) []string {
+	values := []string{}
+	doc.Find("meta").Each(func(_ int, sel *goquery.Selection) {
+		if strings.EqualFold(attr(sel, "name"), name) || strings.EqualFold(attr(sel, "property"), name) {
+			if content := attr(sel, "content"); content != "" {
+				values = append(values, content)
+			}
+		}
+	})
+	return values
+}
+
+func meta(doc *goquery.Document, name string) string {
+	values := metas(doc, name)
+	if len(values) == 0 {
+		return ""
+	}
+	return values[0]
+}
+
+func notURL(value string) string {
+	if strings.HasPrefix(value, "http://") || strings.HasPrefix(value, "https://") {
+		return ""
+	}
+	return value
+}
+
+var titleSuffixRp = regexp.MustCompile(`\s+[|\-–—]\s+[^|\-–—]+$`)
+
+// pageTitle returns the title of the page without the site name that is often added to the end
+func pageTitle(doc *goquery.Document) string {
+	title := strings.TrimSpace(doc.Find("title").First().Text())
+	if trimmed := titleSuffixRp.ReplaceAllString(title, ""); trimmed != "" {
+		title = trimmed
+	}
+	return first(title, doc.Find("h1").First().Text())
+}
+
+var bylinePrefixRp = regexp.MustCompile(`(?i)^\s*(by|written by|posted by)\s+`)
+
+// byline looks for the author in the markup sites usually use for bylines
+func byline(doc *goquery.Document) string {
+	for _, query := range []string{"[rel=author]", "[itemprop=author]", ".byline", ".author", "#byline"} {
+		text := strings.Join(strings.Fields(doc.Find(query).First().Text()), " ")
+		text = bylinePrefixRp.ReplaceAllString(text, "")
+		// Bylines longer than this are usually author bios
+		if text != "" && len(text) < 80 {
+			return text
+		}
+	}
+	return ""
+}
+
+var isoDateRp = regexp.MustCompile(`^(\d{4}-\d{2}-\d{2})T`)
+
+func cleanDate(date string) string {
+	if match := isoDateRp.FindStringSubmatch(date); match != nil {
+		return match[1]
+	}
+	return date
+}
+
+// JSON-LD
+
+// articleTypes are the schema.org types that describe the article itself rather than the site
+var articleTypes = []string{"Article", "NewsArticle", "BlogPosting", "ScholarlyArticle", "Report", "OpinionNewsArticle", "ReportageNewsArticle"}
+
+// linkedData returns the JSON-LD object describing the article, or nil if the page doesn't have one
+func linkedData(doc *goquery.Document) map[string]interface{} {
+	var found map[string]interface{}
+	doc.Find(`script[type="application/ld+json"]`).EachWithBreak(func(_ int, sel *goquery.Selection) bool {
+		var raw interface{}
+		if json.Unmarshal([]byte(sel.Text()), &raw) != nil {
+			return true
+		}
+		for _, obj := range ldObjects(raw) {
+			if ldIsArticle(obj) {
+				found = obj
+				return false
+			}
+		}
+		return true
+	})
+	return found
+}
+
+// ldObjects flattens arrays and @graph lists into the objects they hold
+func ldObjects(raw interface{}) []map[string]interface{} {
+	objects := []map[string]interface{}{}
+	switch value := raw.(type) {
+	case []interface{}:
+		for _, item := range value {
+			objects = append(objects, ldObjects(item)...)
+		}
+	case map[string]interface{}:
+		objects = append(objects, value)
+		if graph, ok := value["@graph"]; ok {
+			objects = append(objects, ldObjects(graph)...)
+		}
+	}
+	return objects
+}
+
+func ldIsArticle(obj map[string]interface{}) bool {
+	types := []string{}
+	switch value := obj["@type"].(type) {
+	case string:
+		types = append(types, value)
+	case []interface{}:
+		for _, item := range value {
+			if str, ok := item.(string); ok {
+				types = append(types, str)
+			}
+		}
+	}
+	for _, typ := range types {
+		for _, articleType := range articleTypes {
+			if typ == articleType {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func ldString(obj map[string]interface{}, key string) string {
+	if obj == nil {
+		return ""
+	}
+	str, _ := obj[key].(string)
+	return str
+}
+
+// ldName returns the name of a person or organization, which can be a plain string or an object with a name
+func ldName(value interface{}) string {
+	switch value := value.(type) {
+	case string:
+		return value
+	case map[string]interface{}:
+		name, _ := value["name"].(string)
+		return name
+	}
+	return ""
+}
+
+func ldAuthors(obj map[string]interface{}) []string {
+	if obj == nil {
+		return nil
+	}
+	authors := []string{}
+	list, ok := obj["author"].([]interface{})
+	if !ok {
+		list = []interface{}{obj["author"]}
+	}
+	for _, author := range list {
+		if name := strings.TrimSpace(ldName(author)); name != "" {
+			authors = append(authors, name)
+		}
+	}
+	return authors
+}
+
+func ldPublisher(obj map[string]interface{}) string {
+	if obj == nil {
+		return ""
+	}
+	return ldName(obj["publisher"])
+}
+
+// Boilerplate removal
+
+var (
+	// unlikelyRp matches classes and IDs of things around an article rather than in it
+	unlikelyRp = regexp.MustCompile(`(?i)comment|sidebar|footer|header|menu|nav|share|social|promo|related|advert|sponsor|subscribe|newsletter|cookie|popup|modal|banner|breadcrumb`)
+	// likelyRp matches classes and IDs of the article itself
+	likelyRp = regexp.MustCompile(`(?i)article|body|content|entry|main|post|story|text`)
+)
+
+// minParagraph is the shortest text that counts as a paragraph of the article
+const minParagraph = 25
+
+// mainText finds the element holding the most article-like paragraphs and returns its paragraphs
+// Paragraphs score their parent by length and commas, and half of that goes to the grandparent, like readability does
+func mainText(doc *goquery.Document) []string {
+	body := doc.Find("body")
+	body.Find("script, style, noscript, nav, header, footer, aside, form, iframe, button").Remove()
+	body.Find("*").Each(func(_ int, sel *goquery.Selection) {
+		id := attr(sel, "class") + " " + attr(sel, "id")
+		if unlikelyRp.MatchString(id) && !likelyRp.MatchString(id) && goquery.NodeName(sel) != "body" {
+			sel.Remove()
+		}
+	})
+
+	scores := make(map[*html.Node]float64)
+	body.Find("p").Each(func(_ int, sel *goquery.Selection) {
+		text := strings.TrimSpace(sel.Text())
+		if len(text) < minParagraph {
+			return
+		}
+		score := 1 + float64(strings.Count(text, ",")) + math.Min(float64(len(text))/100, 3)
+		parent := sel.Parent()
+		if parent.Length() == 0 {
+			return
+		}
+		scores[parent.Nodes[0]] += score
+		if grand := parent.Parent(); grand.Length() > 0 {
+			scores[grand.Nodes[0]] += score / 2
+		}
+	})
+
+	var best *html.Node
+	bestScore := 0.0
+	for node, score := range scores {
+		sel := goquery.NewDocumentFromNode(node).Selection
+		id := attr(sel, "class") + " " + attr(sel, "id")
+		if likelyRp.MatchString(id) || node.Data == "article" {
+			score += 25
+		}
+		if score > bestScore {
+			best, bestScore = node, score
+		}
+	}
+	if best == nil {
+		return paragraphs(body)
+	}
+	return paragraphs(goquery.NewDocumentFromNode(best).Selection)
+}
+
+// paragraphs returns the text of each paragraph-like element in the selection
+func paragraphs(sel *goquery.Selection) []string {
+	texts := []string{}
+	sel.Find("p, h2, h3, li, blockquote, pre").Each(func(_ int, para *goquery.Selection) {
+		// Paragraphs inside list items or quotes are already part of their text
+		if para.ParentsFiltered("li, blockquote").Length() > 0 {
+			return
+		}
+		text := strings.Join(strings.Fields(para.Text()), " ")
+		if text != "" {
+			texts = append(texts, text)
+		}
+	})
+	return texts
+}
@@ -0,0 +1,220 @@
+package history
+
+import (
+	"fmt"
+)
+
+// Limit is the most operations that are kept in each direction
+const Limit = 200
+
+// Splice replaces the Removed blocks starting at Index with the Inserted blocks
+// A document is treated as a list of blocks, which are the HTML strings of the top level elements in its body
+type Splice struct {
+	Index    int
+	Removed  []string
+	Inserted []string
+}
+
+// Op is a single undoable command, such as a burst of typing or moving a block
+type Op struct {
+	Label   string   // What the user would call the command, like "Delete card"
+	Splices []Splice // Applied in order, each to the result of the last
+}
+
+// Stack holds the operations that can be undone and redone for one document
+type Stack struct {
+	Done   []Op // Operations that can be undone, most recent last
+	Undone []Op // Operations that can be redone, most recent last
+}
+
+// Apply applies the splice to the blocks, checking that the removed blocks are the ones expected
+func (sp Splice) Apply(blocks []string) ([]string, error) {
+	if sp.Index < 0 || sp.Index+len(sp.Removed) > len(blocks) {
+		return nil, fmt.Errorf("splice at %v removing %v blocks is outside of the %v block document", sp.Index, len(sp.Removed), len(blocks))
+	}
+	for i, removed := range sp.Removed {
+		if blocks[sp.Index+i] != removed {
+			return nil, fmt.Errorf("block %v doesn't match the block being removed", sp.Index+i)
+		}
+	}
+	result := make([]string, 0, len(blocks)-len(sp.Removed)+len(sp.Inserted))
+	result = append(result, blocks[:sp.Index]...)
+	result = append(result, sp.Inserted...)
+	result = append(result, blocks[sp.Index+len(sp.Removed):]...)
+	return result, nil
+}
+
+// Invert returns the splice that undoes this one
+func (sp Splice) Invert() Splice {
+	return Splice{Index: sp.Index, Removed: sp.Inserted, Inserted: sp.Removed}
+}
+
+// Apply applies every splice of the operation to the blocks
+func (op Op) Apply(blocks []string) ([]string, error) {
+	var err error
+	for _, sp := range op.Splices {
+		blocks, err = sp.Apply(blocks)
+		if err != nil {
+			return nil, fmt.Errorf("failed to apply %s: %s", op.Label, err.Error())
+		}
+	}
+	return blocks, nil
+}
+
+// Invert returns the operation that undoes this one
+func (op Op) Invert() Op {
+	inverse := Op{Label: op.Label}
+	for i := len(op.Splices) - 1; i >= 0; i-- {
+		inverse.Splices = append(inverse.Splices, op.Splices[i].Invert())
+	}
+	return inverse
+}
+
+// Diff returns the smallest single splice that turns before into after, and false if they are the same
+func Diff(before []string, after []string) (Splice, bool) {
+	prefix := 0
+	for prefix < len(before) && prefix < len(after) && before[prefix] == after[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(before)-prefix && suffix < len(after)-prefix &&
+		before[len(before)-1-suffix] == after[len(after)-1-suffix] {
+		suffix++
+	}
+	if prefix == len(before) && prefix == len(after) {
+		return Splice{}, false
+	}
+	return Splice{
+		Index:    prefix,
+		Removed:  copyBlocks(before[prefix : len(before)-suffix]),
+		Inserted: copyBlocks(after[prefix : len(after)-suffix]),
+	}, true
+}
+
+// Move returns the operation that moves count blocks starting at from so that they come right before the block at to
+// to is an index into the blocks before the move happens, and may be len(blocks) to move to the end
+func Move(blocks []string, from int, count int, to int) (Op, error) {
+	if from < 0 || count < 1 || from+count > len(blocks) || to < 0 || to > len(blocks) {
+		return Op{}, fmt.Errorf("can't move %v blocks from %v to %v in a %v block document", count, from, to, len(blocks))
+	}
+	if to >= from && to <= from+count {
+		return Op{}, fmt.Errorf("can't move blocks into themselves")
+	}
+	moved := copyBlocks(blocks[from : from+count])
+	insertAt := to
+	if to > from {
+		insertAt -= count
+	}
+	return Op{
+		Label: "Move",
+		Splices: []Splice{
+			{Index: from, Removed: moved},
+			{Index: insertAt, Inserted: moved},
+		},
+	}, nil
+}
+
+// Push records an operation that was just applied, which makes everything undone so far impossible to redo
+func (st *Stack) Push(op Op) {
+	st.Done = append(st.Done, op)
+	if len(st.Done) > Limit {
+		st.Done = st.Done[len(st.Done)-Limit:]
+	}
+	st.Undone = nil
+}
+
+// CanUndo returns true if there is an operation to undo
+func (st *Stack) CanUndo() bool {
+	return len(st.Done) > 0
+}
+
+// CanRedo returns true if there is an operation to redo
+func (st *Stack) CanRedo() bool {
+	return len(st.Undone) > 0
+}
+
+// Undo reverts the last operation on the blocks
+func (st *Stack) Undo(blocks []string) ([]string, error) {
+	if !st.CanUndo() {
+		return blocks, nil
+	}
+	op := st.Done[len(st.Done)-1]
+	result, err := op.Invert().Apply(blocks)
+	if err != nil {
+		return nil, err
+	}
+	st.Done = st.Done[:len(st.Done)-1]
+	st.Undone = append(st.Undone, op)
+	return result, nil
+}
+
+// Redo applies the last undone operation to the blocks again
+func (st *Stack) Redo(blocks []string) ([]string, error) {
+	if !st.CanRedo() {
+		return blocks, nil
+	}
+	op := st.Undone[len(st.Undone)-1]
+	result, err := op.Apply(blocks)
+	if err != nil {
+		return nil, err
+	}
+	st.Undone = st.Undone[:len(st.Undone)-1]
+	st.Done = append(st.Done, op)
+	return result, nil
+}
+
+func copyBlocks(blocks []string) []string {
+	return append([]string{}, blocks...)
+}
+
+// Transform adjusts the splice s so it can be applied after over, when both were made on the same blocks
+// If both change the same blocks, neither change is lost: the blocks s removed are removed, and what over inserted is kept
+// before what s inserted, so the result holds both versions. It returns true when that happened, so the user can pick one
+func Transform(s Splice, over Splice) (Splice, bool) {
+	sStart, sEnd := s.Index, s.Index+len(s.Removed)
+	oStart, oEnd := over.Index, over.Index+len(over.Removed)
+	overlaps := (sStart < oEnd && oStart < sEnd) ||
+		(sStart == sEnd && oStart < sStart && sStart < oEnd) ||
+		(oStart == oEnd && sStart < oStart && oStart < sEnd)
+
+	switch {
+	case !overlaps && sEnd <= oStart:
+		return s, false
+	case !overlaps:
+		shifted := s
+		shifted.Index += len(over.Inserted) - len(over.Removed)
+		return shifted, false
+	}
+
+	// The blocks now in the overlapping range are whatever s removed around over, and what over inserted
+	removed := []string{}
+	if sStart < oStart {
+		removed = append(removed, s.Removed[:oStart-sStart]...)
+	}
+	removed = append(removed, over.Inserted...)
+	if sEnd > oEnd {
+		removed = append(removed, s.Removed[oEnd-sStart:]...)
+	}
+	start := sStart
+	if oStart < start {
+		start = oStart
+	}
+	if sameBlocks(s.Inserted, over.Inserted) {
+		// Both made the same change, so only the blocks s removed outside of over are left to remove
+		return Splice{Index: start, Removed: removed, Inserted: copyBlocks(over.Inserted)}, false
+	}
+	inserted := append(copyBlocks(over.Inserted), s.Inserted...)
+	return Splice{Index: start, Removed: removed, Inserted: inserted}, true
+}
+
+func sameBlocks(a []string, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
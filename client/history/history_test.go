@@ -0,0 +1,176 @@
+package history
+
+import (
+	"strings"
+	"testing"
+)
+
+// letters makes blocks out of a string, one block per letter, which keeps the tables readable
+func letters(s string) []string {
+	blocks := []string{}
+	for _, r := range s {
+		blocks = append(blocks, string(r))
+	}
+	return blocks
+}
+
+func TestDiff(t *testing.T) {
+	tests := []struct {
+		before  string
+		after   string
+		splice  Splice
+		changed bool
+	}{
+		{"abc", "abc", Splice{}, false},
+		{"", "", Splice{}, false},
+		{"", "abc", Splice{Index: 0, Removed: letters(""), Inserted: letters("abc")}, true},
+		{"abc", "", Splice{Index: 0, Removed: letters("abc"), Inserted: letters("")}, true},
+		{"abc", "abxc", Splice{Index: 2, Removed: letters(""), Inserted: letters("x")}, true},
+		{"abc", "ac", Splice{Index: 1, Removed: letters("b"), Inserted: letters("")}, true},
+		{"abcd", "axyd", Splice{Index: 1, Removed: letters("bc"), Inserted: letters("xy")}, true},
+		{"abc", "xbc", Splice{Index: 0, Removed: letters("a"), Inserted: letters("x")}, true},
+		{"aaa", "aaaa", Splice{Index: 3, Removed: letters(""), Inserted: letters("a")}, true},
+		{"abab", "ab", Splice{Index: 2, Removed: letters("ab"), Inserted: letters("")}, true},
+	}
+	for _, test := range tests {
+		splice, changed := Diff(letters(test.before), letters(test.after))
+		if changed != test.changed {
+			t.Errorf("Diff(%q, %q) changed = %v, want %v", test.before, test.after, changed, test.changed)
+			continue
+		}
+		if !changed {
+			continue
+		}
+		if splice.Index != test.splice.Index || !sameBlocks(splice.Removed, test.splice.Removed) || !sameBlocks(splice.Inserted, test.splice.Inserted) {
+			t.Errorf("Diff(%q, %q) = %+v, want %+v", test.before, test.after, splice, test.splice)
+		}
+		result, err := splice.Apply(letters(test.before))
+		if err != nil || strings.Join(result, "") != test.after {
+			t.Errorf("Diff(%q, %q) doesn't apply: got %q, %v", test.before, test.after, result, err)
+		}
+	}
+}
+
+func TestTransform(t *testing.T) {
+	tests := []struct {
+		name     string
+		blocks   string
+		s        Splice
+		over     Splice
+		want     string // The blocks after over and then the transformed s
+		conflict bool
+	}{
+		{"s before over", "abcd",
+			Splice{Index: 0, Removed: letters("a"), Inserted: letters("x")},
+			Splice{Index: 2, Removed: letters("c"), Inserted: letters("yz")},
+			"xbyzd", false},
+		{"s after over", "abcd",
+			Splice{Index: 3, Removed: letters("d"), Inserted: letters("x")},
+			Splice{Index: 0, Removed: letters("ab"), Inserted: letters("y")},
+			"ycx", false},
+		{"touching ranges", "abcd",
+			Splice{Index: 2, Removed: letters("cd"), Inserted: letters("x")},
+			Splice{Index: 0, Removed: letters("ab"), Inserted: letters("y")},
+			"yx", false},
+		{"inserts in the same place", "ab",
+			Splice{Index: 1, Inserted: letters("x")},
+			Splice{Index: 1, Inserted: letters("y")},
+			"axyb", false},
+		{"insert inside a change", "abcd",
+			Splice{Index: 2, Inserted: letters("x")},
+			Splice{Index: 1, Removed: letters("bc"), Inserted: letters("y")},
+			"ayxd", true},
+		{"change around an insert", "abcd",
+			Splice{Index: 1, Removed: letters("bc"), Inserted: letters("y")},
+			Splice{Index: 2, Inserted: letters("x")},
+			"axyd", true},
+		{"both changing a block", "abc",
+			Splice{Index: 1, Removed: letters("b"), Inserted: letters("x")},
+			Splice{Index: 1, Removed: letters("b"), Inserted: letters("y")},
+			"ayxc", true},
+		{"both making the same change", "abc",
+			Splice{Index: 1, Removed: letters("b"), Inserted: letters("x")},
+			Splice{Index: 1, Removed: letters("b"), Inserted: letters("x")},
+			"axc", false},
+		{"deleting what the other changed", "abc",
+			Splice{Index: 1, Removed: letters("b")},
+			Splice{Index: 1, Removed: letters("b"), Inserted: letters("y")},
+			"ayc", true},
+		{"both deleting a block", "abc",
+			Splice{Index: 1, Removed: letters("b")},
+			Splice{Index: 1, Removed: letters("b")},
+			"ac", false},
+		{"overlapping changes", "abcde",
+			Splice{Index: 1, Removed: letters("bc"), Inserted: letters("x")},
+			Splice{Index: 2, Removed: letters("cd"), Inserted: letters("y")},
+			"ayxe", true},
+	}
+	for _, test := range tests {
+		afterOver, err := test.over.Apply(letters(test.blocks))
+		if err != nil {
+			t.Fatalf("%s: %s", test.name, err)
+		}
+		moved, conflict := Transform(test.s, test.over)
+		if conflict != test.conflict {
+			t.Errorf("%s: conflict = %v, want %v", test.name, conflict, test.conflict)
+		}
+		result, err := moved.Apply(afterOver)
+		if err != nil {
+			t.Errorf("%s: the transformed splice %+v doesn't apply: %s", test.name, moved, err)
+			continue
+		}
+		if strings.Join(result, "") != test.want {
+			t.Errorf("%s: got %q, want %q", test.name, strings.Join(result, ""), test.want)
+		}
+	}
+}
+
+func TestUndoRedo(t *testing.T) {
+	blocks := letters("abc")
+	st := Stack{}
+	ops := []Op{
+		{Label: "Typing", Splices: []Splice{{Index: 1, Removed: letters("b"), Inserted: letters("x")}}},
+		{Label: "Delete card", Splices: []Splice{{Index: 0, Removed: letters("a")}}},
+	}
+	for _, op := range ops {
+		var err error
+		blocks, err = op.Apply(blocks)
+		if err != nil {
+			t.Fatal(err)
+		}
+		st.Push(op)
+	}
+	steps := []struct {
+		redo bool
+		want string
+	}{
+		{false, "axc"},
+		{false, "abc"},
+		{false, "abc"},
+		{true, "axc"},
+		{true, "xc"},
+		{true, "xc"},
+	}
+	for i, step := range steps {
+		var err error
+		if step.redo {
+			blocks, err = st.Redo(blocks)
+		} else {
+			blocks, err = st.Undo(blocks)
+		}
+		if err != nil {
+			t.Fatalf("step %v: %s", i, err)
+		}
+		if strings.Join(blocks, "") != step.want {
+			t.Errorf("step %v: got %q, want %q", i, strings.Join(blocks, ""), step.want)
+		}
+	}
+	_, err := st.Undo(blocks)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Undoing the typing has to find the typed block where it was left
+	if _, err := st.Undo(letters("zzz")); err == nil {
+		t.Error("undoing on blocks the history doesn't match succeeded")
+	}
+}
@@ -0,0 +1,160 @@
+package idb
+
+import (
+	"fmt"
+	"syscall/js"
+
+	"github.com/pkg/errors"
+)
+
+// ErrQuota is returned when the browser refuses to store more data
+var ErrQuota = errors.New("storage quota exceeded")
+
+// DB is an open IndexedDB database whose object stores hold strings under string keys
+// Every function blocks until IndexedDB responds, so they must not be called directly from a JS callback
+type DB struct {
+	inst js.Value
+}
+
+// Open opens the database with the given name, creating any of the given object stores that don't exist yet
+// The version must be raised whenever a new store is added
+func Open(name string, version int, stores ...string) (*DB, error) {
+	storeSlice := make([]interface{}, len(stores))
+	for i, store := range stores {
+		storeSlice[i] = store
+	}
+	// Object stores can only be created synchronously inside onupgradeneeded, so that part is handled by idbOpen in index.js
+	req := js.Global().Call("idbOpen", name, version, storeSlice)
+	inst, err := wait(req, req)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to open database %s", name)
+	}
+	return &DB{inst: inst}, nil
+}
+
+// Put saves the value under the key in the store, replacing what was there before
+func (db *DB) Put(store string, key string, value string) error {
+	tx := db.transaction(store, "readwrite")
+	req := tx.Call("objectStore", store).Call("put", value, key)
+	_, err := wait(req, tx)
+	if err != nil {
+		return errors.Wrapf(err, "failed to put %s in %s", key, store)
+	}
+	return nil
+}
+
+// Get returns the value saved under the key in the store, and whether it existed
+func (db *DB) Get(store string, key string) (string, bool, error) {
+	tx := db.transaction(store, "readonly")
+	req := tx.Call("objectStore", store).Call("get", key)
+	value, err := wait(req, req)
+	if err != nil {
+		return "", false, errors.Wrapf(err, "failed to get %s from %s", key, store)
+	}
+	if value.Type() != js.TypeString {
+		return "", false, nil
+	}
+	return value.String(), true, nil
+}
+
+// Delete removes the key and its value from the store
+func (db *DB) Delete(store string, key string) error {
+	tx := db.transaction(store, "readwrite")
+	req := tx.Call("objectStore", store).Call("delete", key)
+	_, err := wait(req, tx)
+	if err != nil {
+		return errors.Wrapf(err, "failed to delete %s from %s", key, store)
+	}
+	return nil
+}
+
+// Keys returns every key in the store
+func (db *DB) Keys(store string) ([]string, error) {
+	tx := db.transaction(store, "readonly")
+	req := tx.Call("objectStore", store).Call("getAllKeys")
+	result, err := wait(req, req)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get the keys of %s", store)
+	}
+	keys := []string{}
+	for i := 0; i < result.Length(); i++ {
+		keys = append(keys, result.Index(i).String())
+	}
+	return keys, nil
+}
+
+func (db *DB) transaction(store string, mode string) js.Value {
+	return db.inst.Call("transaction", []interface{}{store}, mode)
+}
+
+// wait blocks until req has a result and target reports success or failure
+// For reads target is the request itself, while writes wait for the whole transaction to be committed
+func wait(req js.Value, target js.Value) (js.Value, error) {
+	done := make(chan error, 1)
+	successEvent := "success"
+	if target != req {
+		successEvent = "complete"
+	}
+	// A request can fail and then abort its transaction, so only the first result is sent and the rest are dropped
+	onSuccess := js.NewCallback(func(args []js.Value) {
+		select {
+		case done <- nil:
+		default:
+		}
+	})
+	onError := js.NewCallback(func(args []js.Value) {
+		select {
+		case done <- domError(args[0].Get("target").Get("error")):
+		default:
+		}
+	})
+	target.Call("addEventListener", successEvent, onSuccess)
+	target.Call("addEventListener", "error", onError)
+	target.Call("addEventListener", "abort", onError)
+	defer func() {
+		target.Call("removeEventListener", successEvent, onSuccess)
+		target.Call("removeEventListener", "error", onError)
+		target.Call("removeEventListener", "abort", onError)
+		onSuccess.Release()
+		onError.Release()
+	}()
+
+	err := <-done
+	if err != nil {
+		return js.Undefined(), err
+	}
+	return req.Get("result"), nil
+}
+
+// domError converts a JS DOMException to a Go error
+func domError(exception js.Value) error {
+	if exception == js.Null() || exception == js.Undefined() {
+		return errors.New("unknown IndexedDB error")
+	}
+	if exception.Get("name").String() == "QuotaExceededError" {
+		return ErrQuota
+	}
+	return fmt.Errorf("%s: %s", exception.Get("name").String(), exception.Get("message").String())
+}
+
+// Estimate returns how many bytes this site is using and how many it is allowed to use
+func Estimate() (usage float64, quota float64, err error) {
+	storageManager := js.Global().Get("navigator").Get("storage")
+	if storageManager == js.Undefined() {
+		return 0, 0, errors.New("the browser can't estimate storage usage")
+	}
+	done := make(chan error, 1)
+	onResult := js.NewCallback(func(args []js.Value) {
+		usage = args[0].Get("usage").Float()
+		quota = args[0].Get("quota").Float()
+		done <- nil
+	})
+	onError := js.NewCallback(func(args []js.Value) {
+		done <- domError(args[0])
+	})
+	defer onResult.Release()
+	defer onError.Release()
+	storageManager.Call("estimate").Call("then", onResult, onError)
+	err = <-done
+	return usage, quota, err
+}
@@ -0,0 +1,23 @@
+package importer
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// HTML imports a web page or a document saved as HTML, keeping only what can be edited and is safe to show, see Sanitize
+func HTML(data []byte) (string, error) {
+	doc, err := goquery.NewDocumentFromReader(bytes.NewReader(data))
+	if err != nil {
+		return "", fmt.Errorf("failed to read the HTML: %s", err.Error())
+	}
+	body := doc.Find("body")
+	sanitize(body)
+	if strings.TrimSpace(body.Text()) == "" {
+		return "", ErrEmpty
+	}
+	return body.Html()
+}
@@ -0,0 +1,75 @@
+package importer
+
+import (
+	"errors"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Importer turns the contents of a file into the HTML of a case
+type Importer func(data []byte) (string, error)
+
+// format is a kind of file that can be imported
+type format struct {
+	exts     []string // Extensions with the dot, like .txt
+	mimes    []string
+	importer Importer
+}
+
+var formats = []format{}
+
+// ErrEmpty is returned when a file was read but had no text in it
+var ErrEmpty = errors.New("the file has no text in it")
+
+// Register adds an importer for files with any of the extensions or MIME types
+// Formats registered later win, so a format can be replaced
+func Register(exts []string, mimes []string, importer Importer) {
+	formats = append(formats, format{exts: exts, mimes: mimes, importer: importer})
+}
+
+// Find returns the importer for a file, looked up by its extension and then by its MIME type
+func Find(name string, mime string) (Importer, bool) {
+	ext := strings.ToLower(filepath.Ext(name))
+	for i := len(formats) - 1; i >= 0; i-- {
+		for _, known := range formats[i].exts {
+			if ext == known {
+				return formats[i].importer, true
+			}
+		}
+	}
+	// Browsers add parameters like charset to some types
+	mime = strings.ToLower(strings.TrimSpace(strings.Split(mime, ";")[0]))
+	for i := len(formats) - 1; i >= 0 && mime != ""; i-- {
+		for _, known := range formats[i].mimes {
+			if mime == known {
+				return formats[i].importer, true
+			}
+		}
+	}
+	return nil, false
+}
+
+// Extensions returns every extension that can be imported, sorted
+func Extensions() []string {
+	seen := make(map[string]bool)
+	exts := []string{}
+	for _, format := range formats {
+		for _, ext := range format.exts {
+			if !seen[ext] {
+				seen[ext] = true
+				exts = append(exts, ext)
+			}
+		}
+	}
+	sort.Strings(exts)
+	return exts
+}
+
+func init() {
+	Register([]string{".html", ".htm", ".xhtml"}, []string{"text/html", "application/xhtml+xml"}, HTML)
+	Register([]string{".txt", ".text"}, []string{"text/plain"}, Text)
+	Register([]string{".md", ".markdown"}, []string{"text/markdown", "text/x-markdown"}, Markdown)
+	Register([]string{".rtf"}, []string{"application/rtf", "text/rtf"}, RTF)
+	Register([]string{".odt"}, []string{"application/vnd.oasis.opendocument.text"}, ODT)
+}
@@ -0,0 +1,217 @@
+package importer
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"io/ioutil"
+	"strconv"
+	"strings"
+)
+
+// The XML namespaces of OpenDocument
+const (
+	odtText   = "urn:oasis:names:tc:opendocument:xmlns:text:1.0"
+	odtStyle  = "urn:oasis:names:tc:opendocument:xmlns:style:1.0"
+	odtFO     = "urn:oasis:names:tc:opendocument:xmlns:xsl-fo-compatible:1.0"
+	odtOffice = "urn:oasis:names:tc:opendocument:xmlns:office:1.0"
+)
+
+// odtFormat is the text formatting of an OpenDocument style
+type odtFormat struct {
+	bold      bool
+	italic    bool
+	underline bool
+	highlight bool
+}
+
+// ODT imports an OpenDocument text file, keeping paragraphs, headings, lists, bold, italics, underlining and highlighting
+func ODT(data []byte) (string, error) {
+	zipped, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return "", fmt.Errorf("failed to open the ODT file: %s", err.Error())
+	}
+	var content []byte
+	for _, file := range zipped.File {
+		if file.Name != "content.xml" {
+			continue
+		}
+		reader, err := file.Open()
+		if err != nil {
+			return "", fmt.Errorf("failed to open the text of the ODT file: %s", err.Error())
+		}
+		content, err = ioutil.ReadAll(reader)
+		reader.Close()
+		if err != nil {
+			return "", fmt.Errorf("failed to read the text of the ODT file: %s", err.Error())
+		}
+	}
+	if content == nil {
+		return "", errors.New("the ODT file has no content.xml")
+	}
+
+	styles, err := odtStyles(content)
+	if err != nil {
+		return "", err
+	}
+	out, err := odtBody(content, styles)
+	if err != nil {
+		return "", err
+	}
+	if strings.TrimSpace(out) == "" {
+		return "", ErrEmpty
+	}
+	return out, nil
+}
+
+func attrOf(el xml.StartElement, space string, local string) string {
+	for _, attr := range el.Attr {
+		if attr.Name.Space == space && attr.Name.Local == local {
+			return attr.Value
+		}
+	}
+	return ""
+}
+
+// odtStyles reads the formatting of the automatic styles, which is where word processors put bold and highlighting
+func odtStyles(content []byte) (map[string]odtFormat, error) {
+	styles := make(map[string]odtFormat)
+	decoder := xml.NewDecoder(bytes.NewReader(content))
+	current := ""
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return styles, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the styles of the ODT file: %s", err.Error())
+		}
+		el, ok := token.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		switch {
+		case el.Name.Space == odtStyle && el.Name.Local == "style":
+			current = attrOf(el, odtStyle, "name")
+		case el.Name.Space == odtStyle && el.Name.Local == "text-properties" && current != "":
+			underline := attrOf(el, odtStyle, "text-underline-style")
+			background := attrOf(el, odtFO, "background-color")
+			styles[current] = odtFormat{
+				bold:      attrOf(el, odtFO, "font-weight") == "bold",
+				italic:    attrOf(el, odtFO, "font-style") == "italic",
+				underline: underline != "" && underline != "none",
+				highlight: background != "" && background != "transparent",
+			}
+		case el.Name.Space == odtOffice && el.Name.Local == "body":
+			return styles, nil
+		}
+	}
+}
+
+// odtBody converts the body of the document to HTML
+func odtBody(content []byte, styles map[string]odtFormat) (string, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(content))
+	out := &strings.Builder{}
+	// closers holds what to write when each open element ends, so nesting comes out right
+	closers := []string{}
+	// skip counts how deep the decoder is in elements that hold no text of the document, like notes
+	skip := 0
+	inBody := false
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return out.String(), nil
+		}
+		if err != nil {
+			return "", fmt.Errorf("failed to read the ODT file: %s", err.Error())
+		}
+		switch el := token.(type) {
+		case xml.StartElement:
+			if el.Name.Space == odtOffice && el.Name.Local == "text" {
+				inBody = true
+			}
+			if skip > 0 || el.Name == (xml.Name{Space: odtOffice, Local: "annotation"}) || el.Name == (xml.Name{Space: odtText, Local: "note"}) {
+				skip++
+				closers = append(closers, "")
+				continue
+			}
+			if !inBody {
+				closers = append(closers, "")
+				continue
+			}
+			closers = append(closers, odtOpen(out, el, styles))
+		case xml.EndElement:
+			if len(closers) == 0 {
+				continue
+			}
+			closer := closers[len(closers)-1]
+			closers = closers[:len(closers)-1]
+			if skip > 0 {
+				skip--
+				continue
+			}
+			out.WriteString(closer)
+		case xml.CharData:
+			if inBody && skip == 0 {
+				out.WriteString(html.EscapeString(string(el)))
+			}
+		}
+	}
+}
+
+// odtOpen writes the start of an element and returns what closes it
+func odtOpen(out *strings.Builder, el xml.StartElement, styles map[string]odtFormat) string {
+	if el.Name.Space != odtText {
+		return ""
+	}
+	switch el.Name.Local {
+	case "h":
+		level, err := strconv.Atoi(attrOf(el, odtText, "outline-level"))
+		if err != nil || level < 1 {
+			level = 1
+		}
+		if level > 6 {
+			level = 6
+		}
+		fmt.Fprintf(out, "<h%v>", level)
+		return fmt.Sprintf("</h%v>", level)
+	case "p":
+		out.WriteString("<p>")
+		return "</p>"
+	case "list":
+		out.WriteString("<ul>")
+		return "</ul>"
+	case "list-item":
+		out.WriteString("<li>")
+		return "</li>"
+	case "span", "a":
+		format := styles[attrOf(el, odtText, "style-name")]
+		open, closer := "", ""
+		for _, tag := range []struct {
+			on   bool
+			name string
+		}{{format.bold, "b"}, {format.italic, "i"}, {format.underline, "u"}, {format.highlight, "mark"}} {
+			if tag.on {
+				open += "<" + tag.name + ">"
+				closer = "</" + tag.name + ">" + closer
+			}
+		}
+		out.WriteString(open)
+		return closer
+	case "s":
+		count, err := strconv.Atoi(attrOf(el, odtText, "c"))
+		if err != nil || count < 1 {
+			count = 1
+		}
+		out.WriteString(strings.Repeat(" ", count))
+	case "tab":
+		out.WriteString(" ")
+	case "line-break":
+		out.WriteString("<br>")
+	}
+	return ""
+}
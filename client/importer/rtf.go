@@ -0,0 +1,284 @@
+package importer
+
+import (
+	"errors"
+	"fmt"
+	"html"
+	"strconv"
+	"strings"
+)
+
+// rtfState is the formatting of an RTF group, which is restored when the group ends
+type rtfState struct {
+	bold      bool
+	italic    bool
+	underline bool
+	highlight bool
+	skip      bool // The group holds something that isn't text, like the font table or a picture
+	uc        int  // How many fallback characters follow a \u character
+}
+
+// rtfSkipped are the destinations that hold no text of the document
+var rtfSkipped = map[string]bool{
+	"fonttbl": true, "colortbl": true, "stylesheet": true, "info": true, "pict": true, "object": true,
+	"header": true, "headerl": true, "headerr": true, "headerf": true, "footer": true, "footerl": true,
+	"footerr": true, "footerf": true, "footnote": true, "listtable": true, "listoverridetable": true,
+	"rsidtbl": true, "generator": true, "themedata": true, "colorschememapping": true, "latentstyles": true,
+	"datastore": true, "xmlnstbl": true, "fldinst": true, "bkmkstart": true, "bkmkend": true,
+}
+
+// cp1252 maps the bytes Windows-1252 uses differently from Latin-1, which is how most RTF encodes accents and quotes
+var cp1252 = map[byte]rune{
+	0x80: '€', 0x85: '…', 0x91: '‘', 0x92: '’', 0x93: '“', 0x94: '”', 0x95: '•', 0x96: '–', 0x97: '—', 0x99: '™',
+}
+
+// rtfWriter builds the HTML of an RTF document one paragraph at a time
+type rtfWriter struct {
+	out     *strings.Builder
+	para    *strings.Builder
+	open    rtfState // The formatting of the text written to para so far
+	heading int      // The outline level of the current paragraph plus one, or 0 if it isn't a heading
+}
+
+// text writes text in the formatting of the group, closing and opening tags where it changed
+func (w *rtfWriter) text(state rtfState, text string) {
+	if text == "" {
+		return
+	}
+	w.format(state)
+	w.para.WriteString(html.EscapeString(text))
+}
+
+func (w *rtfWriter) format(state rtfState) {
+	tags := []struct {
+		was, is bool
+		tag     string
+	}{
+		{w.open.bold, state.bold, "b"},
+		{w.open.italic, state.italic, "i"},
+		{w.open.underline, state.underline, "u"},
+		{w.open.highlight, state.highlight, "mark"},
+	}
+	// Close everything that changed in reverse order so the tags nest
+	changed := -1
+	for i, tag := range tags {
+		if tag.was != tag.is {
+			changed = i
+			break
+		}
+	}
+	if changed == -1 {
+		return
+	}
+	for i := len(tags) - 1; i >= changed; i-- {
+		if tags[i].was {
+			fmt.Fprintf(w.para, "</%s>", tags[i].tag)
+		}
+	}
+	for i := changed; i < len(tags); i++ {
+		if tags[i].is {
+			fmt.Fprintf(w.para, "<%s>", tags[i].tag)
+		}
+	}
+	w.open = state
+}
+
+// paragraph ends the current paragraph
+func (w *rtfWriter) paragraph() {
+	w.format(rtfState{})
+	text := w.para.String()
+	w.para.Reset()
+	if strings.TrimSpace(text) == "" {
+		return
+	}
+	if w.heading > 0 && w.heading <= 6 {
+		fmt.Fprintf(w.out, "<h%v>%s</h%v>", w.heading, text, w.heading)
+		return
+	}
+	fmt.Fprintf(w.out, "<p>%s</p>", text)
+}
+
+// RTF imports a Rich Text Format document, keeping paragraphs, outline headings, bold, italics, underlining and highlighting
+func RTF(data []byte) (string, error) {
+	src := string(data)
+	if !strings.HasPrefix(strings.TrimSpace(src), `{\rtf`) {
+		return "", errors.New("the file isn't RTF")
+	}
+	w := &rtfWriter{out: &strings.Builder{}, para: &strings.Builder{}}
+	state := rtfState{uc: 1}
+	stack := []rtfState{}
+	// skipNext counts the fallback characters that follow a \u character and must be dropped
+	skipNext := 0
+	plain := &strings.Builder{}
+	flushText := func() {
+		if !state.skip {
+			w.text(state, plain.String())
+		}
+		plain.Reset()
+	}
+
+	for i := 0; i < len(src); i++ {
+		c := src[i]
+		if skipNext > 0 && c != '\\' && c != '{' && c != '}' {
+			if c != '\r' && c != '\n' {
+				skipNext--
+			}
+			continue
+		}
+		switch c {
+		case '{':
+			flushText()
+			stack = append(stack, state)
+		case '}':
+			flushText()
+			if len(stack) > 0 {
+				state = stack[len(stack)-1]
+				stack = stack[:len(stack)-1]
+			}
+		case '\r', '\n':
+		case '\\':
+			if i+1 >= len(src) {
+				continue
+			}
+			next := src[i+1]
+			switch {
+			case next == '\\' || next == '{' || next == '}':
+				plain.WriteByte(next)
+				i++
+			case next == '~':
+				plain.WriteString(" ")
+				i++
+			case next == '-' || next == '_':
+				i++
+			case next == '*':
+				// Destinations starting with \* are ones the reader may not know about, so they are skipped
+				flushText()
+				state.skip = true
+				i++
+			case next == '\'':
+				if i+3 < len(src) {
+					b, err := strconv.ParseUint(src[i+2:i+4], 16, 8)
+					if err == nil {
+						if r, ok := cp1252[byte(b)]; ok {
+							plain.WriteRune(r)
+						} else {
+							plain.WriteRune(rune(b))
+						}
+					}
+				}
+				i += 3
+			case next == '\r' || next == '\n':
+				// An escaped line break is the same as \par
+				flushText()
+				if !state.skip {
+					w.paragraph()
+				}
+				i++
+			case isLetter(next):
+				word, param, hasParam, end := rtfControl(src, i+1)
+				i = end - 1
+				if word == "u" && hasParam {
+					if param < 0 {
+						param += 65536
+					}
+					plain.WriteRune(rune(param))
+					skipNext = state.uc
+					continue
+				}
+				flushText()
+				state = rtfApply(w, state, word, param, hasParam)
+			default:
+				i++
+			}
+		default:
+			plain.WriteByte(c)
+		}
+	}
+	flushText()
+	w.paragraph()
+	if w.out.Len() == 0 {
+		return "", ErrEmpty
+	}
+	return w.out.String(), nil
+}
+
+// rtfApply applies a control word to the group's formatting, writing breaks to the document
+func rtfApply(w *rtfWriter, state rtfState, word string, param int, hasParam bool) rtfState {
+	on := !hasParam || param != 0
+	if rtfSkipped[word] {
+		state.skip = true
+		return state
+	}
+	if state.skip {
+		return state
+	}
+	switch word {
+	case "par", "sect", "page":
+		w.paragraph()
+	case "pard":
+		w.heading = 0
+	case "outlinelevel":
+		w.heading = param + 1
+	case "line":
+		w.format(state)
+		w.para.WriteString("<br>")
+	case "tab", "emspace", "enspace":
+		w.text(state, " ")
+	case "emdash":
+		w.text(state, "—")
+	case "endash":
+		w.text(state, "–")
+	case "lquote":
+		w.text(state, "‘")
+	case "rquote":
+		w.text(state, "’")
+	case "ldblquote":
+		w.text(state, "“")
+	case "rdblquote":
+		w.text(state, "”")
+	case "bullet":
+		w.text(state, "•")
+	case "plain":
+		state.bold, state.italic, state.underline, state.highlight = false, false, false, false
+	case "b":
+		state.bold = on
+	case "i":
+		state.italic = on
+	case "ul":
+		state.underline = on
+	case "ulnone":
+		state.underline = false
+	case "highlight", "cb", "chcbpat":
+		state.highlight = hasParam && param != 0
+	case "uc":
+		state.uc = param
+	}
+	return state
+}
+
+// rtfControl reads the control word starting at start, returning its name, its number if it has one and where it ends
+func rtfControl(src string, start int) (string, int, bool, int) {
+	end := start
+	for end < len(src) && isLetter(src[end]) {
+		end++
+	}
+	word := src[start:end]
+	numStart := end
+	if end < len(src) && src[end] == '-' {
+		end++
+	}
+	for end < len(src) && src[end] >= '0' && src[end] <= '9' {
+		end++
+	}
+	param, err := strconv.Atoi(src[numStart:end])
+	hasParam := err == nil
+	// A space after a control word belongs to it
+	if end < len(src) && src[end] == ' ' {
+		end++
+	}
+	return word, param, hasParam, end
+}
+
+func isLetter(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
@@ -0,0 +1,132 @@
+package importer
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"golang.org/x/net/html"
+)
+
+// droppedTags are removed along with everything in them, since what is in them isn't text that belongs in a case
+var droppedTags = map[string]bool{
+	"script": true, "style": true, "noscript": true, "template": true, "iframe": true, "frame": true, "frameset": true,
+	"object": true, "embed": true, "applet": true, "form": true, "button": true, "input": true, "select": true,
+	"textarea": true, "link": true, "meta": true, "base": true, "head": true, "title": true, "svg": true, "math": true,
+	"audio": true, "video": true, "canvas": true,
+}
+
+// allowedTags are the tags a case can hold, and any other tag is replaced by what is in it
+var allowedTags = map[string]bool{
+	"p": true, "br": true, "hr": true, "div": true, "span": true, "h1": true, "h2": true, "h3": true, "h4": true,
+	"h5": true, "h6": true, "b": true, "strong": true, "i": true, "em": true, "u": true, "s": true, "strike": true,
+	"del": true, "ins": true, "sub": true, "sup": true, "mark": true, "small": true, "big": true, "font": true,
+	"a": true, "img": true, "ul": true, "ol": true, "li": true, "dl": true, "dt": true, "dd": true,
+	"blockquote": true, "q": true, "cite": true, "pre": true, "code": true, "abbr": true, "figure": true,
+	"figcaption": true, "table": true, "caption": true, "thead": true, "tbody": true, "tfoot": true, "tr": true,
+	"th": true, "td": true, "col": true, "colgroup": true,
+}
+
+// allowedAttrs are the attributes a case can hold, along with any data- attribute
+// Event handlers like onclick are left out, since imported HTML runs with everything the app can reach
+var allowedAttrs = map[string]bool{
+	"href": true, "src": true, "alt": true, "title": true, "id": true, "class": true, "style": true, "dir": true,
+	"lang": true, "colspan": true, "rowspan": true, "width": true, "height": true, "start": true, "color": true,
+	"face": true, "size": true,
+}
+
+// allowedSchemes are the URL schemes links and images can use, by attribute
+// URLs without a scheme are relative, and are kept
+var allowedSchemes = map[string]map[string]bool{
+	"href": {"http": true, "https": true, "mailto": true},
+	"src":  {"http": true, "https": true, "data": true},
+}
+
+// Sanitize returns HTML with only the tags, attributes and URLs a case can hold, so it is safe to show in the app
+func Sanitize(source string) (string, error) {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(source))
+	if err != nil {
+		return "", fmt.Errorf("failed to read the HTML: %s", err.Error())
+	}
+	body := doc.Find("body")
+	sanitize(body)
+	return body.Html()
+}
+
+// sanitize strips everything a case can't hold from the selection, in place
+func sanitize(sel *goquery.Selection) {
+	for _, node := range sel.Nodes {
+		sanitizeNode(node)
+	}
+}
+
+func sanitizeNode(parent *html.Node) {
+	for child := parent.FirstChild; child != nil; {
+		next := child.NextSibling
+		switch child.Type {
+		case html.CommentNode, html.DoctypeNode:
+			parent.RemoveChild(child)
+		case html.ElementNode:
+			if droppedTags[child.Data] || child.Namespace != "" {
+				parent.RemoveChild(child)
+				break
+			}
+			sanitizeNode(child)
+			if !allowedTags[child.Data] {
+				for grandchild := child.FirstChild; grandchild != nil; grandchild = child.FirstChild {
+					child.RemoveChild(grandchild)
+					parent.InsertBefore(grandchild, child)
+				}
+				parent.RemoveChild(child)
+				break
+			}
+			child.Attr = sanitizeAttrs(child.Attr)
+		}
+		child = next
+	}
+}
+
+func sanitizeAttrs(attrs []html.Attribute) []html.Attribute {
+	kept := []html.Attribute{}
+	for _, attr := range attrs {
+		key := strings.ToLower(attr.Key)
+		if attr.Namespace != "" || (!allowedAttrs[key] && !strings.HasPrefix(key, "data-")) {
+			continue
+		}
+		if schemes, ok := allowedSchemes[key]; ok && !allowedURL(attr.Val, schemes) {
+			continue
+		}
+		if key == "style" && unsafeStyle(attr.Val) {
+			continue
+		}
+		kept = append(kept, attr)
+	}
+	return kept
+}
+
+// allowedURL returns whether the URL is relative or uses one of the schemes
+// Browsers ignore whitespace and control characters in schemes, so java\tscript: is still javascript:
+func allowedURL(url string, schemes map[string]bool) bool {
+	cleaned := strings.Map(func(r rune) rune {
+		if r <= ' ' {
+			return -1
+		}
+		return r
+	}, url)
+	colon := strings.Index(cleaned, ":")
+	if colon == -1 || strings.ContainsAny(cleaned[:colon], "/?#") {
+		return true
+	}
+	scheme := strings.ToLower(cleaned[:colon])
+	if scheme == "data" {
+		return schemes["data"] && strings.HasPrefix(strings.ToLower(cleaned), "data:image/")
+	}
+	return schemes[scheme]
+}
+
+// unsafeStyle returns whether a style could load something or run script, which formatting never needs
+func unsafeStyle(style string) bool {
+	lower := strings.ToLower(style)
+	return strings.Contains(lower, "url(") || strings.Contains(lower, "expression(") || strings.Contains(lower, "javascript:") ||
+		strings.Contains(lower, "@import") || strings.Contains(lower, `\`)
+}
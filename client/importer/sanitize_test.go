@@ -0,0 +1,39 @@
+package importer
+
+import "testing"
+
+func TestSanitize(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		want   string
+	}{
+		{"script tags", `<p>Text</p><script>alert(1)</script>`, `<p>Text</p>`},
+		{"scripts inside other elements", `<div><p>Text<script>alert(1)</script></p></div>`, `<div><p>Text</p></div>`},
+		{"scripts inside svg", `<svg><script>alert(1)</script></svg><p>Text</p>`, `<p>Text</p>`},
+		{"event handlers", `<p onclick="alert(1)" class="tag">Text</p>`, `<p class="tag">Text</p>`},
+		{"event handlers in capitals", `<p OnMouseOver="alert(1)">Text</p>`, `<p>Text</p>`},
+		{"event handlers on images", `<img src="https://example.com/a.png" onerror="alert(1)"/>`, `<img src="https://example.com/a.png"/>`},
+		{"javascript links", `<a href="javascript:alert(1)">Link</a>`, `<a>Link</a>`},
+		{"javascript links with whitespace and capitals", "<a href=\" JaVa\tscript:alert(1)\">Link</a>", `<a>Link</a>`},
+		{"vbscript links", `<a href="vbscript:msgbox(1)">Link</a>`, `<a>Link</a>`},
+		{"html data URLs", `<img src="data:text/html,alert(1)"/>`, `<img/>`},
+		{"scripts in styles", `<span style="background: url(javascript:alert(1))">Text</span>`, `<span>Text</span>`},
+		{"frames", `<iframe src="https://example.com"></iframe><p>Text</p>`, `<p>Text</p>`},
+		{"comments", `<p>Text<!-- <script>alert(1)</script> --></p>`, `<p>Text</p>`},
+		{"unknown tags keep their text", `<custom-tag><b>Text</b></custom-tag>`, `<b>Text</b>`},
+		{"safe links", `<a href="https://example.com">Link</a><a href="#tag">Link</a>`, `<a href="https://example.com">Link</a><a href="#tag">Link</a>`},
+		{"image data URLs", `<img src="data:image/png;base64,AA"/>`, `<img src="data:image/png;base64,AA"/>`},
+		{"formatting", `<h4 id="a" data-labels="aff">Tag</h4><p><mark>Text</mark> <span style="font-size: 8pt">small</span></p>`, `<h4 id="a" data-labels="aff">Tag</h4><p><mark>Text</mark> <span style="font-size: 8pt">small</span></p>`},
+	}
+	for _, test := range tests {
+		got, err := Sanitize(test.source)
+		if err != nil {
+			t.Errorf("%s: %s", test.name, err)
+			continue
+		}
+		if got != test.want {
+			t.Errorf("%s: got %q, want %q", test.name, got, test.want)
+		}
+	}
+}
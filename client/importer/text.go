@@ -0,0 +1,146 @@
+package importer
+
+import (
+	"fmt"
+	"html"
+	"regexp"
+	"strings"
+)
+
+var blankLineRp = regexp.MustCompile(`\n\s*\n`)
+
+// lines normalizes the line endings of text and splits it into lines
+func lines(data []byte) []string {
+	text := strings.Replace(string(data), "\r\n", "\n", -1)
+	text = strings.Replace(text, "\r", "\n", -1)
+	return strings.Split(strings.TrimPrefix(text, "\ufeff"), "\n")
+}
+
+// Text imports plain text, where paragraphs are separated by blank lines
+func Text(data []byte) (string, error) {
+	out := &strings.Builder{}
+	for _, para := range blankLineRp.Split(strings.Join(lines(data), "\n"), -1) {
+		para = strings.Join(strings.Fields(para), " ")
+		if para != "" {
+			fmt.Fprintf(out, "<p>%s</p>", html.EscapeString(para))
+		}
+	}
+	if out.Len() == 0 {
+		return "", ErrEmpty
+	}
+	return out.String(), nil
+}
+
+var (
+	mdHeadingRp = regexp.MustCompile(`^(#{1,6})\s+(.*?)\s*#*\s*$`)
+	mdBulletRp  = regexp.MustCompile(`^\s*[-*+]\s+(.*)$`)
+	mdNumberRp  = regexp.MustCompile(`^\s*\d+[.)]\s+(.*)$`)
+	mdQuoteRp   = regexp.MustCompile(`^\s*>\s?(.*)$`)
+	mdFenceRp   = regexp.MustCompile("^\\s*(```|~~~)")
+	mdRuleRp    = regexp.MustCompile(`^\s*([-*_])(\s*[-*_]){2,}\s*$`)
+)
+
+// Markdown imports Markdown, supporting headings, paragraphs, lists, quotes, code blocks and inline formatting
+// ==text== becomes highlighted text, which is how debaters mark what gets read
+func Markdown(data []byte) (string, error) {
+	out := &strings.Builder{}
+	para := []string{}
+	list := ""
+	inFence := false
+	flush := func() {
+		if len(para) > 0 {
+			fmt.Fprintf(out, "<p>%s</p>", mdInline(strings.Join(para, " ")))
+			para = para[:0]
+		}
+	}
+	closeList := func() {
+		if list != "" {
+			fmt.Fprintf(out, "</%s>", list)
+			list = ""
+		}
+	}
+	openList := func(tag string) {
+		if list != tag {
+			closeList()
+			fmt.Fprintf(out, "<%s>", tag)
+			list = tag
+		}
+	}
+
+	for _, line := range lines(data) {
+		if mdFenceRp.MatchString(line) {
+			flush()
+			closeList()
+			if inFence {
+				out.WriteString("</pre>")
+			} else {
+				out.WriteString("<pre>")
+			}
+			inFence = !inFence
+			continue
+		}
+		if inFence {
+			out.WriteString(html.EscapeString(line) + "\n")
+			continue
+		}
+		switch {
+		case strings.TrimSpace(line) == "":
+			flush()
+			closeList()
+		case mdRuleRp.MatchString(line):
+			flush()
+			closeList()
+			out.WriteString("<hr>")
+		case mdHeadingRp.MatchString(line):
+			flush()
+			closeList()
+			match := mdHeadingRp.FindStringSubmatch(line)
+			fmt.Fprintf(out, "<h%v>%s</h%v>", len(match[1]), mdInline(match[2]), len(match[1]))
+		case mdBulletRp.MatchString(line):
+			flush()
+			openList("ul")
+			fmt.Fprintf(out, "<li>%s</li>", mdInline(mdBulletRp.FindStringSubmatch(line)[1]))
+		case mdNumberRp.MatchString(line):
+			flush()
+			openList("ol")
+			fmt.Fprintf(out, "<li>%s</li>", mdInline(mdNumberRp.FindStringSubmatch(line)[1]))
+		case mdQuoteRp.MatchString(line):
+			flush()
+			closeList()
+			fmt.Fprintf(out, "<blockquote>%s</blockquote>", mdInline(mdQuoteRp.FindStringSubmatch(line)[1]))
+		default:
+			closeList()
+			para = append(para, strings.TrimSpace(line))
+		}
+	}
+	flush()
+	closeList()
+	if inFence {
+		out.WriteString("</pre>")
+	}
+	if out.Len() == 0 {
+		return "", ErrEmpty
+	}
+	return out.String(), nil
+}
+
+// mdInlines are the inline formats, in the order they are applied to escaped text
+var mdInlines = []struct {
+	rp   *regexp.Regexp
+	repl string
+}{
+	{regexp.MustCompile("`([^`]+)`"), "<code>$1</code>"},
+	{regexp.MustCompile(`\[([^\]]+)\]\((https?://[^)\s]+)\)`), `<a href="$2">$1</a>`},
+	{regexp.MustCompile(`\*\*(.+?)\*\*|__(.+?)__`), "<b>$1$2</b>"},
+	{regexp.MustCompile(`\*(\S(?:.*?\S)?)\*|\b_(\S(?:.*?\S)?)_\b`), "<i>$1$2</i>"},
+	{regexp.MustCompile(`==(.+?)==`), "<mark>$1</mark>"},
+}
+
+// mdInline formats the inline Markdown of a line as HTML
+func mdInline(text string) string {
+	text = html.EscapeString(text)
+	for _, inline := range mdInlines {
+		text = inline.rp.ReplaceAllString(text, inline.repl)
+	}
+	return text
+}
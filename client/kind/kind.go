@@ -0,0 +1,62 @@
+package kind
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// Kind is what a card does in an argument
+type Kind string
+
+// The kinds of cards, in the order the card view shows them
+const (
+	Uniqueness   Kind = "Uniqueness"
+	Link         Kind = "Link"
+	InternalLink Kind = "Internal link"
+	Impact       Kind = "Impact"
+	Solvency     Kind = "Solvency"
+	Answer       Kind = "Answer"
+	Other        Kind = "Other"
+)
+
+// Kinds lists every kind in the order the card view shows them
+var Kinds = []Kind{Uniqueness, Link, InternalLink, Impact, Solvency, Answer, Other}
+
+// rule is a wording that always means a kind, checked before the model
+type rule struct {
+	rp   *regexp.Regexp
+	kind Kind
+}
+
+// rules are checked in order, so answers come first since "no link" is an answer and not a link,
+// and links come before impacts since "the plan kills growth" is a link
+var rules = []rule{
+	// Shorthands that are also words, like "At least" or "Extensions of", only mean an answer when punctuation follows them, like "AT: Politics"
+	{regexp.MustCompile(`(?i)^\s*(a2|a/t)\b`), Answer},
+	{regexp.MustCompile(`(?i)^\s*(at|answers? to|ext(end|ension)?s?|turns?|perm(utation)?s?)\s*[:.\-–—]`), Answer},
+	{regexp.MustCompile(`(?i)^\s*(no|non|not)[\s-]+(link|unique|uniqueness|impact|internal link|solvency|war|escalation|spillover)\b`), Answer},
+	{regexp.MustCompile(`(?i)\b(non[\s-]?unique|doesn'?t solve|can'?t solve|fails to solve|empirically denied)\b`), Answer},
+	{regexp.MustCompile(`(?i)\b(plan|plan's|aff|aff's)\b.*\b(trades? off|causes|triggers|undermines|kills|collapses|destroys|costs|saps|links)\b`), Link},
+	{regexp.MustCompile(`(?i)\b(status quo|squo|right now|on track|is high|is low|will pass|is passing)\b`), Uniqueness},
+	{regexp.MustCompile(`(?i)\b(extinction|nuclear war|nuclear winter|genocide|mass death|outweighs|existential)\b`), Impact},
+	{regexp.MustCompile(`(?i)\b(solves?|solvency|resolves)\b`), Solvency},
+}
+
+// Classify returns what the card with the tag does, guessing from the wording of the tag
+// Tags that match a rule get its kind, and the rest are classified by a model trained on example tags
+func Classify(tag string) Kind {
+	for _, r := range rules {
+		if r.rp.MatchString(tag) {
+			return r.kind
+		}
+	}
+	return trained.classify(Words(tag))
+}
+
+// Words splits a tag into lowercase words without punctuation
+func Words(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r) && r != '\''
+	})
+}
@@ -0,0 +1,187 @@
+package kind
+
+import "math"
+
+// examples are tags of each kind that the model is trained on when the package loads
+// Training takes well under a millisecond, so shipping the examples keeps the model easy to read and to extend
+var examples = map[Kind][]string{
+	Uniqueness: {
+		"Growth is strong and stable",
+		"The economy is recovering",
+		"Relations are improving",
+		"The bill has bipartisan support and momentum",
+		"Capital is high for the president",
+		"Tensions are stable and deterrence holds",
+		"Emissions are declining",
+		"Hiring and markets are up this quarter",
+		"Congress is on schedule to pass it",
+		"Cooperation is increasing",
+		"Current policy is working",
+		"Polls show support is rising",
+	},
+	Link: {
+		"Plan is perceived as a concession",
+		"Spending on the plan trades off with defense",
+		"Plan drains political capital",
+		"Federal action sparks a backlash in Congress",
+		"Funding the program spikes the deficit",
+		"Regulation of industry hurts business confidence",
+		"Expanding the program angers allies",
+		"Unpopular policies cost the president votes",
+		"Increasing enforcement provokes China",
+		"Action signals weakness to adversaries",
+		"New mandates burden small businesses",
+		"Plan undercuts the agenda",
+	},
+	InternalLink: {
+		"Capital is key to passing the agenda",
+		"Confidence is necessary for investment",
+		"Growth is key to hegemony",
+		"Deterrence is necessary to prevent escalation",
+		"Alliances are key to stability in Asia",
+		"Investment drives innovation",
+		"Credibility is key to deterrence",
+		"Recession undermines military readiness",
+		"Economic decline causes diversionary conflict",
+		"Instability spills over to the region",
+		"Loss of leadership emboldens rivals",
+		"Trade is key to cooperation",
+	},
+	Impact: {
+		"Warming causes extinction",
+		"Great power war goes nuclear",
+		"Economic collapse causes war",
+		"Pandemics cause mass casualties",
+		"Conflict escalates and kills millions",
+		"Biodiversity loss collapses ecosystems",
+		"Terrorism risks catastrophic attacks",
+		"Structural violence is the biggest impact",
+		"Famine and poverty kill more people every year",
+		"Escalation destroys civilization",
+		"Hegemony decline causes global war",
+		"Dehumanization is the root of atrocity",
+	},
+	Solvency: {
+		"The plan is effective and enforceable",
+		"Federal action is key to success",
+		"Only the plan provides the certainty industry needs",
+		"Increased funding works",
+		"The program succeeds empirically",
+		"The policy is feasible and will be implemented",
+		"Courts will enforce the ruling",
+		"Investment in the program increases capacity",
+		"The mechanism ensures compliance",
+		"Plan creates the necessary incentives",
+		"Agencies can implement it quickly",
+		"Studies prove the model works",
+	},
+	Answer: {
+		"Their evidence is old and biased",
+		"Their author is wrong",
+		"Link is too small to matter",
+		"Impact is exaggerated",
+		"Alt causes swamp the link",
+		"Thumpers already triggered the link",
+		"Capital theory is false",
+		"Winners win",
+		"Deterrence checks escalation",
+		"Economic decline doesn't cause war",
+		"Their studies are flawed",
+		"Other factors outweigh and decide the outcome",
+	},
+}
+
+// minLead is how much more likely, as a log probability, the best kind must be than the next one to be picked
+const minLead = 0.5
+
+// model is a naive Bayes classifier over the words of tags
+type model struct {
+	priors map[Kind]float64            // The log probability of each kind
+	words  map[Kind]map[string]float64 // The log probability of each word given the kind
+	unseen map[Kind]float64            // The log probability of a word the kind was never trained on
+	vocab  map[string]bool
+}
+
+var trained = train(examples)
+
+// train counts the words of every example, smoothing so no word is impossible for a kind
+func train(examples map[Kind][]string) *model {
+	m := &model{
+		priors: make(map[Kind]float64),
+		words:  make(map[Kind]map[string]float64),
+		unseen: make(map[Kind]float64),
+		vocab:  make(map[string]bool),
+	}
+	counts := make(map[Kind]map[string]int)
+	totals := make(map[Kind]int)
+	all := 0
+	for kind, tags := range examples {
+		counts[kind] = make(map[string]int)
+		for _, tag := range tags {
+			for _, word := range Words(tag) {
+				if stopWords[word] {
+					continue
+				}
+				counts[kind][word]++
+				totals[kind]++
+				m.vocab[word] = true
+			}
+		}
+		all += len(tags)
+	}
+	for kind, tags := range examples {
+		m.priors[kind] = math.Log(float64(len(tags)) / float64(all))
+		denominator := float64(totals[kind] + len(m.vocab))
+		m.words[kind] = make(map[string]float64)
+		for word, count := range counts[kind] {
+			m.words[kind][word] = math.Log(float64(count+1) / denominator)
+		}
+		m.unseen[kind] = math.Log(1 / denominator)
+	}
+	return m
+}
+
+// classify returns the most likely kind for the words, or Other if none of them were trained on or no kind is clearly ahead
+func (m *model) classify(words []string) Kind {
+	known := []string{}
+	for _, word := range words {
+		if m.vocab[word] && !stopWords[word] {
+			known = append(known, word)
+		}
+	}
+	if len(known) == 0 {
+		return Other
+	}
+	best, bestScore, second := Other, math.Inf(-1), math.Inf(-1)
+	// Kinds is walked instead of the map so ties always go the same way
+	for _, kind := range Kinds {
+		if _, ok := m.priors[kind]; !ok {
+			continue
+		}
+		score := m.priors[kind]
+		for _, word := range known {
+			if p, ok := m.words[kind][word]; ok {
+				score += p
+			} else {
+				score += m.unseen[kind]
+			}
+		}
+		switch {
+		case score > bestScore:
+			best, bestScore, second = kind, score, bestScore
+		case score > second:
+			second = score
+		}
+	}
+	if bestScore-second < minLead {
+		return Other
+	}
+	return best
+}
+
+// stopWords are too common in tags to say anything about their kind
+var stopWords = map[string]bool{
+	"the": true, "a": true, "an": true, "and": true, "or": true, "of": true, "to": true, "in": true,
+	"on": true, "for": true, "with": true, "is": true, "are": true, "it": true, "its": true,
+	"that": true, "this": true, "their": true, "by": true, "as": true, "be": true, "will": true,
+}
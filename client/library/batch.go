@@ -0,0 +1,140 @@
+package library
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"gitlab.com/256/DebateFrame/client/importer"
+)
+
+// Source is a file waiting to be imported
+type Source struct {
+	Path string                 // Where the file is in the folder or zip, like Aff/Advantages/Econ.docx
+	MIME string                 // The type the browser gave the file, if any
+	Read func() ([]byte, error) // Reads the file, which is only done when it is its turn so big batches aren't all held in memory
+}
+
+// Result is what happened to one file of a batch
+type Result struct {
+	Path      string
+	Entry     Entry // The entry the file was imported as, or the entry it duplicates
+	Duplicate bool  // The file was already in the library, so it was skipped
+	Err       error // Why the file couldn't be imported
+}
+
+// ignored returns whether a file in a folder or zip is one the user never meant to import, like lock files and macOS metadata
+func ignored(filePath string) bool {
+	name := path.Base(filePath)
+	return strings.HasPrefix(name, ".") || strings.HasPrefix(name, "~$") || strings.Contains(filePath, "__MACOSX/")
+}
+
+// Unzip returns the files of a zip as sources, leaving out folders and files that aren't evidence
+func Unzip(data []byte) ([]Source, error) {
+	zipped, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to open the zip")
+	}
+	sources := []Source{}
+	for _, file := range zipped.File {
+		if file.FileInfo().IsDir() || ignored(file.Name) {
+			continue
+		}
+		file := file
+		sources = append(sources, Source{
+			Path: file.Name,
+			Read: func() ([]byte, error) {
+				reader, err := file.Open()
+				if err != nil {
+					return nil, err
+				}
+				defer reader.Close()
+				return ioutil.ReadAll(reader)
+			},
+		})
+	}
+	return sources, nil
+}
+
+// Import adds every source to the library, reporting progress after each file
+// A file that fails doesn't stop the rest, and files already in the library are skipped
+func (lib *Library) Import(sources []Source, progress func(done int, total int, current string)) []Result {
+	results := []Result{}
+	for i, src := range sources {
+		if progress != nil {
+			progress(i, len(sources), src.Path)
+		}
+		if ignored(src.Path) {
+			continue
+		}
+		results = append(results, lib.importOne(src))
+	}
+	if progress != nil {
+		progress(len(sources), len(sources), "")
+	}
+	return results
+}
+
+func (lib *Library) importOne(src Source) Result {
+	result := Result{Path: src.Path}
+	imp, ok := importer.Find(src.Path, src.MIME)
+	if !ok {
+		result.Err = fmt.Errorf("%s files can't be imported", path.Ext(src.Path))
+		return result
+	}
+	data, err := src.Read()
+	if err != nil {
+		result.Err = errors.Wrap(err, "failed to read the file")
+		return result
+	}
+	id := Hash(data)
+	if entry, ok := lib.Entry(id); ok {
+		result.Entry = entry
+		result.Duplicate = true
+		return result
+	}
+	html, err := imp(data)
+	if err != nil {
+		result.Err = err
+		return result
+	}
+
+	folder := path.Dir(strings.Trim(src.Path, "/"))
+	if folder == "." {
+		folder = ""
+	}
+	name := path.Base(src.Path)
+	result.Entry = Entry{
+		ID:       id,
+		Name:     strings.TrimSuffix(name, path.Ext(name)),
+		Folder:   folder,
+		Topic:    GuessTopic(folder),
+		Side:     GuessSide(src.Path),
+		Size:     len(data),
+		Imported: time.Now(),
+	}
+	result.Err = lib.Add(result.Entry, html)
+	return result
+}
+
+// Summary describes the results of a batch in one sentence
+func Summary(results []Result) string {
+	imported, duplicates, failed := 0, 0, 0
+	for _, result := range results {
+		switch {
+		case result.Err != nil:
+			failed++
+		case result.Duplicate:
+			duplicates++
+		default:
+			imported++
+		}
+	}
+	return fmt.Sprintf("Imported %v files into the library, skipped %v already there and %v failed", imported, duplicates, failed)
+}
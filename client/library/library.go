@@ -0,0 +1,157 @@
+package library
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"gitlab.com/256/DebateFrame/client/idb"
+)
+
+const (
+	EntriesStore = "library"     // Holds an Entry as json for every file in the library, keyed by ID
+	DocsStore    = "libraryDocs" // Holds the HTML of every file in the library, keyed by ID
+)
+
+// Entry is a file that was imported into the library
+// The HTML is kept separately so the library can be listed without reading every document
+type Entry struct {
+	ID       string    // The hash of the file as it was imported, so importing the same file again is noticed
+	Name     string    // The file name without its extension
+	Folder   string    // Where the file was in the folder or zip it came from, like Aff/Advantages, or empty
+	Topic    string    // What the file is about, like Econ DA, or empty if nobody has said
+	Side     string    // Aff, Neg or empty if it is for both
+	Size     int       // The size of the imported file in bytes
+	Imported time.Time // When the file was imported
+}
+
+// Path returns the folder and name of the entry, like Aff/Advantages/Econ
+func (entry Entry) Path() string {
+	if entry.Folder == "" {
+		return entry.Name
+	}
+	return entry.Folder + "/" + entry.Name
+}
+
+// Library holds the files imported from folders and zips, without showing them as tabs
+// Every function that touches storage blocks, so they must not be called directly from a JS callback
+type Library struct {
+	db      *idb.DB
+	entries map[string]Entry // By ID
+}
+
+// Open reads the library kept in the database, which must have been opened with EntriesStore and DocsStore
+func Open(db *idb.DB) (*Library, error) {
+	lib := &Library{db: db, entries: make(map[string]Entry)}
+	keys, err := db.Keys(EntriesStore)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list the library")
+	}
+	for _, key := range keys {
+		str, ok, err := db.Get(EntriesStore, key)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to read library entry %s", key)
+		}
+		if !ok {
+			continue
+		}
+		entry := Entry{}
+		err = json.Unmarshal([]byte(str), &entry)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to decode library entry %s", key)
+		}
+		lib.entries[entry.ID] = entry
+	}
+	return lib, nil
+}
+
+// Hash returns the ID of the entry a file is imported as
+func Hash(data []byte) string {
+	sum := sha1.Sum(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// Add saves a file to the library
+func (lib *Library) Add(entry Entry, html string) error {
+	if entry.ID == "" {
+		return errors.New("library entries need an ID")
+	}
+	str, err := json.Marshal(entry)
+	if err != nil {
+		return errors.Wrap(err, "failed to convert the library entry to json")
+	}
+	// The document goes first so an entry is never listed without one
+	err = lib.db.Put(DocsStore, entry.ID, html)
+	if err != nil {
+		return err
+	}
+	err = lib.db.Put(EntriesStore, entry.ID, string(str))
+	if err != nil {
+		return err
+	}
+	lib.entries[entry.ID] = entry
+	return nil
+}
+
+// Update saves changes to an entry, like a new topic, without touching its document
+func (lib *Library) Update(entry Entry) error {
+	if _, ok := lib.entries[entry.ID]; !ok {
+		return fmt.Errorf("library entry %s doesn't exist", entry.ID)
+	}
+	str, err := json.Marshal(entry)
+	if err != nil {
+		return errors.Wrap(err, "failed to convert the library entry to json")
+	}
+	err = lib.db.Put(EntriesStore, entry.ID, string(str))
+	if err != nil {
+		return err
+	}
+	lib.entries[entry.ID] = entry
+	return nil
+}
+
+// Entries returns every entry in the library, sorted by path
+func (lib *Library) Entries() []Entry {
+	entries := []Entry{}
+	for _, entry := range lib.entries {
+		entries = append(entries, entry)
+	}
+	sort.Slice(entries, func(a, b int) bool {
+		return strings.ToLower(entries[a].Path()) < strings.ToLower(entries[b].Path())
+	})
+	return entries
+}
+
+// Entry returns the entry with the ID, and whether there is one
+func (lib *Library) Entry(id string) (Entry, bool) {
+	entry, ok := lib.entries[id]
+	return entry, ok
+}
+
+// Document returns the HTML of an entry
+func (lib *Library) Document(id string) (string, error) {
+	html, ok, err := lib.db.Get(DocsStore, id)
+	if err != nil {
+		return "", err
+	}
+	if !ok {
+		return "", fmt.Errorf("library entry %s has no document", id)
+	}
+	return html, nil
+}
+
+// Remove deletes an entry and its document from the library
+func (lib *Library) Remove(id string) error {
+	err := lib.db.Delete(EntriesStore, id)
+	if err != nil {
+		return err
+	}
+	delete(lib.entries, id)
+	return lib.db.Delete(DocsStore, id)
+}
@@ -0,0 +1,150 @@
+package library
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// The ways the library tree can be grouped
+const (
+	ByFolder = "folder"
+	ByTopic  = "topic"
+	BySide   = "side"
+)
+
+const (
+	Aff = "Aff"
+	Neg = "Neg"
+)
+
+var (
+	affRp = regexp.MustCompile(`(?i)(^|[^a-z])(affs?|affirmative|1ac|2ac|1ar|2ar)([^a-z]|$)`)
+	negRp = regexp.MustCompile(`(?i)(^|[^a-z])(negs?|negative|1nc|2nc|1nr|2nr|da|disad|cp|counterplan|k|kritik)([^a-z]|$)`)
+	// sideFolderRp matches folders that only say which side is in them
+	sideFolderRp = regexp.MustCompile(`(?i)^\s*(aff|neg|affirmative|negative)s?\s*$`)
+)
+
+// GuessSide guesses the side a file is for from its path, like Aff for Aff/Advantages/Econ.docx
+// Folders are checked before the file name, since they are usually organized by side
+func GuessSide(filePath string) string {
+	for _, part := range strings.Split(filePath, "/") {
+		isAff, isNeg := affRp.MatchString(part), negRp.MatchString(part)
+		switch {
+		case isAff && !isNeg:
+			return Aff
+		case isNeg && !isAff:
+			return Neg
+		}
+	}
+	return ""
+}
+
+// GuessTopic guesses the topic of a file from its folder, which is the first folder that isn't a side folder
+func GuessTopic(folder string) string {
+	for _, part := range strings.Split(folder, "/") {
+		if strings.TrimSpace(part) != "" && !sideFolderRp.MatchString(part) {
+			return part
+		}
+	}
+	return ""
+}
+
+// Node is a group of the library tree
+type Node struct {
+	Name     string
+	Children []*Node
+	Entries  []Entry
+}
+
+// child returns the child group with the name, adding it if it doesn't exist
+func (node *Node) child(name string) *Node {
+	for _, child := range node.Children {
+		if child.Name == name {
+			return child
+		}
+	}
+	child := &Node{Name: name}
+	node.Children = append(node.Children, child)
+	return child
+}
+
+// Count returns how many entries are in the group and the groups under it
+func (node *Node) Count() int {
+	count := len(node.Entries)
+	for _, child := range node.Children {
+		count += child.Count()
+	}
+	return count
+}
+
+func (node *Node) sort() {
+	sort.Slice(node.Children, func(a, b int) bool {
+		return strings.ToLower(node.Children[a].Name) < strings.ToLower(node.Children[b].Name)
+	})
+	sort.Slice(node.Entries, func(a, b int) bool {
+		return strings.ToLower(node.Entries[a].Name) < strings.ToLower(node.Entries[b].Name)
+	})
+	for _, child := range node.Children {
+		child.sort()
+	}
+}
+
+// Tree groups the entries by folder, by topic and then side, or by side and then topic
+func Tree(entries []Entry, groupBy string) *Node {
+	root := &Node{}
+	for _, entry := range entries {
+		node := root
+		for _, group := range groups(entry, groupBy) {
+			node = node.child(group)
+		}
+		node.Entries = append(node.Entries, entry)
+	}
+	root.sort()
+	return root
+}
+
+// groups returns the names of the groups an entry is in, from the top of the tree down
+func groups(entry Entry, groupBy string) []string {
+	topic := entry.Topic
+	if topic == "" {
+		topic = "No topic"
+	}
+	side := entry.Side
+	if side == "" {
+		side = "Both sides"
+	}
+	switch groupBy {
+	case ByTopic:
+		return []string{topic, side}
+	case BySide:
+		return []string{side, topic}
+	}
+	if entry.Folder == "" {
+		return nil
+	}
+	return strings.Split(entry.Folder, "/")
+}
+
+// Filter returns the entries whose path, topic or side has every word of the query in it
+func Filter(entries []Entry, query string) []Entry {
+	words := strings.Fields(strings.ToLower(query))
+	if len(words) == 0 {
+		return entries
+	}
+	found := []Entry{}
+	for _, entry := range entries {
+		text := strings.ToLower(entry.Path() + " " + entry.Topic + " " + entry.Side)
+		matches := true
+		for _, word := range words {
+			if !strings.Contains(text, word) {
+				matches = false
+				break
+			}
+		}
+		if matches {
+			found = append(found, entry)
+		}
+	}
+	return found
+}
@@ -24,9 +24,7 @@ func main() {
 	err = config.RestoreState()
 	if err != nil {
 		log.WarnMessage(err.Error())
-		log.DebugMessage("Failed to restore previous state... Clearing LocalStorage and starting over")
-		config.LocalStorage.Clear()
-		config.CurrentConfig = config.Configuration{}
+		log.DebugMessage("Failed to restore previous state... Starting over with the default configuration")
 	}
 	if !config.CurrentConfig.FinishedWizard { 
 		state.StartWrap(state.Setup, func(container *dyndom.Element) {
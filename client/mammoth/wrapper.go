@@ -1,6 +1,7 @@
 package mammoth
 
 import (
+	"errors"
 	"syscall/js"
 	"time"
 
@@ -30,6 +31,31 @@ func ConvertToHTML(file *js.Value, fn func(html string)) {
 	})
 }
 
+// ConvertBytes generates HTML from the contents of a docx file, so it must never be called from a callback
+func ConvertBytes(data []byte) (string, error) {
+	array := js.TypedArrayOf(data)
+	buffer := js.Global().Get("Uint8Array").New(array).Get("buffer")
+	array.Release()
+	options := make(map[string]interface{})
+	options["arrayBuffer"] = buffer
+
+	var html string
+	done := make(chan error, 1)
+	onResult := js.NewCallback(func(args []js.Value) {
+		html = args[0].Get("value").String()
+		done <- nil
+	})
+	onError := js.NewCallback(func(args []js.Value) {
+		done <- errors.New(args[0].Call("toString").String())
+	})
+	defer onResult.Release()
+	defer onError.Release()
+
+	js.Global().Get("mammoth").Call("convertToHtml", options).Call("then", onResult, onError)
+	err := <-done
+	return html, err
+}
+
 func loadingScreen() {
 	modalElem := dom.GetDocument().GetElementById("modal-mammothload")
 	modalElem.ClassList().Remove("simplehide")
@@ -274,7 +274,7 @@ func (opts *PasteOptions) Value() js.Value {
 }
 
 type Binding struct {
-	Command string // argument passed to editor.execAction() when key-combination is used
+	Command string // argument passed to editor.execAction() when key-combination is used, or empty to disable the key-combination
 	Key     rune   // keyboard character that triggers this command
 	Meta    bool   // whether the ctrl/meta key has to be active or inactive
 	Shift   bool   // whether the shift key has to be active or inactive
@@ -284,6 +284,9 @@ type Binding struct {
 func (bind *Binding) Value() js.Value {
 	bindingMap := make(map[string]interface{})
 	bindingMap["command"] = bind.Command
+	if bind.Command == "" {
+		bindingMap["command"] = false
+	}
 	bindingMap["key"] = string(bind.Key)
 	bindingMap["meta"] = bind.Meta
 	bindingMap["shift"] = bind.Shift
@@ -0,0 +1,190 @@
+package merge
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Section is a heading and the blocks under it until the next heading, which is the unit that gets merged
+// The blocks before the first heading are a section with an empty key
+type Section struct {
+	Key    string // Identifies the section in every version of the document
+	Blocks []string
+}
+
+// Conflict is a section that both sides changed in different ways
+// A side is nil if it deleted the section
+type Conflict struct {
+	Key    string
+	Base   *Section
+	Ours   *Section
+	Theirs *Section
+}
+
+// Part is one piece of a merged document, which is either a section or a conflict that still has to be resolved
+type Part struct {
+	Section  *Section
+	Conflict *Conflict
+}
+
+// Choice is how a conflict is resolved
+type Choice int
+
+const (
+	// KeepOurs uses our side of the conflict
+	KeepOurs Choice = iota
+	// KeepTheirs uses their side of the conflict
+	KeepTheirs
+	// KeepBoth uses our side followed by their side
+	KeepBoth
+)
+
+// Split cuts blocks into sections, using key to get the key of blocks that start a section
+// key returns false for blocks that don't start a section. Sections with the same key are numbered so keys are unique
+func Split(blocks []string, key func(block string) (string, bool)) []Section {
+	sections := []Section{{Key: ""}}
+	seen := make(map[string]int)
+	for _, block := range blocks {
+		k, ok := key(block)
+		if !ok {
+			last := &sections[len(sections)-1]
+			last.Blocks = append(last.Blocks, block)
+			continue
+		}
+		seen[k]++
+		if seen[k] > 1 {
+			k = fmt.Sprintf("%s#%v", k, seen[k])
+		}
+		sections = append(sections, Section{Key: k, Blocks: []string{block}})
+	}
+	return sections
+}
+
+// Merge combines the changes made by ours and theirs since base
+// Sections changed by only one side take that change, and sections changed by both in different ways become conflicts
+// The result follows the order of ours, with sections that only theirs added placed after the section they follow in theirs
+func Merge(base []Section, ours []Section, theirs []Section) []Part {
+	baseByKey := byKey(base)
+	oursByKey := byKey(ours)
+	theirsByKey := byKey(theirs)
+
+	parts := []Part{}
+	for _, key := range order(ours, theirs) {
+		b, inBase := baseByKey[key]
+		o, inOurs := oursByKey[key]
+		t, inTheirs := theirsByKey[key]
+
+		switch {
+		case inOurs && inTheirs:
+			switch {
+			case same(o, t) || same(t, b):
+				parts = append(parts, Part{Section: o})
+			case same(o, b):
+				parts = append(parts, Part{Section: t})
+			default:
+				parts = append(parts, conflict(key, b, o, t))
+			}
+		case inOurs:
+			if !inBase {
+				// We added it
+				parts = append(parts, Part{Section: o})
+			} else if !same(o, b) {
+				// They deleted what we changed
+				parts = append(parts, conflict(key, b, o, nil))
+			}
+		case inTheirs:
+			if !inBase {
+				parts = append(parts, Part{Section: t})
+			} else if !same(t, b) {
+				parts = append(parts, conflict(key, b, nil, t))
+			}
+		}
+	}
+	return parts
+}
+
+// Conflicts returns every conflict in the merged document
+func Conflicts(parts []Part) []*Conflict {
+	conflicts := []*Conflict{}
+	for _, part := range parts {
+		if part.Conflict != nil {
+			conflicts = append(conflicts, part.Conflict)
+		}
+	}
+	return conflicts
+}
+
+// Resolve turns the merged document into blocks, resolving each conflict with the choice made for its key
+// Conflicts without a choice keep our side
+func Resolve(parts []Part, choices map[string]Choice) []string {
+	blocks := []string{}
+	for _, part := range parts {
+		if part.Section != nil {
+			blocks = append(blocks, part.Section.Blocks...)
+			continue
+		}
+		conf := part.Conflict
+		choice := choices[conf.Key]
+		if (choice == KeepOurs || choice == KeepBoth) && conf.Ours != nil {
+			blocks = append(blocks, conf.Ours.Blocks...)
+		}
+		if (choice == KeepTheirs || choice == KeepBoth) && conf.Theirs != nil {
+			blocks = append(blocks, conf.Theirs.Blocks...)
+		}
+	}
+	return blocks
+}
+
+func conflict(key string, base *Section, ours *Section, theirs *Section) Part {
+	return Part{Conflict: &Conflict{Key: key, Base: base, Ours: ours, Theirs: theirs}}
+}
+
+// order returns every key in ours and theirs, in the order of ours with keys only in theirs placed after their predecessor
+func order(ours []Section, theirs []Section) []string {
+	keys := []string{}
+	inOurs := make(map[string]bool)
+	for _, section := range ours {
+		keys = append(keys, section.Key)
+		inOurs[section.Key] = true
+	}
+
+	after := ""
+	for _, section := range theirs {
+		if inOurs[section.Key] {
+			after = section.Key
+			continue
+		}
+		keys = insertAfter(keys, after, section.Key)
+		inOurs[section.Key] = true
+		after = section.Key
+	}
+	return keys
+}
+
+func insertAfter(keys []string, after string, key string) []string {
+	for i, k := range keys {
+		if k == after {
+			keys = append(keys, "")
+			copy(keys[i+2:], keys[i+1:])
+			keys[i+1] = key
+			return keys
+		}
+	}
+	return append(keys, key)
+}
+
+func byKey(sections []Section) map[string]*Section {
+	result := make(map[string]*Section)
+	for i := range sections {
+		result[sections[i].Key] = &sections[i]
+	}
+	return result
+}
+
+// same returns true if both sections have the same contents, where a missing section only matches another missing one
+func same(one *Section, two *Section) bool {
+	if one == nil || two == nil {
+		return one == two
+	}
+	return strings.Join(one.Blocks, "") == strings.Join(two.Blocks, "")
+}
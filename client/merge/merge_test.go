@@ -0,0 +1,104 @@
+package merge
+
+import (
+	"strings"
+	"testing"
+)
+
+// headingKey treats blocks starting with # as headings, keyed by their text
+func headingKey(block string) (string, bool) {
+	if !strings.HasPrefix(block, "#") {
+		return "", false
+	}
+	return block, true
+}
+
+// sections splits a document written one block per line
+func sections(doc string) []Section {
+	blocks := []string{}
+	if doc != "" {
+		blocks = strings.Split(doc, "\n")
+	}
+	return Split(blocks, headingKey)
+}
+
+func TestSplit(t *testing.T) {
+	split := sections("intro\n#A\none\n#B\n#A\ntwo")
+	want := []Section{
+		{Key: "", Blocks: []string{"intro"}},
+		{Key: "#A", Blocks: []string{"#A", "one"}},
+		{Key: "#B", Blocks: []string{"#B"}},
+		{Key: "#A#2", Blocks: []string{"#A", "two"}},
+	}
+	if len(split) != len(want) {
+		t.Fatalf("got %v sections, want %v", len(split), len(want))
+	}
+	for i := range want {
+		if split[i].Key != want[i].Key || strings.Join(split[i].Blocks, "\n") != strings.Join(want[i].Blocks, "\n") {
+			t.Errorf("section %v is %+v, want %+v", i, split[i], want[i])
+		}
+	}
+}
+
+func TestMerge(t *testing.T) {
+	base := "#A\na\n#B\nb\n#C\nc"
+	tests := []struct {
+		name      string
+		ours      string
+		theirs    string
+		conflicts []string
+		want      string // With every conflict resolved by keeping both sides
+	}{
+		{"nothing changed", base, base, nil, base},
+		{"only ours changed", "#A\na1\n#B\nb\n#C\nc", base, nil, "#A\na1\n#B\nb\n#C\nc"},
+		{"only theirs changed", base, "#A\na\n#B\nb2\n#C\nc", nil, "#A\na\n#B\nb2\n#C\nc"},
+		{"different sections changed", "#A\na1\n#B\nb\n#C\nc", "#A\na\n#B\nb\n#C\nc2", nil, "#A\na1\n#B\nb\n#C\nc2"},
+		{"same change on both sides", "#A\na1\n#B\nb\n#C\nc", "#A\na1\n#B\nb\n#C\nc", nil, "#A\na1\n#B\nb\n#C\nc"},
+		{"both changed a section", "#A\na1\n#B\nb\n#C\nc", "#A\na2\n#B\nb\n#C\nc", []string{"#A"}, "#A\na1\n#A\na2\n#B\nb\n#C\nc"},
+		{"we deleted what they changed", "#A\na\n#C\nc", "#A\na\n#B\nb2\n#C\nc", []string{"#B"}, "#A\na\n#B\nb2\n#C\nc"},
+		{"they deleted what we changed", "#A\na\n#B\nb1\n#C\nc", "#A\na\n#C\nc", []string{"#B"}, "#A\na\n#B\nb1\n#C\nc"},
+		{"they deleted what we left alone", base, "#A\na\n#C\nc", nil, "#A\na\n#C\nc"},
+		{"both deleted a section", "#A\na\n#C\nc", "#A\na\n#C\nc", nil, "#A\na\n#C\nc"},
+		{"they added a section", base, "#A\na\n#D\nd\n#B\nb\n#C\nc", nil, "#A\na\n#D\nd\n#B\nb\n#C\nc"},
+		{"both added the same heading differently", "#A\na\n#B\nb\n#C\nc\n#D\nd1", "#A\na\n#B\nb\n#C\nc\n#D\nd2", []string{"#D"}, "#A\na\n#B\nb\n#C\nc\n#D\nd1\n#D\nd2"},
+		{"text before the first heading", "intro\n" + base, base, nil, "intro\n" + base},
+	}
+	for _, test := range tests {
+		parts := Merge(sections(base), sections(test.ours), sections(test.theirs))
+		conflicts := Conflicts(parts)
+		keys := []string{}
+		choices := make(map[string]Choice)
+		for _, conf := range conflicts {
+			keys = append(keys, conf.Key)
+			choices[conf.Key] = KeepBoth
+		}
+		if strings.Join(keys, ",") != strings.Join(test.conflicts, ",") {
+			t.Errorf("%s: conflicts in %v, want %v", test.name, keys, test.conflicts)
+		}
+		if got := strings.Join(Resolve(parts, choices), "\n"); got != test.want {
+			t.Errorf("%s: merged into %q, want %q", test.name, got, test.want)
+		}
+	}
+}
+
+func TestResolve(t *testing.T) {
+	base := sections("#A\na\n#B\nb")
+	ours := sections("#A\na1\n#B\nb1")
+	theirs := sections("#A\na2")
+	parts := Merge(base, ours, theirs)
+	tests := []struct {
+		name    string
+		choices map[string]Choice
+		want    string
+	}{
+		{"no choices keeps ours", map[string]Choice{}, "#A\na1\n#B\nb1"},
+		{"keeping theirs", map[string]Choice{"#A": KeepTheirs, "#B": KeepTheirs}, "#A\na2"},
+		{"keeping both", map[string]Choice{"#A": KeepBoth, "#B": KeepBoth}, "#A\na1\n#A\na2\n#B\nb1"},
+		{"mixed", map[string]Choice{"#A": KeepTheirs, "#B": KeepOurs}, "#A\na2\n#B\nb1"},
+	}
+	for _, test := range tests {
+		if got := strings.Join(Resolve(parts, test.choices), "\n"); got != test.want {
+			t.Errorf("%s: got %q, want %q", test.name, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package pdfjs
+
+import (
+	"errors"
+	"math"
+	"sort"
+	"strings"
+	"syscall/js"
+
+	"gitlab.com/256/DebateFrame/client/waiter"
+)
+
+// line is a run of text on one line of a page
+type line struct {
+	text   string
+	y      float64 // The distance from the bottom of the page
+	height float64
+}
+
+// Paragraphs reads the text layer of a PDF and joins its lines into paragraphs
+// Scanned PDFs have no text layer, so they give no paragraphs
+func Paragraphs(data []byte) ([]string, error) {
+	lib := js.Global().Get("pdfjsLib")
+	if lib == js.Undefined() {
+		return nil, errors.New("PDF.js isn't loaded")
+	}
+	array := js.TypedArrayOf(data)
+	// PDF.js keeps the data after getDocument returns, so it gets a copy rather than a view of Go memory
+	copied := js.Global().Get("Uint8Array").New(array)
+	array.Release()
+
+	doc, err := waiter.Await(lib.Call("getDocument", map[string]interface{}{"data": copied}).Get("promise"))
+	if err != nil {
+		return nil, err
+	}
+	paragraphs := []string{}
+	pages := doc.Get("numPages").Int()
+	for i := 1; i <= pages; i++ {
+		page, err := waiter.Await(doc.Call("getPage", i))
+		if err != nil {
+			return nil, err
+		}
+		content, err := waiter.Await(page.Call("getTextContent"))
+		if err != nil {
+			return nil, err
+		}
+		paragraphs = append(paragraphs, joinLines(pageLines(content.Get("items")))...)
+	}
+	return paragraphs, nil
+}
+
+// pageLines groups the text items of a page into lines, from the top of the page down
+func pageLines(items js.Value) []line {
+	lines := []line{}
+	for i := 0; i < items.Length(); i++ {
+		item := items.Index(i)
+		transform := item.Get("transform")
+		y := transform.Index(5).Float()
+		height := item.Get("height").Float()
+		text := item.Get("str").String()
+		if n := len(lines); n > 0 && math.Abs(lines[n-1].y-y) < math.Max(height, 1)/2 {
+			lines[n-1].text += text
+			continue
+		}
+		lines = append(lines, line{text: text, y: y, height: height})
+	}
+	sort.SliceStable(lines, func(a, b int) bool {
+		return lines[a].y > lines[b].y
+	})
+	return lines
+}
+
+// joinLines joins lines into paragraphs, starting a new one where the gap between lines is bigger than usual
+func joinLines(lines []line) []string {
+	gaps := []float64{}
+	for i := 1; i < len(lines); i++ {
+		gaps = append(gaps, lines[i-1].y-lines[i].y)
+	}
+	usual := 0.0
+	if len(gaps) > 0 {
+		sorted := append([]float64{}, gaps...)
+		sort.Float64s(sorted)
+		usual = sorted[len(sorted)/2]
+	}
+
+	paragraphs := []string{}
+	current := []string{}
+	flush := func() {
+		if text := strings.Join(strings.Fields(strings.Join(current, " ")), " "); text != "" {
+			paragraphs = append(paragraphs, text)
+		}
+		current = current[:0]
+	}
+	for i, ln := range lines {
+		if i > 0 && gaps[i-1] > usual*1.5 {
+			flush()
+		}
+		current = append(current, ln.text)
+	}
+	flush()
+	return paragraphs
+}
@@ -0,0 +1,172 @@
+package sections
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Card is what a card of a section is compared by to tell whether the cards of a document changed
+// It has the fields of the document package's InfoCard in the same order, so either converts to the other
+type Card struct {
+	Title      string
+	Contents   string
+	URL        string
+	Year       uint8
+	Author     string
+	Level      uint8
+	Heading    int // Which heading of the level holds the tag, counting headings nested in other elements, from 0
+	Highlights []string
+}
+
+// Parse reads the cards from HTML whose tags are headings of the level
+type Parse func(html string, level uint8) []*Card
+
+// Cache remembers the card read from each section, so only the sections that were edited are read again
+// A section is a tag and the blocks up to the next tag, which is what a card is read from
+type Cache struct {
+	level    uint8
+	sections map[string]*Card // By the HTML of the section, nil if the section isn't a card
+}
+
+var (
+	// levelRp finds the heading level of a block that is a heading
+	levelRp = regexp.MustCompile(`(?i)^<h([1-6])[\s>]`)
+	// headingTagRp finds the opening tag of every heading in a block, including headings nested in other elements
+	// Blocks are written out with < escaped in text and attributes, so only comments can hold a tag that isn't one
+	headingTagRp = regexp.MustCompile(`(?i)<h([1-6])[\s/>]`)
+	commentRp    = regexp.MustCompile(`<!--[\s\S]*?-->`)
+)
+
+// Level returns the heading level of the block, or 0 if it isn't a heading
+func Level(block string) uint8 {
+	match := levelRp.FindStringSubmatch(block)
+	if len(match) < 2 {
+		return 0
+	}
+	level, _ := strconv.Atoi(match[1])
+	return uint8(level)
+}
+
+// Headings returns the level of every heading in the block, in document order
+func Headings(block string) []uint8 {
+	levels := []uint8{}
+	for _, match := range headingTagRp.FindAllStringSubmatch(commentRp.ReplaceAllString(block, ""), -1) {
+		level, _ := strconv.Atoi(match[1])
+		levels = append(levels, uint8(level))
+	}
+	return levels
+}
+
+// TagLevel returns the heading level that tags use, which is the most common of the levels
+// Ties go to the biggest heading, including when every level is used once, and it returns false if there are no levels
+func TagLevel(levels []uint8) (uint8, bool) {
+	counts := make(map[uint8]int)
+	best := uint8(0)
+	for _, level := range levels {
+		counts[level]++
+		if best == 0 || counts[level] > counts[best] || (counts[level] == counts[best] && level < best) {
+			best = level
+		}
+	}
+	return best, best != 0
+}
+
+// BlocksLevel returns the heading level that tags use in the blocks, counting headings nested in other elements
+func BlocksLevel(blocks []string) (uint8, bool) {
+	levels := []uint8{}
+	for _, block := range blocks {
+		levels = append(levels, Headings(block)...)
+	}
+	return TagLevel(levels)
+}
+
+// nestedTag returns whether the block holds a heading of the level inside another element
+func nestedTag(block string, level uint8) bool {
+	count := 0
+	for _, found := range Headings(block) {
+		if found == level {
+			count++
+		}
+	}
+	if Level(block) == level {
+		count--
+	}
+	return count > 0
+}
+
+// Cards returns the cards of the blocks, reading only the sections it hasn't seen before
+func (cache *Cache) Cards(blocks []string, parse Parse) []*Card {
+	level, ok := BlocksLevel(blocks)
+	if !ok {
+		cache.sections = nil
+		return []*Card{}
+	}
+	if level != cache.level {
+		cache.level = level
+		cache.sections = nil
+	}
+	for _, block := range blocks {
+		if nestedTag(block, level) {
+			// A section could start partway through the block, so the blocks can't be split into sections
+			cache.sections = nil
+			return parse(strings.Join(blocks, ""), level)
+		}
+	}
+	seen := make(map[string]*Card)
+	cards := []*Card{}
+	heading := 0
+	for start := 0; start < len(blocks); start++ {
+		if Level(blocks[start]) != level {
+			continue
+		}
+		end := start + 1
+		for end < len(blocks) && Level(blocks[end]) != level {
+			end++
+		}
+		section := strings.Join(blocks[start:end], "")
+		parsed, ok := cache.sections[section]
+		if !ok {
+			parsed = parseSection(section, level, parse)
+		}
+		seen[section] = parsed
+		if parsed != nil {
+			found := *parsed
+			found.Heading = heading
+			cards = append(cards, &found)
+		}
+		heading++
+	}
+	// Sections that are gone are forgotten, so the cache doesn't grow with every keystroke
+	cache.sections = seen
+	return cards
+}
+
+// parseSection returns the card in a section, or nil if it isn't one
+func parseSection(section string, level uint8, parse Parse) *Card {
+	cards := parse(section, level)
+	if len(cards) != 1 {
+		return nil
+	}
+	return cards[0]
+}
+
+// Same returns whether two lists of cards are the same, so the card view isn't redrawn for typing that doesn't change them
+func Same(one []*Card, two []*Card) bool {
+	if len(one) != len(two) {
+		return false
+	}
+	for i := range one {
+		a, b := one[i], two[i]
+		if a.Title != b.Title || a.Contents != b.Contents || a.URL != b.URL || a.Year != b.Year || a.Author != b.Author ||
+			a.Level != b.Level || a.Heading != b.Heading || len(a.Highlights) != len(b.Highlights) {
+			return false
+		}
+		for j := range a.Highlights {
+			if a.Highlights[j] != b.Highlights[j] {
+				return false
+			}
+		}
+	}
+	return true
+}
@@ -0,0 +1,238 @@
+package sections
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// testCard returns the HTML of a card with its tag at the level
+func testCard(level int, tag string) string {
+	return fmt.Sprintf("<h%v>%s</h%v><p>Smith 19 [Jane Smith, Professor]</p><p>Text about %s, <mark>highlighted</mark></p>", level, tag, level, tag)
+}
+
+// testParser reads a card from every heading of the level, counting headings nested in other elements like GetCards does
+// A tag starting with "No cite" isn't a card, like a tag GetCards drops for having no cite
+type testParser struct {
+	parsed []string // The HTML of everything that was parsed
+}
+
+func (parser *testParser) parse(html string, level uint8) []*Card {
+	parser.parsed = append(parser.parsed, html)
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(html))
+	if err != nil {
+		return []*Card{}
+	}
+	cards := []*Card{}
+	doc.Find(fmt.Sprintf("h%v", level)).Each(func(i int, sel *goquery.Selection) {
+		if strings.HasPrefix(sel.Text(), "No cite") {
+			return
+		}
+		highlights := []string{}
+		sel.NextUntil(fmt.Sprintf("h%v", level)).Find("mark").Each(func(_ int, mark *goquery.Selection) {
+			highlights = append(highlights, mark.Text())
+		})
+		cards = append(cards, &Card{Title: sel.Text(), Contents: sel.NextUntil(fmt.Sprintf("h%v", level)).Text(), Level: level, Heading: i, Highlights: highlights})
+	})
+	return cards
+}
+
+// testBlocks splits HTML into blocks the way the document package does
+func testBlocks(t *testing.T, html string) []string {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(html))
+	if err != nil {
+		t.Fatal(err)
+	}
+	blocks := []string{}
+	doc.Find("body").Contents().Each(func(_ int, sel *goquery.Selection) {
+		block, err := goquery.OuterHtml(sel)
+		if err != nil {
+			t.Fatal(err)
+		}
+		blocks = append(blocks, block)
+	})
+	return blocks
+}
+
+// wantCards returns the cards parsing the whole document finds
+func wantCards(t *testing.T, html string) []*Card {
+	blocks := testBlocks(t, html)
+	level, ok := BlocksLevel(blocks)
+	if !ok {
+		return []*Card{}
+	}
+	parser := testParser{}
+	return parser.parse(strings.Join(blocks, ""), level)
+}
+
+func describeCards(cards []*Card) string {
+	parts := []string{}
+	for _, c := range cards {
+		parts = append(parts, fmt.Sprintf("h%v #%v %q", c.Level, c.Heading, c.Title))
+	}
+	return "[" + strings.Join(parts, ", ") + "]"
+}
+
+var cacheTests = []struct {
+	name  string
+	html  string
+	level uint8
+	cards int
+}{
+	{"no headings", "<p>Just text</p>", 0, 0},
+	{"top level tags", "<h1>Aff</h1><h2>Advantage</h2>" + testCard(4, "One") + testCard(4, "Two") + testCard(4, "Three"), 4, 3},
+	{"tags that aren't cards", testCard(4, "One") + "<h4>No cite</h4><p>Text</p>" + testCard(4, "Three"), 4, 2},
+	{"nested tags", "<div>" + testCard(4, "One") + "</div><div>" + testCard(4, "Two") + "</div>" + testCard(4, "Three"), 4, 3},
+	{"nested tag after a top level one", testCard(4, "One") + "<blockquote><p>Quote</p>" + testCard(4, "Two") + "</blockquote>", 4, 2},
+	{"nested headings change the level", testCard(3, "One") + testCard(3, "Two") + "<div><h4>A</h4><h4>B</h4><h4>C</h4></div>", 4, 3},
+	{"ties go to the bigger heading", testCard(3, "One") + testCard(3, "Two") + testCard(4, "Three") + testCard(4, "Four"), 3, 2},
+	{"every level used once", "<h1>Aff</h1>" + testCard(2, "One"), 1, 1},
+	{"headings in comments don't count", "<!-- <h3>Old</h3><h3>Old</h3> -->" + testCard(4, "One") + testCard(4, "Two"), 4, 2},
+	{"headings with attributes", `<h4 id="a" class="tag">One</h4><p>Smith 19 [Jane Smith]</p><H4 id="b">Two</H4><p>Smith 20 [Jane Smith]</p>`, 4, 2},
+}
+
+func TestBlocksLevel(t *testing.T) {
+	for _, test := range cacheTests {
+		level, ok := BlocksLevel(testBlocks(t, test.html))
+		if level != test.level || ok != (test.level != 0) {
+			t.Errorf("%s: BlocksLevel returned %v, %v, want %v", test.name, level, ok, test.level)
+		}
+	}
+}
+
+func TestLevel(t *testing.T) {
+	tests := []struct {
+		block string
+		level uint8
+	}{
+		{"<h1>Aff</h1>", 1},
+		{`<H4 id="a">Tag</H4>`, 4},
+		{"<p>Text</p>", 0},
+		{"<div><h4>Nested</h4></div>", 0},
+		{"<header>Not a heading</header>", 0},
+		{"<hr/>", 0},
+		{"Text", 0},
+	}
+	for _, test := range tests {
+		if level := Level(test.block); level != test.level {
+			t.Errorf("Level(%q) = %v, want %v", test.block, level, test.level)
+		}
+	}
+}
+
+func TestHeadings(t *testing.T) {
+	tests := []struct {
+		block  string
+		levels []uint8
+	}{
+		{"<p>Text</p>", []uint8{}},
+		{"<h4>Tag</h4>", []uint8{4}},
+		{"<div><h2>A</h2><p>Text</p><h4>B</h4></div>", []uint8{2, 4}},
+		{"<div><!-- <h3>Old</h3> --><h4>B</h4></div>", []uint8{4}},
+		{"<p>&lt;h4&gt; isn&#39;t a tag</p>", []uint8{}},
+		{"<div><header>Not a heading</header><hr/></div>", []uint8{}},
+	}
+	for _, test := range tests {
+		levels := Headings(test.block)
+		if fmt.Sprint(levels) != fmt.Sprint(test.levels) {
+			t.Errorf("Headings(%q) = %v, want %v", test.block, levels, test.levels)
+		}
+	}
+}
+
+func TestCache(t *testing.T) {
+	for _, test := range cacheTests {
+		want := wantCards(t, test.html)
+		if len(want) != test.cards {
+			t.Errorf("%s: parsing the whole document found %v cards, want %v", test.name, len(want), test.cards)
+		}
+		cache := Cache{}
+		parser := testParser{}
+		got := cache.Cards(testBlocks(t, test.html), parser.parse)
+		if !Same(got, want) {
+			t.Errorf("%s: got %s, parsing the whole document found %s", test.name, describeCards(got), describeCards(want))
+		}
+	}
+}
+
+// TestCacheEdits reuses one cache as the document is edited, like typing does
+func TestCacheEdits(t *testing.T) {
+	edits := []struct {
+		name   string
+		html   string
+		parsed int // How many times the edit has to parse, so only sections that changed are parsed again
+	}{
+		{"start", "<h2>Advantage</h2>" + testCard(4, "One") + testCard(4, "Two") + testCard(4, "Three"), 3},
+		{"typing in a card", "<h2>Advantage</h2>" + testCard(4, "One") + testCard(4, "Two edited") + testCard(4, "Three"), 1},
+		{"adding a card before the rest", "<h2>Advantage</h2>" + testCard(4, "Zero") + testCard(4, "One") + testCard(4, "Two edited") + testCard(4, "Three"), 1},
+		{"deleting a card", "<h2>Advantage</h2>" + testCard(4, "Zero") + testCard(4, "Two edited") + testCard(4, "Three"), 0},
+		{"breaking a cite", "<h2>Advantage</h2>" + testCard(4, "Zero") + "<h4>No cite</h4><p>Text</p>" + testCard(4, "Three"), 1},
+		{"wrapping a card", "<h2>Advantage</h2><div>" + testCard(4, "Zero") + "</div>" + testCard(4, "Three"), 1},
+		{"unwrapping it again", "<h2>Advantage</h2>" + testCard(4, "Zero") + testCard(4, "Three"), 2},
+		{"typing outside the cards", "<h2>Advantage edited</h2>" + testCard(4, "Zero") + testCard(4, "Three"), 0},
+		{"changing the tag level", "<h2>Advantage</h2>" + testCard(3, "Zero") + testCard(3, "Three") + testCard(3, "Four"), 3},
+		{"removing every heading", "<p>Nothing</p>", 0},
+		{"adding them back", testCard(4, "One") + testCard(4, "Two"), 2},
+	}
+	cache := Cache{}
+	for _, edit := range edits {
+		want := wantCards(t, edit.html)
+		parser := testParser{}
+		got := cache.Cards(testBlocks(t, edit.html), parser.parse)
+		if !Same(got, want) {
+			t.Errorf("after %s: got %s, parsing the whole document found %s", edit.name, describeCards(got), describeCards(want))
+		}
+		if len(parser.parsed) != edit.parsed {
+			t.Errorf("after %s: parsed %v times, want %v", edit.name, len(parser.parsed), edit.parsed)
+		}
+	}
+}
+
+func TestTagLevel(t *testing.T) {
+	tests := []struct {
+		levels []uint8
+		level  uint8
+	}{
+		{[]uint8{}, 0},
+		{[]uint8{4, 4, 3}, 4},
+		{[]uint8{3, 4, 4, 3}, 3},
+		{[]uint8{1, 2, 3}, 1},
+		{[]uint8{1, 2, 4, 4, 4, 2}, 4},
+	}
+	for _, test := range tests {
+		level, ok := TagLevel(test.levels)
+		if level != test.level || ok != (test.level != 0) {
+			t.Errorf("TagLevel(%v) = %v, %v, want %v", test.levels, level, ok, test.level)
+		}
+	}
+}
+
+func TestSame(t *testing.T) {
+	base := func() []*Card {
+		return []*Card{
+			{Title: "One", Contents: "Text", Author: "Smith", Year: 19, Level: 4, Heading: 0, Highlights: []string{"a", "b"}},
+			{Title: "Two", Contents: "Text", Author: "Jones", Year: 20, Level: 4, Heading: 1},
+		}
+	}
+	tests := []struct {
+		name   string
+		change func(cards []*Card) []*Card
+		same   bool
+	}{
+		{"unchanged", func(cards []*Card) []*Card { return cards }, true},
+		{"tag", func(cards []*Card) []*Card { cards[0].Title = "Changed"; return cards }, false},
+		{"contents", func(cards []*Card) []*Card { cards[1].Contents = "Changed"; return cards }, false},
+		{"year", func(cards []*Card) []*Card { cards[1].Year = 21; return cards }, false},
+		{"heading", func(cards []*Card) []*Card { cards[1].Heading = 2; return cards }, false},
+		{"highlights", func(cards []*Card) []*Card { cards[0].Highlights = []string{"a\nb"}; return cards }, false},
+		{"card removed", func(cards []*Card) []*Card { return cards[:1] }, false},
+		{"no cards", func(cards []*Card) []*Card { return []*Card{} }, false},
+	}
+	for _, test := range tests {
+		if same := Same(base(), test.change(base())); same != test.same {
+			t.Errorf("%s: Same returned %v", test.name, same)
+		}
+	}
+}
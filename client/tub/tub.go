@@ -0,0 +1,162 @@
+package tub
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+	"syscall/js"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"gitlab.com/256/DebateFrame/client/waiter"
+)
+
+// ErrLogin is returned when the server needs the user to log in first
+var ErrLogin = errors.New("log in to the server first")
+
+// Meta describes a case in the team's tub, matching the case info sent by the server
+type Meta struct {
+	ID       string
+	Name     string
+	Uploader string
+	Updated  time.Time
+	Size     int64
+	Owner    string
+	ACL      map[string]string // Who else can access the case, like "team:varsity": "editor"
+	Role     string            // What the logged in user can do with the case: reader, editor or owner
+}
+
+// Client talks to the case API of a DebateFrame server
+type Client struct {
+	Server string // Address of the server, like http://192.168.1.5:8080
+	Token  string // Sent with every request once logged in
+}
+
+// Login logs in to the server, keeping the token in the client
+func (cl *Client) Login(name string, password string) error {
+	creds, err := json.Marshal(map[string]string{"Name": name, "Password": password})
+	if err != nil {
+		return errors.Wrap(err, "failed to convert the login to json")
+	}
+	body, err := cl.request("POST", "/api/login", creds)
+	if err != nil {
+		return errors.Wrap(err, "failed to log in")
+	}
+	login := struct{ Token string }{}
+	err = json.Unmarshal(body, &login)
+	if err != nil {
+		return errors.Wrap(err, "the server sent a broken login")
+	}
+	cl.Token = login.Token
+	return nil
+}
+
+// Logout ends the session on the server
+func (cl *Client) Logout() error {
+	_, err := cl.request("POST", "/api/logout", nil)
+	cl.Token = ""
+	return err
+}
+
+// SetACL replaces who can access a case, which only its owner can do
+func (cl *Client) SetACL(id string, acl map[string]string) error {
+	body, err := json.Marshal(acl)
+	if err != nil {
+		return errors.Wrap(err, "failed to convert the access list to json")
+	}
+	_, err = cl.request("PUT", caseURL(id)+"/acl", body)
+	if err != nil {
+		return errors.Wrap(err, "failed to change who can access the case")
+	}
+	return nil
+}
+
+// List returns every case in the tub, most recently updated first
+func (cl *Client) List() ([]Meta, error) {
+	body, err := cl.request("GET", "/api/cases", nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list the team's cases")
+	}
+	metas := []Meta{}
+	err = json.Unmarshal(body, &metas)
+	if err != nil {
+		return nil, errors.Wrap(err, "the server sent a broken list of cases")
+	}
+	return metas, nil
+}
+
+// Download returns the case file of a case in the tub
+func (cl *Client) Download(id string) ([]byte, error) {
+	body, err := cl.request("GET", caseURL(id), nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to download the case")
+	}
+	return body, nil
+}
+
+// Upload puts a case file in the tub, replacing the case with the same ID
+func (cl *Client) Upload(id string, name string, uploader string, data []byte) error {
+	query := url.Values{}
+	query.Set("name", name)
+	query.Set("uploader", uploader)
+	_, err := cl.request("PUT", caseURL(id)+"?"+query.Encode(), data)
+	if err != nil {
+		return errors.Wrap(err, "failed to upload the case")
+	}
+	return nil
+}
+
+// Delete removes a case from the tub
+func (cl *Client) Delete(id string) error {
+	_, err := cl.request("DELETE", caseURL(id), nil)
+	if err != nil {
+		return errors.Wrap(err, "failed to delete the case")
+	}
+	return nil
+}
+
+func caseURL(id string) string {
+	return "/api/cases/" + url.PathEscape(id)
+}
+
+// request fetches a path on the server and returns the response body, treating any status but 2xx as an error
+func (cl *Client) request(method string, path string, body []byte) ([]byte, error) {
+	options := map[string]interface{}{"method": method}
+	if cl.Token != "" {
+		options["headers"] = map[string]interface{}{"Authorization": "Bearer " + cl.Token}
+	}
+	if body != nil {
+		array := js.TypedArrayOf(body)
+		defer array.Release()
+		// Copy out of Go memory, which can move while the request is in flight
+		options["body"] = js.Global().Get("Uint8Array").New(array)
+	}
+
+	resp, err := waiter.Await(js.Global().Call("fetch", strings.TrimRight(cl.Server, "/")+path, options))
+	if err != nil {
+		return nil, fmt.Errorf("could not reach %s", cl.Server)
+	}
+	buffer, err := waiter.Await(resp.Call("arrayBuffer"))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read the response")
+	}
+	data := bytesOf(buffer)
+	if resp.Get("status").Int() == 401 && !strings.HasSuffix(path, "/login") {
+		return nil, ErrLogin
+	}
+	if !resp.Get("ok").Bool() {
+		return nil, fmt.Errorf("the server said: %s", strings.TrimSpace(string(data)))
+	}
+	return data, nil
+}
+
+// bytesOf copies an ArrayBuffer into Go memory
+func bytesOf(buffer js.Value) []byte {
+	data := make([]byte, buffer.Get("byteLength").Int())
+	array := js.TypedArrayOf(data)
+	defer array.Release()
+	array.Call("set", js.Global().Get("Uint8Array").New(buffer))
+	return data
+}
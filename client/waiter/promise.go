@@ -0,0 +1,24 @@
+package waiter
+
+import (
+	"errors"
+	"syscall/js"
+)
+
+// Await blocks until the promise settles, so it must never be called from a callback
+func Await(promise js.Value) (js.Value, error) {
+	var result js.Value
+	done := make(chan error, 1)
+	onResult := js.NewCallback(func(args []js.Value) {
+		result = args[0]
+		done <- nil
+	})
+	onError := js.NewCallback(func(args []js.Value) {
+		done <- errors.New(args[0].Call("toString").String())
+	})
+	defer onResult.Release()
+	defer onError.Release()
+	promise.Call("then", onResult, onError)
+	err := <-done
+	return result, err
+}
@@ -1,22 +1,31 @@
 package waiter
 
 import (
+	"syscall/js"
 	"time"
 
 	"github.com/dennwc/dom"
 )
 
 // EventWaiter runs a function once an event stops triggering for the amount of milliseconds specified in tTime
-func EventWaiter(node *dom.NodeBase, event string, tTime int, fn func()) {
+// The returned function stops listening for the event, and must only be called once
+func EventWaiter(node *dom.NodeBase, event string, tTime int, fn func()) func() {
 	sleepTime := tTime / 3
 	timeout := 0
 	happened := false
-	node.AddEventListener(event, func(e dom.Event) {
+	stopped := make(chan struct{})
+	listener := js.NewCallback(func(args []js.Value) {
 		happened = true
 		timeout = 3
 	})
+	node.JSValue().Call("addEventListener", event, listener)
 	go func() {
 		for {
+			select {
+			case <-stopped:
+				return
+			default:
+			}
 			if timeout > 0 {
 				timeout--
 			} else if timeout <= 0 && happened {
@@ -27,4 +36,9 @@ func EventWaiter(node *dom.NodeBase, event string, tTime int, fn func()) {
 			time.Sleep(time.Duration(int(time.Millisecond) * sleepTime))
 		}
 	}()
+	return func() {
+		node.JSValue().Call("removeEventListener", event, listener)
+		listener.Release()
+		close(stopped)
+	}
 }
@@ -16,7 +16,9 @@ import (
 func Start(container *dyndom.Element) config.Configuration {
 	welcome(container)
 	preferences(container)
-	return config.Configuration{FinishedWizard: true}
+	conf := config.Default()
+	conf.FinishedWizard = true
+	return conf
 }
 
 func welcome(container *dyndom.Element) {
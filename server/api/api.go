@@ -0,0 +1,362 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"strings"
+
+	"gitlab.com/256/DebateFrame/server/auth"
+	"gitlab.com/256/DebateFrame/server/store"
+)
+
+// maxCaseSize is the largest case file that can be uploaded, in bytes
+const maxCaseSize = 64 << 20
+
+// caseType is the content type of case files, the same one the client downloads them as
+const caseType = "application/vnd.dframe-case"
+
+// errDenied stops an upload the user isn't allowed to make, and is never shown to them
+var errDenied = errors.New("not allowed to upload the case")
+
+// API serves the team's cases over HTTP
+// Every request but logging in needs an Authorization: Bearer <token> header, unless the server has no accounts
+//
+//	POST   /api/login           logs in with {Name, Password}, returning {Token, Name, Teams}
+//	POST   /api/logout          ends the session of the token
+//	GET    /api/cases           lists every case the user can read, with the user's Role for each
+//	GET    /api/cases/{id}      downloads a case file
+//	PUT    /api/cases/{id}      uploads a case file, with the name in the query
+//	DELETE /api/cases/{id}      deletes a case
+//	GET    /api/cases/{id}/acl  returns who can access a case
+//	PUT    /api/cases/{id}/acl  replaces who can access a case, like {"team:varsity": "editor", "user:coach": "owner"}
+type API struct {
+	store    *store.Disk
+	accounts *auth.Accounts
+}
+
+// caseInfo is a case as it is listed to a user
+type caseInfo struct {
+	store.Meta
+	Role auth.Role // What the user asking can do with the case
+}
+
+// New creates an API for the cases in the store, limited by the accounts
+func New(st *store.Disk, accounts *auth.Accounts) *API {
+	return &API{store: st, accounts: accounts}
+}
+
+// Handler returns the handler for everything under /api/
+func (api *API) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/login", api.login)
+	mux.HandleFunc("/api/logout", api.logout)
+	mux.HandleFunc("/api/cases", api.list)
+	mux.HandleFunc("/api/cases/", api.caseByID)
+	return cors(mux)
+}
+
+func (api *API) login(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		http.Error(w, "only POST is allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	creds := struct {
+		Name     string
+		Password string
+	}{}
+	err := json.NewDecoder(req.Body).Decode(&creds)
+	if err != nil {
+		http.Error(w, "send the name and password as json", http.StatusBadRequest)
+		return
+	}
+	token, err := api.accounts.Login(creds.Name, creds.Password)
+	if err == auth.ErrLogin {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	} else if err != nil {
+		serverError(w, err)
+		return
+	}
+	user := api.accounts.User(token)
+	writeJSON(w, struct {
+		Token string
+		Name  string
+		Teams []string
+	}{token, user.Name, user.Teams})
+}
+
+func (api *API) logout(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		http.Error(w, "only POST is allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	api.accounts.Logout(bearer(req))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (api *API) list(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		http.Error(w, "only GET is allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	user, ok := api.user(w, req)
+	if !ok {
+		return
+	}
+	metas, err := api.store.List()
+	if err != nil {
+		serverError(w, err)
+		return
+	}
+	infos := []caseInfo{}
+	for _, meta := range metas {
+		if role := api.role(user, meta); role >= auth.Reader {
+			infos = append(infos, caseInfo{Meta: meta, Role: role})
+		}
+	}
+	writeJSON(w, infos)
+}
+
+func (api *API) caseByID(w http.ResponseWriter, req *http.Request) {
+	user, ok := api.user(w, req)
+	if !ok {
+		return
+	}
+	id := strings.TrimPrefix(req.URL.Path, "/api/cases/")
+	if strings.HasSuffix(id, "/acl") {
+		api.acl(w, req, user, strings.TrimSuffix(id, "/acl"))
+		return
+	}
+
+	switch req.Method {
+	case http.MethodGet:
+		meta, data, err := api.store.Get(id)
+		if err != nil {
+			storeError(w, err)
+			return
+		}
+		if !api.allowed(w, user, meta, auth.Reader) {
+			return
+		}
+		w.Header().Set("Content-Type", caseType)
+		w.Header().Set("Last-Modified", meta.Updated.Format(http.TimeFormat))
+		w.Write(data)
+	case http.MethodPut:
+		api.upload(w, req, user, id)
+	case http.MethodDelete:
+		meta, err := api.store.Meta(id)
+		if err != nil {
+			storeError(w, err)
+			return
+		}
+		if !api.allowed(w, user, meta, auth.Owner) {
+			return
+		}
+		err = api.store.Delete(id)
+		if err != nil {
+			storeError(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		http.Error(w, "only GET, PUT and DELETE are allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// upload stores a new version of a case, making the user the owner if the case is new or has no owner
+func (api *API) upload(w http.ResponseWriter, req *http.Request, user *auth.User, id string) {
+	// Checked before reading the body so users who can't edit the case don't get to upload it, and checked again when saving
+	meta, err := api.store.Meta(id)
+	if err != nil && err != store.ErrNotFound {
+		storeError(w, err)
+		return
+	} else if err == nil && !api.allowed(w, user, meta, auth.Editor) {
+		return
+	}
+
+	data, err := ioutil.ReadAll(http.MaxBytesReader(w, req.Body, maxCaseSize))
+	if err != nil {
+		http.Error(w, "the case is too big or the upload was cut off", http.StatusRequestEntityTooLarge)
+		return
+	}
+	name := strings.TrimSpace(req.URL.Query().Get("name"))
+	if name == "" {
+		name = "Untitled Document"
+	}
+	uploader := req.URL.Query().Get("uploader")
+	if user != nil {
+		uploader = user.Name
+	}
+	// Whether the case is new is decided while nobody else can upload it, so two uploads can't both create it
+	var denied *store.Meta
+	meta, err = api.store.Update(id, data, func(meta store.Meta, err error) (store.Meta, error) {
+		if err == store.ErrNotFound {
+			meta = store.Meta{ACL: auth.ACL{}}
+		} else if err != nil {
+			return meta, err
+		} else if api.role(user, meta) < auth.Editor {
+			denied = &meta
+			return meta, errDenied
+		}
+		if meta.Owner == "" && user != nil {
+			meta.Owner = user.Name
+		}
+		meta.Name = name
+		meta.Uploader = uploader
+		return meta, nil
+	})
+	if err == errDenied {
+		// The case's access changed while it was being uploaded
+		api.allowed(w, user, *denied, auth.Editor)
+		return
+	} else if err != nil {
+		storeError(w, err)
+		return
+	}
+	writeJSON(w, caseInfo{Meta: meta, Role: api.role(user, meta)})
+}
+
+// acl shows or changes who can access a case
+func (api *API) acl(w http.ResponseWriter, req *http.Request, user *auth.User, id string) {
+	meta, err := api.store.Meta(id)
+	if err != nil {
+		storeError(w, err)
+		return
+	}
+	switch req.Method {
+	case http.MethodGet:
+		if !api.allowed(w, user, meta, auth.Reader) {
+			return
+		}
+		writeJSON(w, meta.ACL)
+	case http.MethodPut:
+		if !api.allowed(w, user, meta, auth.Owner) {
+			return
+		}
+		acl := auth.ACL{}
+		err := json.NewDecoder(req.Body).Decode(&acl)
+		if err != nil {
+			http.Error(w, "the access list is broken: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		for key := range acl {
+			if !strings.HasPrefix(key, "user:") && !strings.HasPrefix(key, "team:") {
+				http.Error(w, "access list entries must start with user: or team:", http.StatusBadRequest)
+				return
+			}
+		}
+		meta.ACL = acl
+		err = api.store.SetMeta(meta)
+		if err != nil {
+			storeError(w, err)
+			return
+		}
+		writeJSON(w, meta.ACL)
+	default:
+		http.Error(w, "only GET and PUT are allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// user returns who made the request, writing an error and returning false if they aren't logged in
+// The user is nil if the server has no accounts
+func (api *API) user(w http.ResponseWriter, req *http.Request) (*auth.User, bool) {
+	if api.accounts.Empty() {
+		return nil, true
+	}
+	user := api.accounts.User(bearer(req))
+	if user == nil {
+		http.Error(w, "log in first", http.StatusUnauthorized)
+		return nil, false
+	}
+	return user, true
+}
+
+// role returns what the user can do with the case
+func (api *API) role(user *auth.User, meta store.Meta) auth.Role {
+	switch {
+	case user == nil:
+		// Nobody has an account, so everyone on the network can do everything
+		return auth.Owner
+	case meta.Owner == "":
+		// Cases uploaded before there were accounts can be edited by everyone, and the next upload makes its uploader the owner
+		return auth.Editor
+	case meta.Owner == user.Name:
+		return auth.Owner
+	default:
+		return meta.ACL.RoleOf(user)
+	}
+}
+
+// RoomRole returns what the user can do in the relay room of the case with the ID
+// Cases that were never uploaded are only known to whoever was given the room code, so any logged in user can edit them
+func (api *API) RoomRole(user *auth.User, id string) auth.Role {
+	meta, err := api.store.Meta(id)
+	switch {
+	case err == store.ErrNotFound:
+		return auth.Editor
+	case err != nil:
+		log.Printf("Failed to check access to room %s: %s", id, err.Error())
+		return auth.None
+	}
+	return api.role(user, meta)
+}
+
+// allowed writes an error and returns false if the user doesn't have at least the role needed
+// Users who can't read the case are told it doesn't exist
+func (api *API) allowed(w http.ResponseWriter, user *auth.User, meta store.Meta, needed auth.Role) bool {
+	role := api.role(user, meta)
+	switch {
+	case role >= needed:
+		return true
+	case role == auth.None:
+		http.Error(w, store.ErrNotFound.Error(), http.StatusNotFound)
+	default:
+		http.Error(w, "you need to be "+needed.String()+" of this case to do that", http.StatusForbidden)
+	}
+	return false
+}
+
+func bearer(req *http.Request) string {
+	return strings.TrimPrefix(req.Header.Get("Authorization"), "Bearer ")
+}
+
+// cors lets the client call the API from a different address than the server
+func cors(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Headers", "Authorization, Content-Type")
+		if req.Method == http.MethodOptions {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		next.ServeHTTP(w, req)
+	})
+}
+
+func writeJSON(w http.ResponseWriter, value interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	err := json.NewEncoder(w).Encode(value)
+	if err != nil {
+		log.Printf("Failed to send a response: %s", err.Error())
+	}
+}
+
+func storeError(w http.ResponseWriter, err error) {
+	switch err {
+	case store.ErrNotFound:
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case store.ErrBadID:
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		serverError(w, err)
+	}
+}
+
+func serverError(w http.ResponseWriter, err error) {
+	log.Printf("Request failed: %s", err.Error())
+	http.Error(w, "something went wrong on the server", http.StatusInternalServerError)
+}
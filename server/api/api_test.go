@@ -0,0 +1,379 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"gitlab.com/256/DebateFrame/server/auth"
+	"gitlab.com/256/DebateFrame/server/store"
+)
+
+// testServer serves an API over a new directory, and removes the directory when closed
+type testServer struct {
+	*httptest.Server
+	api      *API
+	accounts *auth.Accounts
+	dir      string
+}
+
+func newTestServer(t *testing.T) *testServer {
+	dir, err := ioutil.TempDir("", "api")
+	if err != nil {
+		t.Fatal(err)
+	}
+	disk, err := store.NewDisk(filepath.Join(dir, "tub"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	accounts, err := auth.Open(filepath.Join(dir, "accounts.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	api := New(disk, accounts)
+	return &testServer{Server: httptest.NewServer(api.Handler()), api: api, accounts: accounts, dir: dir}
+}
+
+func (ts *testServer) Close() {
+	ts.Server.Close()
+	os.RemoveAll(ts.dir)
+}
+
+// do sends a request with the token, returning the status and body of the response
+func (ts *testServer) do(t *testing.T, method string, path string, token string, body []byte) (int, []byte) {
+	req, err := http.NewRequest(method, ts.URL+path, bytes.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	raw, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp.StatusCode, raw
+}
+
+// listed returns the IDs of the cases the token can see
+func (ts *testServer) listed(t *testing.T, token string) []string {
+	status, raw := ts.do(t, http.MethodGet, "/api/cases", token, nil)
+	if status != http.StatusOK {
+		t.Fatalf("listing returned %v: %s", status, raw)
+	}
+	infos := []caseInfo{}
+	err := json.Unmarshal(raw, &infos)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ids := []string{}
+	for _, info := range infos {
+		ids = append(ids, info.ID)
+	}
+	return ids
+}
+
+func TestOpenServer(t *testing.T) {
+	ts := newTestServer(t)
+	defer ts.Close()
+
+	status, raw := ts.do(t, http.MethodPut, "/api/cases/case-1?name=Aff&uploader=Sam", "", []byte("contents"))
+	if status != http.StatusOK {
+		t.Fatalf("upload returned %v: %s", status, raw)
+	}
+	info := caseInfo{}
+	err := json.Unmarshal(raw, &info)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Name != "Aff" || info.Uploader != "Sam" || info.Role != auth.Owner {
+		t.Errorf("upload returned %+v", info)
+	}
+
+	if ids := ts.listed(t, ""); len(ids) != 1 || ids[0] != "case-1" {
+		t.Errorf("listed %v", ids)
+	}
+	status, raw = ts.do(t, http.MethodGet, "/api/cases/case-1", "", nil)
+	if status != http.StatusOK || string(raw) != "contents" {
+		t.Errorf("download returned %v: %q", status, raw)
+	}
+	status, _ = ts.do(t, http.MethodDelete, "/api/cases/case-1", "", nil)
+	if status != http.StatusNoContent {
+		t.Errorf("delete returned %v", status)
+	}
+	status, _ = ts.do(t, http.MethodGet, "/api/cases/case-1", "", nil)
+	if status != http.StatusNotFound {
+		t.Errorf("download after delete returned %v", status)
+	}
+}
+
+func TestBadRequests(t *testing.T) {
+	ts := newTestServer(t)
+	defer ts.Close()
+	tests := []struct {
+		method string
+		path   string
+		want   int
+	}{
+		{http.MethodGet, "/api/cases/missing", http.StatusNotFound},
+		{http.MethodGet, "/api/cases/..%2Faccounts", http.StatusBadRequest},
+		{http.MethodPut, "/api/cases/a.json", http.StatusBadRequest},
+		{http.MethodPost, "/api/cases/case-1", http.StatusMethodNotAllowed},
+		{http.MethodPost, "/api/cases", http.StatusMethodNotAllowed},
+		{http.MethodGet, "/api/login", http.StatusMethodNotAllowed},
+	}
+	for _, test := range tests {
+		if status, raw := ts.do(t, test.method, test.path, "", nil); status != test.want {
+			t.Errorf("%s %s returned %v, want %v: %s", test.method, test.path, status, test.want, raw)
+		}
+	}
+}
+
+// login creates the account and returns a token for it
+func (ts *testServer) login(t *testing.T, name string, teams ...string) string {
+	err := ts.accounts.SetUser(name, "secret-"+name, teams)
+	if err != nil {
+		t.Fatal(err)
+	}
+	status, raw := ts.do(t, http.MethodPost, "/api/login", "", []byte(`{"Name": "`+name+`", "Password": "secret-`+name+`"}`))
+	if status != http.StatusOK {
+		t.Fatalf("login returned %v: %s", status, raw)
+	}
+	reply := struct {
+		Token string
+		Name  string
+	}{}
+	err = json.Unmarshal(raw, &reply)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reply.Name != name || reply.Token == "" {
+		t.Fatalf("login returned %s", raw)
+	}
+	return reply.Token
+}
+
+func TestLogin(t *testing.T) {
+	ts := newTestServer(t)
+	defer ts.Close()
+	token := ts.login(t, "coach")
+
+	status, _ := ts.do(t, http.MethodPost, "/api/login", "", []byte(`{"Name": "coach", "Password": "wrong"}`))
+	if status != http.StatusUnauthorized {
+		t.Errorf("login with the wrong password returned %v", status)
+	}
+	status, _ = ts.do(t, http.MethodPost, "/api/login", "", []byte(`not json`))
+	if status != http.StatusBadRequest {
+		t.Errorf("login without json returned %v", status)
+	}
+	status, _ = ts.do(t, http.MethodGet, "/api/cases", "", nil)
+	if status != http.StatusUnauthorized {
+		t.Errorf("listing without a token returned %v", status)
+	}
+	ts.listed(t, token)
+
+	status, _ = ts.do(t, http.MethodPost, "/api/logout", token, nil)
+	if status != http.StatusNoContent {
+		t.Errorf("logout returned %v", status)
+	}
+	status, _ = ts.do(t, http.MethodGet, "/api/cases", token, nil)
+	if status != http.StatusUnauthorized {
+		t.Errorf("listing after logging out returned %v", status)
+	}
+}
+
+func TestAccess(t *testing.T) {
+	ts := newTestServer(t)
+	defer ts.Close()
+	owner := ts.login(t, "coach", "coaches")
+	varsity := ts.login(t, "sam", "varsity")
+	jv := ts.login(t, "alex", "jv")
+
+	for _, id := range []string{"aff", "notes"} {
+		status, raw := ts.do(t, http.MethodPut, "/api/cases/"+id, owner, []byte(id))
+		if status != http.StatusOK {
+			t.Fatalf("upload of %s returned %v: %s", id, status, raw)
+		}
+	}
+	status, raw := ts.do(t, http.MethodPut, "/api/cases/aff/acl", owner, []byte(`{"team:varsity": "reader", "user:alex": "editor"}`))
+	if status != http.StatusOK {
+		t.Fatalf("setting the access list returned %v: %s", status, raw)
+	}
+
+	lists := []struct {
+		token string
+		want  []string
+	}{
+		{owner, []string{"aff", "notes"}},
+		{varsity, []string{"aff"}},
+		{jv, []string{"aff"}},
+	}
+	for _, list := range lists {
+		ids := ts.listed(t, list.token)
+		if len(ids) != len(list.want) {
+			t.Errorf("listed %v, want %v", ids, list.want)
+			continue
+		}
+		seen := make(map[string]bool)
+		for _, id := range ids {
+			seen[id] = true
+		}
+		for _, id := range list.want {
+			if !seen[id] {
+				t.Errorf("listed %v, want %v", ids, list.want)
+			}
+		}
+	}
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		token  string
+		body   string
+		want   int
+	}{
+		{"reader downloads", http.MethodGet, "/api/cases/aff", varsity, "", http.StatusOK},
+		{"reader uploads", http.MethodPut, "/api/cases/aff", varsity, "changed", http.StatusForbidden},
+		{"reader deletes", http.MethodDelete, "/api/cases/aff", varsity, "", http.StatusForbidden},
+		{"reader reads the access list", http.MethodGet, "/api/cases/aff/acl", varsity, "", http.StatusOK},
+		{"reader changes the access list", http.MethodPut, "/api/cases/aff/acl", varsity, `{"team:varsity": "owner"}`, http.StatusForbidden},
+		{"editor uploads", http.MethodPut, "/api/cases/aff", jv, "changed", http.StatusOK},
+		{"editor deletes", http.MethodDelete, "/api/cases/aff", jv, "", http.StatusForbidden},
+		{"no access downloads", http.MethodGet, "/api/cases/notes", varsity, "", http.StatusNotFound},
+		{"no access uploads", http.MethodPut, "/api/cases/notes", varsity, "changed", http.StatusNotFound},
+		{"no access deletes", http.MethodDelete, "/api/cases/notes", varsity, "", http.StatusNotFound},
+		{"no access reads the access list", http.MethodGet, "/api/cases/notes/acl", varsity, "", http.StatusNotFound},
+		{"bad access list key", http.MethodPut, "/api/cases/aff/acl", owner, `{"varsity": "reader"}`, http.StatusBadRequest},
+		{"bad access list role", http.MethodPut, "/api/cases/aff/acl", owner, `{"team:varsity": "admin"}`, http.StatusBadRequest},
+	}
+	for _, test := range tests {
+		if status, raw := ts.do(t, test.method, test.path, test.token, []byte(test.body)); status != test.want {
+			t.Errorf("%s returned %v, want %v: %s", test.name, status, test.want, raw)
+		}
+	}
+
+	status, raw = ts.do(t, http.MethodGet, "/api/cases/aff", owner, nil)
+	if status != http.StatusOK || string(raw) != "changed" {
+		t.Errorf("the editor's upload wasn't kept: %v %q", status, raw)
+	}
+}
+
+func TestACLUpdate(t *testing.T) {
+	ts := newTestServer(t)
+	defer ts.Close()
+	owner := ts.login(t, "coach")
+	sam := ts.login(t, "sam", "varsity")
+
+	status, raw := ts.do(t, http.MethodPut, "/api/cases/aff", owner, []byte("contents"))
+	if status != http.StatusOK {
+		t.Fatalf("upload returned %v: %s", status, raw)
+	}
+	status, raw = ts.do(t, http.MethodPut, "/api/cases/aff/acl", owner, []byte(`{"team:varsity": "owner"}`))
+	if status != http.StatusOK {
+		t.Fatalf("setting the access list returned %v: %s", status, raw)
+	}
+	acl := auth.ACL{}
+	err := json.Unmarshal(raw, &acl)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(acl) != 1 || acl["team:varsity"] != auth.Owner {
+		t.Errorf("the access list is %v", acl)
+	}
+
+	// Owners through a team can change the list too, and replacing it takes away what they had
+	status, raw = ts.do(t, http.MethodPut, "/api/cases/aff/acl", sam, []byte(`{"user:sam": "reader"}`))
+	if status != http.StatusOK {
+		t.Fatalf("a team owner setting the access list returned %v: %s", status, raw)
+	}
+	status, _ = ts.do(t, http.MethodDelete, "/api/cases/aff", sam, nil)
+	if status != http.StatusForbidden {
+		t.Errorf("deleting as a reader returned %v", status)
+	}
+	status, _ = ts.do(t, http.MethodPut, "/api/cases/aff/acl", owner, []byte(`{}`))
+	if status != http.StatusOK {
+		t.Errorf("clearing the access list returned %v", status)
+	}
+	status, _ = ts.do(t, http.MethodGet, "/api/cases/aff", sam, nil)
+	if status != http.StatusNotFound {
+		t.Errorf("downloading after being removed returned %v", status)
+	}
+	status, _ = ts.do(t, http.MethodPut, "/api/cases/missing/acl", owner, []byte(`{}`))
+	if status != http.StatusNotFound {
+		t.Errorf("setting the access list of a missing case returned %v", status)
+	}
+}
+
+func TestRoomRole(t *testing.T) {
+	ts := newTestServer(t)
+	defer ts.Close()
+	owner := ts.login(t, "coach")
+	ts.login(t, "sam")
+	status, raw := ts.do(t, http.MethodPut, "/api/cases/aff", owner, []byte("contents"))
+	if status != http.StatusOK {
+		t.Fatalf("upload returned %v: %s", status, raw)
+	}
+	sam := &auth.User{Name: "sam"}
+	tests := []struct {
+		id   string
+		want auth.Role
+	}{
+		{"aff", auth.None},
+		{"never-uploaded", auth.Editor},
+		{"../accounts", auth.None},
+	}
+	for _, test := range tests {
+		if got := ts.api.RoomRole(sam, test.id); got != test.want {
+			t.Errorf("RoomRole(%q) = %v, want %v", test.id, got, test.want)
+		}
+	}
+}
+
+// TestOwnerless checks cases uploaded before there were accounts belong to whoever uploads them next
+func TestOwnerless(t *testing.T) {
+	ts := newTestServer(t)
+	defer ts.Close()
+	sam := ts.login(t, "sam")
+	alex := ts.login(t, "alex")
+	if _, err := ts.api.store.Put(store.Meta{ID: "old"}, []byte("contents")); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		token  string
+		body   string
+		want   int
+	}{
+		{"downloading", http.MethodGet, "/api/cases/old", alex, "", http.StatusOK},
+		{"deleting before it has an owner", http.MethodDelete, "/api/cases/old", alex, "", http.StatusForbidden},
+		{"changing the access list before it has an owner", http.MethodPut, "/api/cases/old/acl", alex, `{"user:alex": "owner"}`, http.StatusForbidden},
+		{"uploading", http.MethodPut, "/api/cases/old", sam, "changed", http.StatusOK},
+		{"uploading after sam owns it", http.MethodPut, "/api/cases/old", alex, "changed again", http.StatusNotFound},
+		{"sam changing the access list", http.MethodPut, "/api/cases/old/acl", sam, `{"user:alex": "reader"}`, http.StatusOK},
+	}
+	for _, test := range tests {
+		if status, raw := ts.do(t, test.method, test.path, test.token, []byte(test.body)); status != test.want {
+			t.Errorf("%s returned %v, want %v: %s", test.name, status, test.want, raw)
+		}
+	}
+	meta, err := ts.api.store.Meta("old")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if meta.Owner != "sam" {
+		t.Errorf("the case belongs to %q, want sam", meta.Owner)
+	}
+}
@@ -0,0 +1,257 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// ErrLogin is returned when a name and password don't match an account
+var ErrLogin = errors.New("wrong name or password")
+
+const (
+	hashRounds = 20000 // How many times passwords were hashed before accounts used bcrypt
+	tokenLife  = time.Hour * 24 * 7
+)
+
+// Role is what a user may do with a case, where each role can do everything the ones before it can
+type Role int
+
+const (
+	// None can't see the case
+	None Role = iota
+	// Reader can download the case
+	Reader
+	// Editor can upload new versions of the case
+	Editor
+	// Owner can delete the case and change who can access it
+	Owner
+)
+
+var roleNames = []string{"none", "reader", "editor", "owner"}
+
+func (role Role) String() string {
+	if role < 0 || int(role) >= len(roleNames) {
+		return "none"
+	}
+	return roleNames[role]
+}
+
+// ParseRole reads a role from its name
+func ParseRole(name string) (Role, error) {
+	for i, roleName := range roleNames {
+		if strings.EqualFold(name, roleName) {
+			return Role(i), nil
+		}
+	}
+	return None, fmt.Errorf("%q is not a role, use reader, editor or owner", name)
+}
+
+// MarshalJSON saves roles by name so accounts and ACLs stay readable
+func (role Role) MarshalJSON() ([]byte, error) {
+	return json.Marshal(role.String())
+}
+
+// UnmarshalJSON reads a role saved by name
+func (role *Role) UnmarshalJSON(raw []byte) error {
+	name := ""
+	err := json.Unmarshal(raw, &name)
+	if err != nil {
+		return err
+	}
+	*role, err = ParseRole(name)
+	return err
+}
+
+// User is an account on the server
+type User struct {
+	Name  string
+	Teams []string // Teams the user is on, like varsity or coaches
+	Salt  string   `json:",omitempty"` // Only set for accounts saved before passwords were hashed with bcrypt
+	Hash  string   // The bcrypt hash of the password, or the hex of the password hashed with the salt for older accounts
+}
+
+// ACL says who can access a case
+// Keys are user:<name> or team:<name>
+type ACL map[string]Role
+
+// RoleOf returns the best role the user gets from the ACL
+func (acl ACL) RoleOf(user *User) Role {
+	best := acl["user:"+user.Name]
+	for _, team := range user.Teams {
+		if role := acl["team:"+team]; role > best {
+			best = role
+		}
+	}
+	return best
+}
+
+type session struct {
+	user    string
+	expires time.Time
+}
+
+// Accounts holds the users of the server and who is logged in
+// Users are saved in a json file that is only read when the server starts, and logins only last until it restarts
+type Accounts struct {
+	path     string
+	mux      sync.Mutex
+	users    map[string]*User
+	sessions map[string]session
+}
+
+// Open loads the accounts saved in the file, starting with none if it doesn't exist yet
+func Open(path string) (*Accounts, error) {
+	acc := &Accounts{
+		path:     path,
+		users:    make(map[string]*User),
+		sessions: make(map[string]session),
+	}
+	raw, err := ioutil.ReadFile(acc.path)
+	if os.IsNotExist(err) {
+		return acc, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to read the accounts: %s", err.Error())
+	}
+	users := []*User{}
+	err = json.Unmarshal(raw, &users)
+	if err != nil {
+		return nil, fmt.Errorf("the accounts file is broken: %s", err.Error())
+	}
+	for _, user := range users {
+		acc.users[user.Name] = user
+	}
+	return acc, nil
+}
+
+// Empty returns true if there are no accounts, in which case the server is open to everyone
+func (acc *Accounts) Empty() bool {
+	acc.mux.Lock()
+	defer acc.mux.Unlock()
+	return len(acc.users) == 0
+}
+
+// SetUser creates or updates an account and saves the accounts
+func (acc *Accounts) SetUser(name string, password string, teams []string) error {
+	if name == "" || strings.ContainsAny(name, ":,") {
+		return fmt.Errorf("%q can't be used as a name", name)
+	}
+	if password == "" {
+		return errors.New("a password is needed")
+	}
+	hashed, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return fmt.Errorf("failed to hash the password: %s", err.Error())
+	}
+	acc.mux.Lock()
+	defer acc.mux.Unlock()
+	acc.users[name] = &User{Name: name, Teams: teams, Hash: string(hashed)}
+	return acc.save()
+}
+
+// Login checks the password and returns a token that the user sends with each request
+// The password is checked without holding the lock, since hashing it is slow on purpose
+func (acc *Accounts) Login(name string, password string) (string, error) {
+	acc.mux.Lock()
+	user, ok := acc.users[name]
+	acc.mux.Unlock()
+	if !ok || !checkPassword(user, password) {
+		return "", ErrLogin
+	}
+	token, err := randomHex(32)
+	if err != nil {
+		return "", err
+	}
+
+	acc.mux.Lock()
+	defer acc.mux.Unlock()
+	now := time.Now()
+	// Sessions nobody logged out of would pile up until the server restarts
+	for old, sess := range acc.sessions {
+		if now.After(sess.expires) {
+			delete(acc.sessions, old)
+		}
+	}
+	acc.sessions[token] = session{user: name, expires: now.Add(tokenLife)}
+	return token, nil
+}
+
+// Logout ends the session of the token
+func (acc *Accounts) Logout(token string) {
+	acc.mux.Lock()
+	defer acc.mux.Unlock()
+	delete(acc.sessions, token)
+}
+
+// User returns the user the token belongs to, or nil if the token isn't valid
+func (acc *Accounts) User(token string) *User {
+	acc.mux.Lock()
+	defer acc.mux.Unlock()
+	sess, ok := acc.sessions[token]
+	if !ok {
+		return nil
+	}
+	if time.Now().After(sess.expires) {
+		delete(acc.sessions, token)
+		return nil
+	}
+	return acc.users[sess.user]
+}
+
+// save writes the accounts to disk, and must be called with the lock held
+func (acc *Accounts) save() error {
+	users := []*User{}
+	for _, user := range acc.users {
+		users = append(users, user)
+	}
+	raw, err := json.MarshalIndent(users, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to convert the accounts to json: %s", err.Error())
+	}
+	tmp := acc.path + ".tmp"
+	err = ioutil.WriteFile(tmp, raw, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to save the accounts: %s", err.Error())
+	}
+	err = os.Rename(tmp, acc.path)
+	if err != nil {
+		return fmt.Errorf("failed to save the accounts: %s", err.Error())
+	}
+	return nil
+}
+
+// checkPassword returns whether the password is the user's, for accounts hashed with bcrypt or saved before it was used
+func checkPassword(user *User, password string) bool {
+	if user.Salt == "" {
+		return bcrypt.CompareHashAndPassword([]byte(user.Hash), []byte(password)) == nil
+	}
+	return subtle.ConstantTimeCompare([]byte(user.Hash), []byte(hash(user.Salt, password))) == 1
+}
+
+func hash(salt string, password string) string {
+	sum := sha256.Sum256([]byte(salt + password))
+	for i := 1; i < hashRounds; i++ {
+		sum = sha256.Sum256(sum[:])
+	}
+	return hex.EncodeToString(sum[:])
+}
+
+func randomHex(size int) (string, error) {
+	buf := make([]byte, size)
+	_, err := rand.Read(buf)
+	if err != nil {
+		return "", fmt.Errorf("failed to make random bytes: %s", err.Error())
+	}
+	return hex.EncodeToString(buf), nil
+}
@@ -0,0 +1,155 @@
+package auth
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func tempAccounts(t *testing.T) (*Accounts, string, func()) {
+	dir, err := ioutil.TempDir("", "auth")
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, "accounts.json")
+	acc, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return acc, path, func() { os.RemoveAll(dir) }
+}
+
+func TestLogin(t *testing.T) {
+	acc, path, cleanup := tempAccounts(t)
+	defer cleanup()
+	if !acc.Empty() {
+		t.Fatal("new accounts aren't empty")
+	}
+	err := acc.SetUser("coach", "secret", []string{"coaches"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := acc.Login("coach", "wrong"); err != ErrLogin {
+		t.Errorf("login with the wrong password returned %v", err)
+	}
+	if _, err := acc.Login("nobody", "secret"); err != ErrLogin {
+		t.Errorf("login as a missing user returned %v", err)
+	}
+	token, err := acc.Login("coach", "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user := acc.User(token); user == nil || user.Name != "coach" {
+		t.Errorf("token belongs to %+v", user)
+	}
+	if user := acc.User("not a token"); user != nil {
+		t.Errorf("a made up token belongs to %+v", user)
+	}
+	acc.Logout(token)
+	if user := acc.User(token); user != nil {
+		t.Errorf("token still belongs to %+v after logging out", user)
+	}
+
+	reopened, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := reopened.Login("coach", "secret"); err != nil {
+		t.Errorf("the account wasn't saved: %v", err)
+	}
+}
+
+func TestSetUserNames(t *testing.T) {
+	acc, _, cleanup := tempAccounts(t)
+	defer cleanup()
+	for _, name := range []string{"", "team:varsity", "a,b"} {
+		if err := acc.SetUser(name, "secret", nil); err == nil {
+			t.Errorf("SetUser accepted the name %q", name)
+		}
+	}
+	if err := acc.SetUser("coach", "", nil); err == nil {
+		t.Error("SetUser accepted an empty password")
+	}
+}
+
+func TestRoleOf(t *testing.T) {
+	acl := ACL{"user:sam": Reader, "team:varsity": Editor, "team:coaches": Owner}
+	tests := []struct {
+		user User
+		want Role
+	}{
+		{User{Name: "sam"}, Reader},
+		{User{Name: "sam", Teams: []string{"varsity"}}, Editor},
+		{User{Name: "alex", Teams: []string{"jv", "coaches"}}, Owner},
+		{User{Name: "alex", Teams: []string{"jv"}}, None},
+	}
+	for _, test := range tests {
+		if got := acl.RoleOf(&test.user); got != test.want {
+			t.Errorf("RoleOf(%+v) = %v, want %v", test.user, got, test.want)
+		}
+	}
+}
+
+func TestRoleJSON(t *testing.T) {
+	raw, err := json.Marshal(ACL{"team:varsity": Editor})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(raw) != `{"team:varsity":"editor"}` {
+		t.Errorf("got %s", raw)
+	}
+	acl := ACL{}
+	if err := json.Unmarshal([]byte(`{"user:sam":"Owner"}`), &acl); err != nil || acl["user:sam"] != Owner {
+		t.Errorf("got %v, %v", acl, err)
+	}
+	if err := json.Unmarshal([]byte(`{"user:sam":"admin"}`), &acl); err == nil {
+		t.Error("admin was read as a role")
+	}
+}
+
+// TestOldAccount checks accounts saved before passwords were hashed with bcrypt can still log in
+func TestOldAccount(t *testing.T) {
+	_, path, cleanup := tempAccounts(t)
+	defer cleanup()
+	users := []*User{{Name: "coach", Salt: "salt", Hash: hash("salt", "secret")}}
+	raw, err := json.Marshal(users)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(path, raw, 0600); err != nil {
+		t.Fatal(err)
+	}
+	acc, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := acc.Login("coach", "wrong"); err != ErrLogin {
+		t.Errorf("login with the wrong password returned %v", err)
+	}
+	if _, err := acc.Login("coach", "secret"); err != nil {
+		t.Errorf("login to the old account returned %v", err)
+	}
+}
+
+func TestExpiredSessions(t *testing.T) {
+	acc, _, cleanup := tempAccounts(t)
+	defer cleanup()
+	if err := acc.SetUser("coach", "secret", nil); err != nil {
+		t.Fatal(err)
+	}
+	acc.sessions["old"] = session{user: "coach", expires: time.Now().Add(-time.Minute)}
+	if user := acc.User("old"); user != nil {
+		t.Errorf("an expired token belongs to %+v", user)
+	}
+	acc.sessions["forgotten"] = session{user: "coach", expires: time.Now().Add(-time.Minute)}
+	if _, err := acc.Login("coach", "secret"); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := acc.sessions["forgotten"]; ok || len(acc.sessions) != 1 {
+		t.Errorf("logging in left %v sessions, want only the new one", len(acc.sessions))
+	}
+}
@@ -0,0 +1,6 @@
+module gitlab.com/256/DebateFrame/server
+
+require (
+	golang.org/x/crypto v0.0.0-20190308221718-c2843e01d9a2
+	golang.org/x/net v0.0.0-20190311183353-d8887717615a
+)
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"flag"
+	"log"
+	"net/http"
+	"strings"
+
+	"gitlab.com/256/DebateFrame/server/api"
+	"gitlab.com/256/DebateFrame/server/auth"
+	"gitlab.com/256/DebateFrame/server/relay"
+	"gitlab.com/256/DebateFrame/server/store"
+)
+
+// main runs the DebateFrame server, which teammates on the same network point their clients at
+// Run it with -adduser to create or change an account instead, like -adduser coach -password secret -teams varsity,coaches
+// A running server doesn't see accounts changed with -adduser until it is restarted
+func main() {
+	addr := flag.String("addr", ":8080", "the address to listen on")
+	data := flag.String("data", "tub", "the directory the team's cases are kept in")
+	accountsPath := flag.String("accounts", "accounts.json", "the file accounts are kept in. With no accounts anyone can use the server")
+	addUser := flag.String("adduser", "", "create or change the account with this name and exit. Restart the server for it to see the change")
+	password := flag.String("password", "", "the password for -adduser")
+	teams := flag.String("teams", "", "the comma separated teams for -adduser")
+	flag.Parse()
+
+	accounts, err := auth.Open(*accountsPath)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if *addUser != "" {
+		err = accounts.SetUser(*addUser, *password, splitTeams(*teams))
+		if err != nil {
+			log.Fatal(err)
+		}
+		log.Printf("Saved the account %s", *addUser)
+		return
+	}
+	if accounts.Empty() {
+		log.Printf("There are no accounts, so anyone who can reach the server can use every case")
+	}
+
+	disk, err := store.NewDisk(*data)
+	if err != nil {
+		log.Fatal(err)
+	}
+	tubAPI := api.New(disk, accounts)
+	http.Handle("/api/", tubAPI.Handler())
+
+	hub := relay.NewHub(accounts, tubAPI.RoomRole)
+	http.Handle("/relay", hub.Handler())
+
+	log.Printf("DebateFrame server listening on %s", *addr)
+	log.Fatal(http.ListenAndServe(*addr, nil))
+}
+
+func splitTeams(list string) []string {
+	teams := []string{}
+	for _, team := range strings.Split(list, ",") {
+		if team = strings.TrimSpace(team); team != "" {
+			teams = append(teams, team)
+		}
+	}
+	return teams
+}
@@ -0,0 +1,240 @@
+package relay
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"sync"
+
+	"golang.org/x/net/websocket"
+
+	"gitlab.com/256/DebateFrame/server/auth"
+)
+
+// Message is everything sent between the relay and the clients
+// The relay never looks inside Op or Presence, it only decides the order that ops are applied in
+type Message struct {
+	Type     string          // join, op or presence from clients. sync, op, reject, denied, presence or leave from the relay
+	Room     string          // The case being edited
+	User     string          // A unique ID for the connection that sent the message, after the account name when there are accounts
+	Name     string          // The name of the user shown to teammates
+	ID       string          // Identifies an op so its sender can recognize it when it comes back
+	Base     int             // How many ops the sender had applied when it made the op
+	Seq      int             // Where the op is in the room's log
+	Op       json.RawMessage `json:",omitempty"`
+	Presence json.RawMessage `json:",omitempty"`
+	Ops      []Message       `json:",omitempty"` // The ops a joining client is missing
+	Reset    bool            `json:",omitempty"` // True if the room lost ops the client already had, like after a restart
+	Error    string          `json:",omitempty"` // Why the client was denied
+}
+
+// Roles returns what the user can do with the case a room is for
+// The user is nil if the server has no accounts
+type Roles func(user *auth.User, room string) auth.Role
+
+// Hub holds every room and the clients connected to it
+type Hub struct {
+	mux      sync.Mutex
+	rooms    map[string]*room
+	accounts *auth.Accounts
+	roles    Roles
+}
+
+type room struct {
+	name     string
+	log      []Message
+	clients  map[*client]bool
+	presence map[string]Message // Last presence message of each connected user
+}
+
+type client struct {
+	conn    *websocket.Conn
+	send    chan Message
+	room    *room
+	account *auth.User // Who logged in, or nil if the server has no accounts
+	role    auth.Role  // What the account can do with the room's case
+	user    string
+	name    string
+}
+
+// NewHub creates a Hub with no rooms, where the accounts decide who can connect and the roles which rooms they can use
+func NewHub(accounts *auth.Accounts, roles Roles) *Hub {
+	return &Hub{rooms: make(map[string]*room), accounts: accounts, roles: roles}
+}
+
+// Handler returns the WebSocket handler that clients connect to
+// Browsers can't send headers with WebSockets, so the login token is sent as the token query parameter
+// Any origin is accepted, since the client is usually opened from a different address than the server
+func (hub *Hub) Handler() http.Handler {
+	return websocket.Server{
+		Handler: hub.serve,
+		Handshake: func(config *websocket.Config, req *http.Request) error {
+			if hub.accounts.Empty() || hub.accounts.User(req.URL.Query().Get("token")) != nil {
+				return nil
+			}
+			return errors.New("log in to the server first")
+		},
+	}
+}
+
+// serve handles one client until it disconnects
+func (hub *Hub) serve(conn *websocket.Conn) {
+	cl := &client{conn: conn, send: make(chan Message, 64)}
+	if !hub.accounts.Empty() {
+		cl.account = hub.accounts.User(conn.Request().URL.Query().Get("token"))
+		if cl.account == nil {
+			// The token expired or was logged out since the handshake
+			conn.Close()
+			return
+		}
+	}
+	go cl.write()
+	defer func() {
+		hub.leave(cl)
+		close(cl.send)
+	}()
+
+	for {
+		msg := Message{}
+		err := websocket.JSON.Receive(conn, &msg)
+		if err != nil {
+			return
+		}
+		switch msg.Type {
+		case "join":
+			hub.join(cl, msg)
+		case "op":
+			hub.op(cl, msg)
+		case "presence":
+			hub.presence(cl, msg)
+		default:
+			log.Printf("Ignoring message of unknown type %q", msg.Type)
+		}
+	}
+}
+
+// write sends queued messages to the client
+func (cl *client) write() {
+	for msg := range cl.send {
+		err := websocket.JSON.Send(cl.conn, msg)
+		if err != nil {
+			cl.conn.Close()
+			return
+		}
+	}
+}
+
+// queue sends the message to the client without blocking the hub
+// A client that falls too far behind is disconnected, and catches up when it joins again
+func (cl *client) queue(msg Message) {
+	select {
+	case cl.send <- msg:
+	default:
+		cl.conn.Close()
+	}
+}
+
+// join adds the client to a room, sending it every op after the ones it already has
+func (hub *Hub) join(cl *client, msg Message) {
+	hub.mux.Lock()
+	defer hub.mux.Unlock()
+	if cl.room != nil {
+		return
+	}
+	role := hub.roles(cl.account, msg.Room)
+	if role < auth.Reader {
+		// Rooms of cases the user can't see are treated as missing, the same as the API does
+		cl.queue(Message{Type: "denied", Room: msg.Room, Error: "case not found"})
+		return
+	}
+	cl.role = role
+	rm, ok := hub.rooms[msg.Room]
+	if !ok {
+		rm = &room{name: msg.Room, clients: make(map[*client]bool), presence: make(map[string]Message)}
+		hub.rooms[msg.Room] = rm
+	}
+	cl.room = rm
+	cl.user = msg.User
+	cl.name = msg.Name
+	if cl.account != nil {
+		// Teammates are shown who logged in, not who the client says it is, and can't take over each other's connections
+		cl.user = cl.account.Name + ":" + msg.User
+		cl.name = cl.account.Name
+	}
+	rm.clients[cl] = true
+
+	reply := Message{Type: "sync", Room: msg.Room, Seq: len(rm.log)}
+	if msg.Base < 0 || msg.Base > len(rm.log) {
+		reply.Reset = true
+		reply.Ops = append([]Message{}, rm.log...)
+	} else {
+		reply.Ops = append([]Message{}, rm.log[msg.Base:]...)
+	}
+	cl.queue(reply)
+	for _, pres := range rm.presence {
+		cl.queue(pres)
+	}
+	log.Printf("%s joined room %s (%v clients)", cl.name, msg.Room, len(rm.clients))
+}
+
+// op adds the op to the log if it was made against the latest op, otherwise the client has to rebase it and send it again
+func (hub *Hub) op(cl *client, msg Message) {
+	hub.mux.Lock()
+	defer hub.mux.Unlock()
+	rm := cl.room
+	if rm == nil {
+		return
+	}
+	if cl.role < auth.Editor {
+		cl.queue(Message{Type: "denied", ID: msg.ID, Error: "you need to be " + auth.Editor.String() + " of this case to change it"})
+		return
+	}
+	if msg.Base != len(rm.log) {
+		cl.queue(Message{Type: "reject", ID: msg.ID, Seq: len(rm.log)})
+		return
+	}
+	accepted := Message{Type: "op", User: cl.user, Name: cl.name, ID: msg.ID, Seq: len(rm.log), Op: msg.Op}
+	rm.log = append(rm.log, accepted)
+	for other := range rm.clients {
+		other.queue(accepted)
+	}
+}
+
+// presence tells everyone else in the room where the client is
+func (hub *Hub) presence(cl *client, msg Message) {
+	hub.mux.Lock()
+	defer hub.mux.Unlock()
+	rm := cl.room
+	if rm == nil {
+		return
+	}
+	pres := Message{Type: "presence", User: cl.user, Name: cl.name, Presence: msg.Presence}
+	rm.presence[cl.user] = pres
+	for other := range rm.clients {
+		if other != cl {
+			other.queue(pres)
+		}
+	}
+}
+
+// leave removes the client from its room
+func (hub *Hub) leave(cl *client) {
+	hub.mux.Lock()
+	defer hub.mux.Unlock()
+	rm := cl.room
+	if rm == nil {
+		return
+	}
+	delete(rm.clients, cl)
+	delete(rm.presence, cl.user)
+	for other := range rm.clients {
+		other.queue(Message{Type: "leave", User: cl.user, Name: cl.name})
+	}
+	log.Printf("%s left (%v clients)", cl.name, len(rm.clients))
+	if len(rm.clients) == 0 {
+		// Nobody has the room open, so its log is freed. Whoever joins next gets a reset and seeds it with their copy
+		delete(hub.rooms, rm.name)
+		log.Printf("Closed room %s", rm.name)
+	}
+}
@@ -0,0 +1,198 @@
+package relay
+
+import (
+	"io/ioutil"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golang.org/x/net/websocket"
+
+	"gitlab.com/256/DebateFrame/server/auth"
+)
+
+// testRelay serves a Hub where sam reads the aff room and edits the notes room, and nobody can see the secret room
+func testRelay(t *testing.T) (*httptest.Server, *auth.Accounts, func()) {
+	dir, err := ioutil.TempDir("", "relay")
+	if err != nil {
+		t.Fatal(err)
+	}
+	accounts, err := auth.Open(filepath.Join(dir, "accounts.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = accounts.SetUser("sam", "secret", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	roles := map[string]auth.Role{"aff": auth.Reader, "notes": auth.Editor, "secret": auth.None}
+	hub := NewHub(accounts, func(user *auth.User, room string) auth.Role {
+		return roles[room]
+	})
+	server := httptest.NewServer(hub.Handler())
+	return server, accounts, func() {
+		server.Close()
+		os.RemoveAll(dir)
+	}
+}
+
+func dial(server *httptest.Server, token string) (*websocket.Conn, error) {
+	url := "ws" + strings.TrimPrefix(server.URL, "http") + "/?token=" + token
+	return websocket.Dial(url, "", server.URL)
+}
+
+func exchange(t *testing.T, conn *websocket.Conn, msg Message) Message {
+	err := websocket.JSON.Send(conn, msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	reply := Message{}
+	err = websocket.JSON.Receive(conn, &reply)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return reply
+}
+
+func TestHandshake(t *testing.T) {
+	server, _, cleanup := testRelay(t)
+	defer cleanup()
+	for _, token := range []string{"", "made-up"} {
+		if conn, err := dial(server, token); err == nil {
+			conn.Close()
+			t.Errorf("connected with the token %q", token)
+		}
+	}
+}
+
+func TestRoomRoles(t *testing.T) {
+	server, accounts, cleanup := testRelay(t)
+	defer cleanup()
+	token, err := accounts.Login("sam", "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		room     string
+		joinType string
+		opType   string
+	}{
+		{"secret", "denied", "denied"},
+		{"aff", "sync", "denied"},
+		{"notes", "sync", "op"},
+	}
+	for _, test := range tests {
+		conn, err := dial(server, token)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if reply := exchange(t, conn, Message{Type: "join", Room: test.room}); reply.Type != test.joinType {
+			t.Errorf("joining %s got %+v, want %s", test.room, reply, test.joinType)
+		}
+		if test.room == "secret" {
+			// Clients that couldn't join aren't in a room, so their ops go nowhere
+			conn.Close()
+			continue
+		}
+		reply := exchange(t, conn, Message{Type: "op", ID: "step", Op: []byte(`{}`)})
+		if reply.Type != test.opType || reply.ID != "step" {
+			t.Errorf("sending an op to %s got %+v, want %s", test.room, reply, test.opType)
+		}
+		conn.Close()
+	}
+}
+
+func receive(t *testing.T, conn *websocket.Conn) Message {
+	msg := Message{}
+	err := websocket.JSON.Receive(conn, &msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return msg
+}
+
+// TestOpOrder checks ops are put in one order, and an op made without the latest op is rejected so it can be rebased
+func TestOpOrder(t *testing.T) {
+	server, accounts, cleanup := testRelay(t)
+	defer cleanup()
+	token, err := accounts.Login("sam", "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	join := func(base int) (*websocket.Conn, Message) {
+		conn, err := dial(server, token)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return conn, exchange(t, conn, Message{Type: "join", Room: "notes", Base: base})
+	}
+	one, _ := join(0)
+	defer one.Close()
+	two, _ := join(0)
+	defer two.Close()
+
+	if reply := exchange(t, one, Message{Type: "op", ID: "first", Base: 0, Op: []byte(`{}`)}); reply.Type != "op" || reply.ID != "first" || reply.Seq != 0 {
+		t.Errorf("the first op got %+v", reply)
+	}
+	if msg := receive(t, two); msg.Type != "op" || msg.ID != "first" || msg.Seq != 0 {
+		t.Errorf("the first op reached the other client as %+v", msg)
+	}
+	// two sends an op before it applied the first one
+	if reply := exchange(t, two, Message{Type: "op", ID: "second", Base: 0, Op: []byte(`{}`)}); reply.Type != "reject" || reply.ID != "second" || reply.Seq != 1 {
+		t.Errorf("an op behind the log got %+v, want a reject", reply)
+	}
+	if reply := exchange(t, two, Message{Type: "op", ID: "second", Base: 1, Op: []byte(`{}`)}); reply.Type != "op" || reply.Seq != 1 {
+		t.Errorf("the rebased op got %+v", reply)
+	}
+	if msg := receive(t, one); msg.Type != "op" || msg.ID != "second" || msg.Seq != 1 {
+		t.Errorf("the second op reached the other client as %+v", msg)
+	}
+
+	late, sync := join(1)
+	defer late.Close()
+	if sync.Type != "sync" || sync.Reset || sync.Seq != 2 || len(sync.Ops) != 1 || sync.Ops[0].ID != "second" {
+		t.Errorf("joining after the first op got %+v, want the second op", sync)
+	}
+	lost, sync := join(5)
+	defer lost.Close()
+	if !sync.Reset || len(sync.Ops) != 2 || sync.Ops[0].ID != "first" || sync.Ops[1].ID != "second" {
+		t.Errorf("joining with ops the relay doesn't have got %+v, want a reset with every op in order", sync)
+	}
+}
+
+// TestIdentity checks teammates see the name of the account that logged in, whatever name the client sends
+func TestIdentity(t *testing.T) {
+	server, accounts, cleanup := testRelay(t)
+	defer cleanup()
+	token, err := accounts.Login("sam", "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	join := func(user string, name string) *websocket.Conn {
+		conn, err := dial(server, token)
+		if err != nil {
+			t.Fatal(err)
+		}
+		exchange(t, conn, Message{Type: "join", Room: "notes", User: user, Name: name})
+		return conn
+	}
+	one := join("one", "sam")
+	defer one.Close()
+	two := join("two", "coach")
+	defer two.Close()
+
+	msg := exchange(t, two, Message{Type: "op", ID: "step", Op: []byte(`{}`)})
+	if msg.Name != "sam" || msg.User != "sam:two" {
+		t.Errorf("the op came back from %q (%s), want sam", msg.Name, msg.User)
+	}
+	receive(t, one)
+	err = websocket.JSON.Send(two, Message{Type: "presence", Presence: []byte(`{}`)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg := receive(t, one); msg.Type != "presence" || msg.Name != "sam" || msg.User != "sam:two" {
+		t.Errorf("the presence reached the other client as %+v, want it from sam", msg)
+	}
+}
@@ -0,0 +1,227 @@
+package store
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"gitlab.com/256/DebateFrame/server/auth"
+)
+
+// ErrNotFound is returned when there is no case with the requested ID
+var ErrNotFound = errors.New("case not found")
+
+// ErrBadID is returned for IDs that can't be used as file names
+var ErrBadID = errors.New("case IDs can only hold letters, numbers and dashes")
+
+var idRp = regexp.MustCompile(`^[A-Za-z0-9-]{1,64}$`)
+
+const (
+	dataExt = ".dfc"  // The case file exactly as the client uploaded it
+	metaExt = ".json" // The Meta of the case
+)
+
+// Meta describes a stored case without its contents
+type Meta struct {
+	ID       string // The ID of the case in the client, which stays the same across uploads
+	Name     string
+	Uploader string    // Who uploaded the latest version
+	Updated  time.Time // When the latest version was uploaded
+	Size     int64     // Size of the case file in bytes
+	Owner    string    // The user who first uploaded the case, or empty until someone logged in uploads a case from before there were accounts
+	ACL      auth.ACL  // Who else can access the case
+}
+
+// Disk keeps cases as files in a directory, two files for each case
+type Disk struct {
+	dir string
+	mux sync.RWMutex
+}
+
+// NewDisk uses the directory to store cases, creating it if it doesn't exist
+func NewDisk(dir string) (*Disk, error) {
+	err := os.MkdirAll(dir, 0755)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create the case directory: %s", err.Error())
+	}
+	return &Disk{dir: dir}, nil
+}
+
+// Put saves a case, replacing any case with the same ID
+func (disk *Disk) Put(meta Meta, data []byte) (Meta, error) {
+	if !idRp.MatchString(meta.ID) {
+		return Meta{}, ErrBadID
+	}
+	disk.mux.Lock()
+	defer disk.mux.Unlock()
+	return disk.put(meta, data)
+}
+
+// Update saves a new version of a case, with change deciding its Meta from the stored one before anyone else can change it
+// change is given ErrNotFound if the case is new, and nothing is saved if it returns an error, which Update returns
+func (disk *Disk) Update(id string, data []byte, change func(meta Meta, err error) (Meta, error)) (Meta, error) {
+	if !idRp.MatchString(id) {
+		return Meta{}, ErrBadID
+	}
+	disk.mux.Lock()
+	defer disk.mux.Unlock()
+	meta, err := change(disk.meta(id))
+	if err != nil {
+		return Meta{}, err
+	}
+	meta.ID = id
+	return disk.put(meta, data)
+}
+
+// Get returns a case and its contents
+func (disk *Disk) Get(id string) (Meta, []byte, error) {
+	if !idRp.MatchString(id) {
+		return Meta{}, nil, ErrBadID
+	}
+	disk.mux.RLock()
+	defer disk.mux.RUnlock()
+	meta, err := disk.meta(id)
+	if err != nil {
+		return Meta{}, nil, err
+	}
+	data, err := ioutil.ReadFile(disk.path(id, dataExt))
+	if err != nil {
+		return Meta{}, nil, fmt.Errorf("failed to read case %s: %s", id, err.Error())
+	}
+	return meta, data, nil
+}
+
+// Meta returns a case without its contents
+func (disk *Disk) Meta(id string) (Meta, error) {
+	if !idRp.MatchString(id) {
+		return Meta{}, ErrBadID
+	}
+	disk.mux.RLock()
+	defer disk.mux.RUnlock()
+	return disk.meta(id)
+}
+
+// SetMeta replaces the Meta of a case that is already stored, leaving its contents alone
+func (disk *Disk) SetMeta(meta Meta) error {
+	if !idRp.MatchString(meta.ID) {
+		return ErrBadID
+	}
+	metaJSON, err := json.Marshal(meta)
+	if err != nil {
+		return fmt.Errorf("failed to convert the case info to json: %s", err.Error())
+	}
+	disk.mux.Lock()
+	defer disk.mux.Unlock()
+	_, err = disk.meta(meta.ID)
+	if err != nil {
+		return err
+	}
+	return writeFile(disk.path(meta.ID, metaExt), metaJSON)
+}
+
+// List returns every stored case, most recently updated first
+func (disk *Disk) List() ([]Meta, error) {
+	disk.mux.RLock()
+	defer disk.mux.RUnlock()
+	files, err := ioutil.ReadDir(disk.dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list the case directory: %s", err.Error())
+	}
+	metas := []Meta{}
+	for _, file := range files {
+		if !strings.HasSuffix(file.Name(), metaExt) {
+			continue
+		}
+		meta, err := disk.meta(strings.TrimSuffix(file.Name(), metaExt))
+		if err != nil {
+			// A half written case shouldn't hide the rest
+			continue
+		}
+		metas = append(metas, meta)
+	}
+	sort.Slice(metas, func(i, j int) bool {
+		return metas[i].Updated.After(metas[j].Updated)
+	})
+	return metas, nil
+}
+
+// Delete removes a case
+func (disk *Disk) Delete(id string) error {
+	if !idRp.MatchString(id) {
+		return ErrBadID
+	}
+	disk.mux.Lock()
+	defer disk.mux.Unlock()
+	err := os.Remove(disk.path(id, metaExt))
+	if os.IsNotExist(err) {
+		return ErrNotFound
+	} else if err != nil {
+		return fmt.Errorf("failed to delete case %s: %s", id, err.Error())
+	}
+	err = os.Remove(disk.path(id, dataExt))
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to delete case %s: %s", id, err.Error())
+	}
+	return nil
+}
+
+// put writes a case, and must be called with the lock held
+func (disk *Disk) put(meta Meta, data []byte) (Meta, error) {
+	meta.Size = int64(len(data))
+	meta.Updated = time.Now().UTC()
+	metaJSON, err := json.Marshal(meta)
+	if err != nil {
+		return Meta{}, fmt.Errorf("failed to convert the case info to json: %s", err.Error())
+	}
+	err = writeFile(disk.path(meta.ID, dataExt), data)
+	if err != nil {
+		return Meta{}, err
+	}
+	err = writeFile(disk.path(meta.ID, metaExt), metaJSON)
+	if err != nil {
+		return Meta{}, err
+	}
+	return meta, nil
+}
+
+// meta reads the Meta of a case, and must be called with the lock held
+func (disk *Disk) meta(id string) (Meta, error) {
+	raw, err := ioutil.ReadFile(disk.path(id, metaExt))
+	if os.IsNotExist(err) {
+		return Meta{}, ErrNotFound
+	} else if err != nil {
+		return Meta{}, fmt.Errorf("failed to read case %s: %s", id, err.Error())
+	}
+	meta := Meta{}
+	err = json.Unmarshal(raw, &meta)
+	if err != nil {
+		return Meta{}, fmt.Errorf("case %s has broken info: %s", id, err.Error())
+	}
+	return meta, nil
+}
+
+func (disk *Disk) path(id string, ext string) string {
+	return filepath.Join(disk.dir, id+ext)
+}
+
+// writeFile writes to a temporary file first so a crash never leaves a half written case
+func writeFile(path string, data []byte) error {
+	tmp := path + ".tmp"
+	err := ioutil.WriteFile(tmp, data, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to write %s: %s", filepath.Base(path), err.Error())
+	}
+	err = os.Rename(tmp, path)
+	if err != nil {
+		return fmt.Errorf("failed to write %s: %s", filepath.Base(path), err.Error())
+	}
+	return nil
+}
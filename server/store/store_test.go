@@ -0,0 +1,200 @@
+package store
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sync"
+	"testing"
+
+	"gitlab.com/256/DebateFrame/server/auth"
+)
+
+// tempDisk returns a Disk in a new directory, and a function that removes the directory
+func tempDisk(t *testing.T) (*Disk, func()) {
+	dir, err := ioutil.TempDir("", "tub")
+	if err != nil {
+		t.Fatal(err)
+	}
+	disk, err := NewDisk(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return disk, func() { os.RemoveAll(dir) }
+}
+
+func TestPutGet(t *testing.T) {
+	disk, cleanup := tempDisk(t)
+	defer cleanup()
+	_, err := disk.Put(Meta{ID: "case-1", Name: "Aff", Owner: "coach"}, []byte("contents"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	meta, data, err := disk.Get("case-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "contents" || meta.Name != "Aff" || meta.Owner != "coach" || meta.Size != 8 {
+		t.Errorf("got %+v with %q", meta, data)
+	}
+}
+
+func TestBadID(t *testing.T) {
+	disk, cleanup := tempDisk(t)
+	defer cleanup()
+	for _, id := range []string{"", "../accounts", "a/b", "a.json"} {
+		if _, err := disk.Put(Meta{ID: id}, nil); err != ErrBadID {
+			t.Errorf("Put(%q) returned %v", id, err)
+		}
+		if _, _, err := disk.Get(id); err != ErrBadID {
+			t.Errorf("Get(%q) returned %v", id, err)
+		}
+	}
+}
+
+func TestNotFound(t *testing.T) {
+	disk, cleanup := tempDisk(t)
+	defer cleanup()
+	if _, _, err := disk.Get("missing"); err != ErrNotFound {
+		t.Errorf("Get returned %v", err)
+	}
+	if err := disk.SetMeta(Meta{ID: "missing"}); err != ErrNotFound {
+		t.Errorf("SetMeta returned %v", err)
+	}
+	if err := disk.Delete("missing"); err != ErrNotFound {
+		t.Errorf("Delete returned %v", err)
+	}
+}
+
+func TestListAndDelete(t *testing.T) {
+	disk, cleanup := tempDisk(t)
+	defer cleanup()
+	for _, id := range []string{"first", "second"} {
+		if _, err := disk.Put(Meta{ID: id}, []byte(id)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	metas, err := disk.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(metas) != 2 || metas[0].ID != "second" {
+		t.Fatalf("got %+v, want second then first", metas)
+	}
+	if err := disk.Delete("second"); err != nil {
+		t.Fatal(err)
+	}
+	metas, err = disk.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(metas) != 1 || metas[0].ID != "first" {
+		t.Errorf("got %+v after deleting second", metas)
+	}
+}
+
+func TestSetMeta(t *testing.T) {
+	disk, cleanup := tempDisk(t)
+	defer cleanup()
+	meta, err := disk.Put(Meta{ID: "case"}, []byte("contents"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	meta.ACL = auth.ACL{"team:varsity": auth.Editor}
+	if err := disk.SetMeta(meta); err != nil {
+		t.Fatal(err)
+	}
+	meta, data, err := disk.Get("case")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if meta.ACL["team:varsity"] != auth.Editor || string(data) != "contents" {
+		t.Errorf("got %+v with %q", meta, data)
+	}
+}
+
+func TestUpdate(t *testing.T) {
+	disk, cleanup := tempDisk(t)
+	defer cleanup()
+	create := func(meta Meta, err error) (Meta, error) {
+		if err == ErrNotFound {
+			return Meta{Owner: "coach"}, nil
+		}
+		return meta, err
+	}
+	meta, err := disk.Update("case", []byte("first"), create)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if meta.ID != "case" || meta.Owner != "coach" || meta.Size != 5 {
+		t.Errorf("creating the case returned %+v", meta)
+	}
+
+	// Updating sees the stored Meta, so it doesn't create the case again
+	_, err = disk.Update("case", []byte("second"), func(meta Meta, err error) (Meta, error) {
+		if err != nil || meta.Owner != "coach" {
+			t.Errorf("the update was given %+v, %v", meta, err)
+		}
+		meta.Uploader = "debater"
+		return meta, err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	denied := errors.New("denied")
+	_, err = disk.Update("case", []byte("third"), func(meta Meta, err error) (Meta, error) {
+		return meta, denied
+	})
+	if err != denied {
+		t.Errorf("a refused update returned %v", err)
+	}
+	meta, data, err := disk.Get("case")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "second" || meta.Owner != "coach" || meta.Uploader != "debater" {
+		t.Errorf("got %+v with %q", meta, data)
+	}
+	if _, err := disk.Update("../case", nil, create); err != ErrBadID {
+		t.Errorf("Update with a bad ID returned %v", err)
+	}
+}
+
+// TestUpdateRace has many uploads create the same case at once, and checks only one of them did
+func TestUpdateRace(t *testing.T) {
+	disk, cleanup := tempDisk(t)
+	defer cleanup()
+	var wg sync.WaitGroup
+	created := make(chan string, 20)
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(owner string) {
+			defer wg.Done()
+			_, err := disk.Update("case", []byte(owner), func(meta Meta, err error) (Meta, error) {
+				if err == ErrNotFound {
+					created <- owner
+					meta.Owner = owner
+					return meta, nil
+				}
+				return meta, err
+			})
+			if err != nil {
+				t.Error(err)
+			}
+		}(fmt.Sprintf("user%v", i))
+	}
+	wg.Wait()
+	close(created)
+	owners := []string{}
+	for owner := range created {
+		owners = append(owners, owner)
+	}
+	meta, err := disk.Meta("case")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(owners) != 1 || meta.Owner != owners[0] {
+		t.Errorf("%v created the case, and it belongs to %q", owners, meta.Owner)
+	}
+}
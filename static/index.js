@@ -3,7 +3,6 @@ require('codemirror/lib/codemirror.css');
 require("github-markdown-css");
 require("uikit/dist/css/uikit.min.css");
 require("./styles.css");
-require("tocbot/dist/tocbot.css");
 require("medium-editor/dist/css/medium-editor.min.css");
 require("medium-editor/dist/css/themes/beagle.min.css");
 require("@fortawesome/fontawesome-free/css/all.css");
@@ -16,9 +15,9 @@ require("./go_wasm.js");
 window.UIkit = require("uikit");
 window.Dropzone = require("dropzone/dist/dropzone.js");
 window.jQuery = require("jquery");
-window.tocbot = require("tocbot");
 window.MediumEditor = require("medium-editor");
 window.rangy = require("rangy/lib/rangy-classapplier");
+window.pdfjsLib = require("pdfjs-dist/webpack"); // Sets up the PDF.js worker through worker-loader
 import Icons from 'uikit/dist/js/uikit-icons';
 require("./vendor/fa-uikit/js/uikit-fa-icons"); // Basically styles as it just loads in the SVG files as icons
 
@@ -108,6 +107,90 @@ function blobToBytes(blob) {
 
 window.blobToBytes = blobToBytes
 
+// Opens an IndexedDB database, creating any missing object stores. This has to be done in JS because
+// stores can only be created synchronously while the upgradeneeded event is being handled
+function idbOpen(name, version, stores) {
+    var request = indexedDB.open(name, version);
+    request.onupgradeneeded = function (event) {
+        var db = event.target.result;
+        stores.forEach(function (store) {
+            if (!db.objectStoreNames.contains(store)) {
+                db.createObjectStore(store);
+            }
+        });
+    };
+    return request;
+}
+
+window.idbOpen = idbOpen
+
+// Lets a section of a case be dragged from the element and other sections be dropped on it. This has to be done in JS
+// because the drag data can only be set and read, and a drop only allowed, while the drag events are being handled.
+// key says which section the element is, and drop is called with the key of the dropped section, key, and whether it
+// was dropped on the second half of the element, across it if across is set and down it otherwise
+function sectionDrag(element, key, across, drop) {
+    var type = "application/x-debateframe-section";
+    element.setAttribute("draggable", "true");
+    element.addEventListener("dragstart", function (event) {
+        event.stopPropagation();
+        event.dataTransfer.setData(type, key);
+        event.dataTransfer.effectAllowed = "move";
+    });
+    element.addEventListener("dragover", function (event) {
+        if (event.dataTransfer.types.indexOf(type) === -1) {
+            return;
+        }
+        event.preventDefault();
+        event.stopPropagation();
+        element.classList.add("dropTarget");
+    });
+    element.addEventListener("dragleave", function () {
+        element.classList.remove("dropTarget");
+    });
+    element.addEventListener("drop", function (event) {
+        element.classList.remove("dropTarget");
+        if (event.dataTransfer.types.indexOf(type) === -1) {
+            return;
+        }
+        event.preventDefault();
+        event.stopPropagation();
+        var rect = element.getBoundingClientRect();
+        var after = across ? event.clientX > rect.left + rect.width / 2 : event.clientY > rect.top + rect.height / 2;
+        drop(event.dataTransfer.getData(type), key, after);
+    });
+}
+
+window.sectionDrag = sectionDrag
+
+// Calls undo or redo for their shortcuts in the element, instead of letting the browser undo on its own. This has to be
+// done in JS because the browser's undo can only be prevented while the keydown event is being handled
+function historyKeys(element, undo, redo) {
+    element.addEventListener("keydown", function (event) {
+        if (!event.ctrlKey && !event.metaKey) {
+            return;
+        }
+        if (event.key === "z") {
+            event.preventDefault();
+            undo();
+        } else if (event.key === "Z" || event.key === "y") {
+            event.preventDefault();
+            redo();
+        }
+    });
+}
+
+window.historyKeys = historyKeys
+
+// keepSelection stops pressing on the element from moving the selection, so a button can act on what was selected in the
+// editor. The Go event handlers run too late to prevent the default
+function keepSelection(element) {
+    element.addEventListener("mousedown", function (event) {
+        event.preventDefault();
+    });
+}
+
+window.keepSelection = keepSelection
+
 if ('serviceWorker' in navigator) {
     window.addEventListener('load', () => {
         navigator.serviceWorker.register('/dist/service-worker.js').then(registration => {